@@ -25,7 +25,7 @@ import (
 // NewCommand creates a new providers subcommand
 // This subcommand is used to determine which providers are registered.
 func NewCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "providers",
 		Short: "Show the list of supported providers",
 		Long:  "Show the list of supported providers",
@@ -51,4 +51,31 @@ func NewCommand() *cobra.Command {
 			return
 		},
 	}
+	cmd.AddCommand(newValidateCommand())
+	return cmd
+}
+
+// newValidateCommand creates the subcommand used to check the config of a
+// provider without starting it.
+func newValidateCommand() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "validate <provider>",
+		Short: "Validate the configuration of a provider",
+		Long: `Validate the configuration of a provider, including the settings
+overridden by environment variables, without starting the provider or
+contacting the cloud it runs on.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := register.ValidateConfig(args[0], configPath); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&configPath, "provider-config", "", "cloud provider configuration file")
+	return cmd
 }
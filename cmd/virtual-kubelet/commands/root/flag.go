@@ -72,6 +72,9 @@ func installFlags(flags *pflag.FlagSet, c *Opts) {
 	flags.IntVar(&c.PodSyncWorkers, "pod-sync-workers", c.PodSyncWorkers, `set the number of pod synchronization workers`)
 	flags.BoolVar(&c.EnableNodeLease, "enable-node-lease", c.EnableNodeLease, `use node leases (1.13) for node heartbeats`)
 
+	flags.Var(mapVar(c.ReservedResources), "reserved-resource", "reserve provider capacity from being allocated to pods in resource=quantity form, e.g. cpu=500m")
+	flags.IntVar(&c.ResourcePressureThreshold, "resource-pressure-threshold", c.ResourcePressureThreshold, "percentage of allocatable resources requested by pods at which to set the ResourcePressure node condition (0 disables it)")
+
 	flags.StringSliceVar(&c.TraceExporters, "trace-exporter", c.TraceExporters, fmt.Sprintf("sets the tracing exporter to use, available exporters: %s", opencensus.AvailableTraceExporters()))
 	flags.StringVar(&c.TraceConfig.ServiceName, "trace-service-name", c.TraceConfig.ServiceName, "sets the name of the service used to register with the trace exporter")
 	flags.Var(mapVar(c.TraceConfig.Tags), "trace-tag", "add tags to include with traces in key=value form")
@@ -104,8 +104,13 @@ func setupHTTPServer(ctx context.Context, p providers.Provider, cfg *apiServerCo
 			return nil, errors.Wrap(err, "could not setup listener for pod metrics http server")
 		}
 
+		mp, err := metricsProvider(p, cfg.NodeName)
+		if err != nil {
+			return nil, err
+		}
+
 		mux := http.NewServeMux()
-		vkubelet.AttachMetricsRoutes(p, mux)
+		vkubelet.AttachMetricsRoutes(mp, mux)
 		s := &http.Server{
 			Handler: mux,
 		}
@@ -116,6 +121,19 @@ func setupHTTPServer(ctx context.Context, p providers.Provider, cfg *apiServerCo
 	return cancel, nil
 }
 
+// metricsProvider returns the provider to serve the metrics routes from.
+// Providers which only implement the per-pod stats interface get their stats
+// summary built by a stats aggregator.
+func metricsProvider(p providers.Provider, nodeName string) (providers.Provider, error) {
+	if _, ok := p.(providers.PodMetricsProvider); ok {
+		return p, nil
+	}
+	if _, ok := p.(providers.PodStatsProvider); !ok {
+		return p, nil
+	}
+	return vkubelet.NewStatsAggregator(p, nodeName, vkubelet.DefaultStatsCacheTTL)
+}
+
 func serveHTTP(ctx context.Context, s *http.Server, l net.Listener, name string) {
 	if err := s.Serve(l); err != nil {
 		select {
@@ -132,6 +150,7 @@ type apiServerConfig struct {
 	KeyPath     string
 	Addr        string
 	MetricsAddr string
+	NodeName    string
 }
 
 func getAPIConfig(c Opts) (*apiServerConfig, error) {
@@ -142,6 +161,7 @@ func getAPIConfig(c Opts) (*apiServerConfig, error) {
 
 	config.Addr = fmt.Sprintf(":%d", c.ListenPort)
 	config.MetricsAddr = c.MetricsAddr
+	config.NodeName = c.NodeName
 
 	return &config, nil
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/virtual-kubelet/virtual-kubelet/version"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -109,3 +110,16 @@ func getTaint(c Opts) (*corev1.Taint, error) {
 		Effect: effect,
 	}, nil
 }
+
+// getReservedResources parses the reserved resources configured for the node.
+func getReservedResources(c Opts) (v1.ResourceList, error) {
+	reserved := make(v1.ResourceList, len(c.ReservedResources))
+	for name, value := range c.ReservedResources {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "invalid quantity for reserved resource %q", name))
+		}
+		reserved[v1.ResourceName(name)] = q
+	}
+	return reserved, nil
+}
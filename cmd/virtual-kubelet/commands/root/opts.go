@@ -75,6 +75,12 @@ type Opts struct {
 	// Use node leases when supported by Kubernetes (instead of node status updates)
 	EnableNodeLease bool
 
+	// Resources held back from the provider's capacity, in resource=quantity form
+	ReservedResources map[string]string
+	// Percentage of allocatable resources requested by pods at which the node
+	// reports resource pressure, 0 disables the condition
+	ResourcePressureThreshold int
+
 	TraceExporters  []string
 	TraceSampleRate string
 	TraceConfig     opencensus.TracingExporterOptions
@@ -129,6 +135,10 @@ func SetDefaultOpts(c *Opts) error {
 		c.TaintEffect = DefaultTaintEffect
 	}
 
+	if c.ReservedResources == nil {
+		c.ReservedResources = make(map[string]string)
+	}
+
 	if c.KubeConfigPath == "" {
 		c.KubeConfigPath = os.Getenv("KUBECONFIG")
 		if c.KubeConfigPath == "" {
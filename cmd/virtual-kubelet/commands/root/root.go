@@ -26,6 +26,7 @@ import (
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"github.com/virtual-kubelet/virtual-kubelet/providers/register"
 	"github.com/virtual-kubelet/virtual-kubelet/vkubelet"
+	"go.opencensus.io/stats/view"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -97,7 +98,7 @@ func runRootCommand(ctx context.Context, c Opts) error {
 	go podInformerFactory.Start(ctx.Done())
 	go scmInformerFactory.Start(ctx.Done())
 
-	rm, err := manager.NewResourceManager(podInformer.Lister(), secretInformer.Lister(), configMapInformer.Lister(), serviceInformer.Lister())
+	rm, err := manager.NewResourceManager(podInformer.Lister(), secretInformer.Lister(), configMapInformer.Lister(), serviceInformer.Lister(), manager.WithServiceAccountTokens(client.CoreV1()))
 	if err != nil {
 		return errors.Wrap(err, "could not create resource manager")
 	}
@@ -132,10 +133,26 @@ func runRootCommand(ctx context.Context, c Opts) error {
 		"watchedNamespace": c.KubeNamespace,
 	}))
 
+	reserved, err := getReservedResources(c)
+	if err != nil {
+		return err
+	}
+
 	pNode := NodeFromProvider(ctx, c.NodeName, taint, p)
-	node, err := vkubelet.NewNode(
+	np, err := vkubelet.NewPodResourceTracker(
 		vkubelet.NaiveNodeProvider{},
 		pNode,
+		podInformer,
+		vkubelet.WithReservedResources(reserved),
+		vkubelet.WithResourcePressureThreshold(c.ResourcePressureThreshold),
+	)
+	if err != nil {
+		return err
+	}
+
+	node, err := vkubelet.NewNode(
+		np,
+		pNode,
 		client.Coordination().Leases(corev1.NamespaceNodeLease),
 		client.CoreV1().Nodes(),
 		vkubelet.WithNodeDisableLease(!c.EnableNodeLease),
@@ -154,6 +171,10 @@ func runRootCommand(ctx context.Context, c Opts) error {
 		PodInformer:     podInformer,
 	})
 
+	if err := view.Register(vkubelet.PodStatusUpdatesView); err != nil {
+		return errors.Wrap(err, "error registering pod status update metrics")
+	}
+
 	cancelHTTP, err := setupHTTPServer(ctx, p, apiConfig)
 	if err != nil {
 		return err
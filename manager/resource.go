@@ -1,8 +1,12 @@
 package manager
 
 import (
+	"errors"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 
 	"github.com/virtual-kubelet/virtual-kubelet/log"
@@ -15,16 +19,35 @@ type ResourceManager struct {
 	secretLister    corev1listers.SecretLister
 	configMapLister corev1listers.ConfigMapLister
 	serviceLister   corev1listers.ServiceLister
+
+	serviceAccounts corev1client.ServiceAccountsGetter
+}
+
+// ResourceManagerOpt is used to configure optional settings of a ResourceManager.
+type ResourceManagerOpt func(*ResourceManager) error
+
+// WithServiceAccountTokens sets the client used to request service account
+// tokens from the Kubernetes API.
+func WithServiceAccountTokens(serviceAccounts corev1client.ServiceAccountsGetter) ResourceManagerOpt {
+	return func(rm *ResourceManager) error {
+		rm.serviceAccounts = serviceAccounts
+		return nil
+	}
 }
 
 // NewResourceManager returns a ResourceManager with the internal maps initialized.
-func NewResourceManager(podLister corev1listers.PodLister, secretLister corev1listers.SecretLister, configMapLister corev1listers.ConfigMapLister, serviceLister corev1listers.ServiceLister) (*ResourceManager, error) {
+func NewResourceManager(podLister corev1listers.PodLister, secretLister corev1listers.SecretLister, configMapLister corev1listers.ConfigMapLister, serviceLister corev1listers.ServiceLister, opts ...ResourceManagerOpt) (*ResourceManager, error) {
 	rm := ResourceManager{
 		podLister:       podLister,
 		secretLister:    secretLister,
 		configMapLister: configMapLister,
 		serviceLister:   serviceLister,
 	}
+	for _, o := range opts {
+		if err := o(&rm); err != nil {
+			return nil, err
+		}
+	}
 	return &rm, nil
 }
 
@@ -52,3 +75,12 @@ func (rm *ResourceManager) GetSecret(name, namespace string) (*v1.Secret, error)
 func (rm *ResourceManager) ListServices() ([]*v1.Service, error) {
 	return rm.serviceLister.List(labels.Everything())
 }
+
+// GetServiceAccountToken requests a token for the specified service account
+// using the TokenRequest API.
+func (rm *ResourceManager) GetServiceAccountToken(name, namespace string, tr *authenticationv1.TokenRequest) (*authenticationv1.TokenRequest, error) {
+	if rm.serviceAccounts == nil {
+		return nil, errors.New("the resource manager is not configured to request service account tokens")
+	}
+	return rm.serviceAccounts.ServiceAccounts(namespace).CreateToken(name, tr)
+}
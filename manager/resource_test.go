@@ -3,9 +3,13 @@ package manager_test
 import (
 	"testing"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/virtual-kubelet/virtual-kubelet/manager"
@@ -150,3 +154,40 @@ func TestListServices(t *testing.T) {
 		t.Fatalf("expected %d services, found %d", len(lsServices), len(services))
 	}
 }
+
+// TestGetServiceAccountToken verifies that the resource manager requests service account tokens using the TokenRequest API.
+func TestGetServiceAccountToken(t *testing.T) {
+	// Create a resource manager which can't request tokens.
+	rm, err := manager.NewResourceManager(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.GetServiceAccountToken("default", "namespace-0", &authenticationv1.TokenRequest{}); err == nil {
+		t.Fatal("expected an error when no service account client is configured")
+	}
+
+	// Create a fake Kubernetes client that answers token requests.
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" || action.GetNamespace() != "namespace-0" {
+			t.Fatalf("unexpected action %v", action)
+		}
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		tr.Status.Token = "token-0"
+		return true, tr, nil
+	})
+
+	rm, err = manager.NewResourceManager(nil, nil, nil, nil, manager.WithServiceAccountTokens(client.CoreV1()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check that the resource manager returns the token issued for the service account.
+	tr, err := rm.GetServiceAccountToken("default", "namespace-0", &authenticationv1.TokenRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Status.Token != "token-0" {
+		t.Fatalf("expected token %q, found %q", "token-0", tr.Status.Token)
+	}
+}
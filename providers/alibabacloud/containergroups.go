@@ -0,0 +1,248 @@
+package alibabacloud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/alibabacloud/eci"
+)
+
+const (
+	// containerGroupSweepInterval is the interval between two refreshes of
+	// the container group index.
+	containerGroupSweepInterval = time.Minute
+
+	// How often and how long calls to ECI failing with transient errors are
+	// retried.
+	eciRetryAttempts     = 5
+	eciRetryInitialDelay = time.Second
+)
+
+// eciAPI is the part of the ECI API used by the provider, implemented by
+// *eci.Client.
+type eciAPI interface {
+	CreateContainerGroup(*eci.CreateContainerGroupRequest) (*eci.CreateContainerGroupResponse, error)
+	DeleteContainerGroup(*eci.DeleteContainerGroupRequest) (*eci.DeleteContainerGroupResponse, error)
+	DescribeContainerGroups(*eci.DescribeContainerGroupsRequest) (*eci.DescribeContainerGroupsResponse, error)
+	DescribeContainerLog(*eci.DescribeContainerLogRequest) (*eci.DescribeContainerLogResponse, error)
+	DescribeMultiContainerGroupMetric(*eci.DescribeMultiContainerGroupMetricRequest) (*eci.DescribeMultiContainerGroupMetricResponse, error)
+	ExecContainerCommand(*eci.ExecContainerCommandRequest) (*eci.ExecContainerCommandResponse, error)
+}
+
+// containerGroupIndex maps the namespace and name of pods to the ID of their
+// container group.
+type containerGroupIndex struct {
+	mu  sync.RWMutex
+	ids map[string]string
+}
+
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (idx *containerGroupIndex) get(namespace, name string) string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.ids[podKey(namespace, name)]
+}
+
+func (idx *containerGroupIndex) set(namespace, name, id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.ids == nil {
+		idx.ids = make(map[string]string)
+	}
+	idx.ids[podKey(namespace, name)] = id
+}
+
+func (idx *containerGroupIndex) delete(namespace, name string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.ids, podKey(namespace, name))
+}
+
+// reset replaces the index with the given container groups.
+func (idx *containerGroupIndex) reset(cgs []eci.ContainerGroup) {
+	ids := make(map[string]string, len(cgs))
+	for i := range cgs {
+		ids[podKey(getECITagValue(&cgs[i], "NameSpace"), getECITagValue(&cgs[i], "PodName"))] = cgs[i].ContainerGroupId
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.ids = ids
+}
+
+// isNodeContainerGroup returns whether a container group was created by this
+// node.
+func (p *ECIProvider) isNodeContainerGroup(cg *eci.ContainerGroup) bool {
+	if getECITagValue(cg, "NodeName") != p.nodeName {
+		return false
+	}
+	cn := getECITagValue(cg, "ClusterName")
+	if cn == "" {
+		cn = "default"
+	}
+	return cn == p.clusterName
+}
+
+// getContainerGroup returns the container group of a pod.
+//
+// The container group is described by its ID if it is in the index, and
+// otherwise, or if the indexed ID is stale because the pod was recreated
+// since, looked up by the tags of the pod.
+func (p *ECIProvider) getContainerGroup(ctx context.Context, namespace, name string) (*eci.ContainerGroup, error) {
+	match := func(cg *eci.ContainerGroup) bool {
+		return getECITagValue(cg, "NameSpace") == namespace && getECITagValue(cg, "PodName") == name
+	}
+
+	if id := p.containerGroups.get(namespace, name); id != "" {
+		request := eci.CreateDescribeContainerGroupsRequest()
+		request.ContainerGroupId = id
+		cg, err := p.describeContainerGroup(ctx, request, match)
+		if err != nil {
+			return nil, err
+		}
+		if cg != nil {
+			return cg, nil
+		}
+		p.containerGroups.delete(namespace, name)
+	}
+
+	request := eci.CreateDescribeContainerGroupsRequest()
+	request.Tags = &[]eci.DescribeContainerGroupsTag{
+		{Key: "NodeName", Value: p.nodeName},
+		{Key: "NameSpace", Value: namespace},
+		{Key: "PodName", Value: name},
+	}
+	cg, err := p.describeContainerGroup(ctx, request, match)
+	if err != nil {
+		return nil, err
+	}
+	if cg == nil {
+		return nil, strongerrors.NotFound(fmt.Errorf("container group of pod %s/%s not found", namespace, name))
+	}
+	p.containerGroups.set(namespace, name, cg.ContainerGroupId)
+	return cg, nil
+}
+
+// getContainerGroupByName returns the container group of the node with the
+// given name.
+func (p *ECIProvider) getContainerGroupByName(ctx context.Context, name string) (*eci.ContainerGroup, error) {
+	request := eci.CreateDescribeContainerGroupsRequest()
+	request.ContainerGroupName = name
+	request.Tags = &[]eci.DescribeContainerGroupsTag{
+		{Key: "NodeName", Value: p.nodeName},
+	}
+
+	cg, err := p.describeContainerGroup(ctx, request, func(cg *eci.ContainerGroup) bool {
+		return cg.ContainerGroupName == name
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cg == nil {
+		return nil, strongerrors.NotFound(fmt.Errorf("container group %s not found", name))
+	}
+	return cg, nil
+}
+
+// describeContainerGroup returns the first container group of the node
+// described by request which matches, or nil if there is none.
+func (p *ECIProvider) describeContainerGroup(ctx context.Context, request *eci.DescribeContainerGroupsRequest, match func(*eci.ContainerGroup) bool) (*eci.ContainerGroup, error) {
+	var response *eci.DescribeContainerGroupsResponse
+	err := retryECI(ctx, func() (err error) {
+		response, err = p.eciClient.DescribeContainerGroups(request)
+		return err
+	})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	for i := range response.ContainerGroups {
+		cg := &response.ContainerGroups[i]
+		if p.isNodeContainerGroup(cg) && match(cg) {
+			return cg, nil
+		}
+	}
+	return nil, nil
+}
+
+// listContainerGroups returns the container groups of the node and refreshes
+// the index with them.
+func (p *ECIProvider) listContainerGroups(ctx context.Context) ([]eci.ContainerGroup, error) {
+	cgs := make([]eci.ContainerGroup, 0)
+	request := eci.CreateDescribeContainerGroupsRequest()
+	request.Tags = &[]eci.DescribeContainerGroupsTag{
+		{Key: "NodeName", Value: p.nodeName},
+	}
+	for {
+		var response *eci.DescribeContainerGroupsResponse
+		err := retryECI(ctx, func() (err error) {
+			response, err = p.eciClient.DescribeContainerGroups(request)
+			return err
+		})
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		for i := range response.ContainerGroups {
+			if p.isNodeContainerGroup(&response.ContainerGroups[i]) {
+				cgs = append(cgs, response.ContainerGroups[i])
+			}
+		}
+
+		if response.NextToken == "" || len(response.ContainerGroups) == 0 {
+			break
+		}
+		request.NextToken = response.NextToken
+	}
+
+	p.containerGroups.reset(cgs)
+	return cgs, nil
+}
+
+// runContainerGroupSweep periodically refreshes the container group index,
+// so that container groups created or deleted outside of the provider are
+// found by their ID.
+func (p *ECIProvider) runContainerGroupSweep(ctx context.Context) {
+	t := time.NewTicker(containerGroupSweepInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if _, err := p.listContainerGroups(ctx); err != nil {
+			log.G(ctx).WithError(err).Warn("Error refreshing the container group index")
+		}
+	}
+}
+
+// retryECI calls fn until it succeeds, fails with an error which is not
+// transient, the attempts are exhausted or ctx is done. The delay between
+// attempts is doubled after each one.
+func retryECI(ctx context.Context, fn func() error) error {
+	delay := eciRetryInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == eciRetryAttempts || !isTransientError(err) {
+			return err
+		}
+
+		log.G(ctx).WithError(err).WithField("attempt", attempt).Debug("Retrying ECI request")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,88 @@
+package alibabacloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/cpuguy83/strongerrors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestGetContainerGroupIndexHit(t *testing.T) {
+	p, f := newFakeECIProvider("")
+	p.containerGroups.set("ns", "pod", "eci-1")
+
+	cg, err := p.getContainerGroup(context.Background(), "ns", "pod")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(cg.ContainerGroupId, "eci-1"))
+
+	// The container group is described by its ID only.
+	assert.Assert(t, is.Len(f.describeRequests, 1))
+	assert.Check(t, is.Equal(f.describeRequests[0].ContainerGroupId, "eci-1"))
+	assert.Check(t, f.describeRequests[0].Tags == nil)
+}
+
+func TestGetContainerGroupTagLookup(t *testing.T) {
+	p, f := newFakeECIProvider("")
+
+	cg, err := p.getContainerGroup(context.Background(), "ns", "pod")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(cg.ContainerGroupId, "eci-1"))
+	assert.Assert(t, is.Len(f.describeRequests, 1))
+	assert.Check(t, is.Equal(f.describeRequests[0].ContainerGroupId, ""))
+
+	// The ID found is indexed.
+	assert.Check(t, is.Equal(p.containerGroups.get("ns", "pod"), "eci-1"))
+}
+
+func TestGetContainerGroupStaleIndex(t *testing.T) {
+	p, f := newFakeECIProvider("")
+
+	// The pod was recreated with a new container group since it was indexed.
+	p.containerGroups.set("ns", "pod", "eci-0")
+
+	cg, err := p.getContainerGroup(context.Background(), "ns", "pod")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(cg.ContainerGroupId, "eci-1"))
+	assert.Assert(t, is.Len(f.describeRequests, 2))
+	assert.Check(t, is.Equal(f.describeRequests[0].ContainerGroupId, "eci-0"))
+	assert.Check(t, f.describeRequests[1].Tags != nil)
+	assert.Check(t, is.Equal(p.containerGroups.get("ns", "pod"), "eci-1"))
+
+	// Pods which are gone are removed from the index.
+	f.cgs = nil
+	_, err = p.getContainerGroup(context.Background(), "ns", "pod")
+	assert.Check(t, strongerrors.IsNotFound(err), "a not found error is expected, got %v", err)
+	assert.Check(t, is.Equal(p.containerGroups.get("ns", "pod"), ""))
+}
+
+func TestRetryECICancellation(t *testing.T) {
+	attempts := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := retryECI(ctx, func() error {
+		attempts++
+		return errors.NewServerError(http.StatusServiceUnavailable, "", "")
+	})
+	assert.Check(t, is.Equal(err, context.Canceled))
+	assert.Check(t, is.Equal(attempts, 1))
+	assert.Check(t, time.Since(start) < eciRetryInitialDelay)
+
+	// Errors which are not transient are not retried.
+	attempts = 0
+	err = retryECI(context.Background(), func() error {
+		attempts++
+		return errors.NewServerError(http.StatusBadRequest, "", "")
+	})
+	assert.Check(t, err != nil)
+	assert.Check(t, is.Equal(attempts, 1))
+}
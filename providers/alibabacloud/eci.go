@@ -9,7 +9,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -20,12 +19,12 @@ import (
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	"github.com/virtual-kubelet/virtual-kubelet/manager"
 	"github.com/virtual-kubelet/virtual-kubelet/providers/alibabacloud/eci"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/statscache"
 	"k8s.io/api/core/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/remotecommand"
 )
 
 // The service account secret mount path.
@@ -36,7 +35,8 @@ const timeFormat = "2006-01-02T15:04:05Z"
 
 // ECIProvider implements the virtual-kubelet provider interface and communicates with Alibaba Cloud's ECI APIs.
 type ECIProvider struct {
-	eciClient          *eci.Client
+	eciClient          eciAPI
+	containerGroups    containerGroupIndex
 	resourceManager    *manager.ResourceManager
 	resourceGroup      string
 	region             string
@@ -50,6 +50,8 @@ type ECIProvider struct {
 	daemonEndpointPort int32
 	secureGroup        string
 	vSwitch            string
+
+	metrics statscache.Cache
 }
 
 // AuthConfig is the secret returned from an ImageRegistryCredential
@@ -147,6 +149,7 @@ func NewECIProvider(config string, rm *manager.ResourceManager, nodeName, operat
 	if err != nil {
 		return nil, err
 	}
+	go p.runContainerGroupSweep(context.Background())
 
 	p.cpu = "1000"
 	p.memory = "4Ti"
@@ -227,8 +230,9 @@ func (p *ECIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	log.G(ctx).WithField("Method", "CreatePod").Info(msg)
 	response, err := p.eciClient.CreateContainerGroup(request)
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
+	p.containerGroups.set(pod.Namespace, pod.Name, response.ContainerGroupId)
 	msg = fmt.Sprintf("CreateContainerGroup successed. %s, %s, %s", response.RequestId, response.ContainerGroupId, ContainerGroupName)
 	log.G(ctx).WithField("Method", "CreatePod").Info(msg)
 	return nil
@@ -245,72 +249,58 @@ func (p *ECIProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
 
 // DeletePod deletes the specified pod out of ECI.
 func (p *ECIProvider) DeletePod(ctx context.Context, pod *v1.Pod) error {
-	eciId := ""
-	for _, cg := range p.GetCgs() {
-		if getECITagValue(&cg, "PodName") == pod.Name && getECITagValue(&cg, "NameSpace") == pod.Namespace {
-			eciId = cg.ContainerGroupId
-			break
-		}
-	}
-	if eciId == "" {
-		return strongerrors.NotFound(fmt.Errorf("DeletePod can't find Pod %s-%s", pod.Namespace, pod.Name))
+	cg, err := p.getContainerGroup(ctx, pod.Namespace, pod.Name)
+	if err != nil {
+		return err
 	}
 
 	request := eci.CreateDeleteContainerGroupRequest()
-	request.ContainerGroupId = eciId
-	_, err := p.eciClient.DeleteContainerGroup(request)
-	return wrapError(err)
+	request.ContainerGroupId = cg.ContainerGroupId
+	err = retryECI(ctx, func() error {
+		_, err := p.eciClient.DeleteContainerGroup(request)
+		return err
+	})
+	if err != nil {
+		return wrapError(err)
+	}
+	p.containerGroups.delete(pod.Namespace, pod.Name)
+	return nil
 }
 
 // GetPod returns a pod by name that is running inside ECI
 // returns nil if a pod by that name is not found.
 func (p *ECIProvider) GetPod(ctx context.Context, namespace, name string) (*v1.Pod, error) {
-	pods, err := p.GetPods(ctx)
+	cg, err := p.getContainerGroup(ctx, namespace, name)
 	if err != nil {
-		return nil, err
-	}
-	for _, pod := range pods {
-		if pod.Name == name && pod.Namespace == namespace {
-			return pod, nil
+		if strongerrors.IsNotFound(err) {
+			return nil, nil
 		}
+		return nil, err
 	}
-	return nil, nil
+	return containerGroupToPod(cg)
 }
 
 // GetContainerLogs returns the logs of a pod by name that is running inside ECI.
 func (p *ECIProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
-	eciId := ""
-	for _, cg := range p.GetCgs() {
-		if getECITagValue(&cg, "PodName") == podName && getECITagValue(&cg, "NameSpace") == namespace {
-			eciId = cg.ContainerGroupId
-			break
-		}
-	}
-	if eciId == "" {
-		return "", errors.New(fmt.Sprintf("GetContainerLogs can't find Pod %s-%s", namespace, podName))
+	cg, err := p.getContainerGroup(ctx, namespace, podName)
+	if err != nil {
+		return "", err
 	}
 
 	request := eci.CreateDescribeContainerLogRequest()
-	request.ContainerGroupId = eciId
+	request.ContainerGroupId = cg.ContainerGroupId
 	request.ContainerName = containerName
-	request.Tail = requests.Integer(tail)
+	request.Tail = requests.NewInteger(tail)
 
-	// get logs from cg
-	logContent := ""
-	retry := 10
-	for i := 0; i < retry; i++ {
-		response, err := p.eciClient.DescribeContainerLog(request)
-		if err != nil {
-			msg := fmt.Sprint("Error getting container logs, retrying")
-			log.G(ctx).WithField("Method", "GetContainerLogs").Info(msg)
-			time.Sleep(5000 * time.Millisecond)
-		} else {
-			logContent = response.Content
-			break
-		}
+	var response *eci.DescribeContainerLogResponse
+	err = retryECI(ctx, func() (err error) {
+		response, err = p.eciClient.DescribeContainerLog(request)
+		return err
+	})
+	if err != nil {
+		return "", wrapError(err)
 	}
-
-	return logContent, nil
+	return response.Content, nil
 }
 
 // Get full pod name as defined in the provider context
@@ -318,12 +308,6 @@ func (p *ECIProvider) GetPodFullName(namespace string, pod string) string {
 	return fmt.Sprintf("%s-%s", namespace, pod)
 }
 
-// ExecInContainer executes a command in a container in the pod, copying data
-// between in/out/err and the container's stdin/stdout/stderr.
-func (p *ECIProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
-	return nil
-}
-
 // GetPodStatus returns the status of a pod by name that is running inside ECI
 // returns nil if a pod by that name is not found.
 func (p *ECIProvider) GetPodStatus(ctx context.Context, namespace, name string) (*v1.PodStatus, error) {
@@ -339,45 +323,19 @@ func (p *ECIProvider) GetPodStatus(ctx context.Context, namespace, name string)
 	return &pod.Status, nil
 }
 
-func (p *ECIProvider) GetCgs() []eci.ContainerGroup {
-	cgs := make([]eci.ContainerGroup, 0)
-	request := eci.CreateDescribeContainerGroupsRequest()
-	for {
-		cgsResponse, err := p.eciClient.DescribeContainerGroups(request)
-		if err != nil || len(cgsResponse.ContainerGroups) == 0 {
-			break
-		}
-		request.NextToken = cgsResponse.NextToken
-
-		for _, cg := range cgsResponse.ContainerGroups {
-			if getECITagValue(&cg, "NodeName") != p.nodeName {
-				continue
-			}
-			cn := getECITagValue(&cg, "ClusterName")
-			if cn == "" {
-				cn = "default"
-			}
-			if cn != p.clusterName {
-				continue
-			}
-			cgs = append(cgs, cg)
-		}
-		if request.NextToken == "" {
-			break
-		}
-	}
-	return cgs
-}
-
 // GetPods returns a list of all pods known to be running within ECI.
 func (p *ECIProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
-	pods := make([]*v1.Pod, 0)
-	for _, cg := range p.GetCgs() {
-		c := cg
-		pod, err := containerGroupToPod(&c)
+	cgs, err := p.listContainerGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make([]*v1.Pod, 0, len(cgs))
+	for i := range cgs {
+		pod, err := containerGroupToPod(&cgs[i])
 		if err != nil {
-			msg := fmt.Sprint("error converting container group to pod", cg.ContainerGroupId, err)
-			log.G(context.TODO()).WithField("Method", "GetPods").Info(msg)
+			msg := fmt.Sprint("error converting container group to pod", cgs[i].ContainerGroupId, err)
+			log.G(ctx).WithField("Method", "GetPods").Info(msg)
 			continue
 		}
 		pods = append(pods, pod)
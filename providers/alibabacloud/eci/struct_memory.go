@@ -0,0 +1,25 @@
+package eci
+
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+//
+// Code generated by Alibaba Cloud SDK Code Generator.
+// Changes may cause incorrect behavior and will be lost if the code is regenerated.
+
+// Memory is a nested struct in eci response
+type Memory struct {
+	AvailableBytes int64 `json:"AvailableBytes" xml:"AvailableBytes"`
+	UsageBytes     int64 `json:"UsageBytes" xml:"UsageBytes"`
+	Cache          int64 `json:"Cache" xml:"Cache"`
+	WorkingSet     int64 `json:"WorkingSet" xml:"WorkingSet"`
+	Rss            int64 `json:"Rss" xml:"Rss"`
+}
@@ -2,6 +2,7 @@ package alibabacloud
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/cpuguy83/strongerrors"
@@ -24,3 +25,19 @@ func wrapError(err error) error {
 		return err
 	}
 }
+
+// isTransientError returns whether an ECI call may succeed when retried: when
+// the request was throttled, failed on the server side or did not reach it.
+func isTransientError(err error) bool {
+	switch e := err.(type) {
+	case *errors.ServerError:
+		return e.HttpStatus() >= http.StatusInternalServerError ||
+			e.HttpStatus() == http.StatusTooManyRequests ||
+			strings.HasPrefix(e.ErrorCode(), "Throttling") ||
+			strings.HasPrefix(e.ErrorCode(), "ServiceUnavailable")
+	case *errors.ClientError:
+		return true
+	default:
+		return false
+	}
+}
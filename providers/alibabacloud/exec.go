@@ -0,0 +1,100 @@
+package alibabacloud
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/cpuguy83/strongerrors"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/alibabacloud/eci"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/wsexec"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ExecInContainer executes a command in a container in the pod, copying data
+// between in/out/err and the container's stdin/stdout/stderr.
+//
+// The command is started with the ExecContainerCommand API, which returns the
+// WebSocketUri of a session attached to it. The API reference documents no
+// framing of the session messages: stdin is sent as is and the messages
+// received, the output of the command, are all written to out. In TTY
+// sessions stdin EOF is sent as EOT; other sessions can't signal it and end
+// when the command exits. ECI neither documents terminal resizes nor reports
+// the exit code of the command, resize events are dropped.
+func (p *ECIProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
+	// Cleanup on exit
+	if out != nil {
+		defer out.Close()
+	}
+	if errstream != nil {
+		defer errstream.Close()
+	}
+
+	if len(cmd) == 0 {
+		return strongerrors.InvalidArgument(errors.New("no command specified"))
+	}
+
+	ctx := context.TODO()
+	cg, err := p.getContainerGroupByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	command, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	request := eci.CreateExecContainerCommandRequest()
+	request.ContainerGroupId = cg.ContainerGroupId
+	request.ContainerName = container
+	request.Command = string(command)
+	request.TTY = requests.NewBoolean(tty)
+	request.Stdin = requests.NewBoolean(in != nil)
+
+	var response *eci.ExecContainerCommandResponse
+	err = retryECI(ctx, func() (err error) {
+		response, err = p.eciClient.ExecContainerCommand(request)
+		return err
+	})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	c, resp, err := websocket.DefaultDialer.Dial(response.WebSocketUri, nil)
+	if err != nil {
+		if resp != nil {
+			return errors.Wrapf(err, "error connecting to exec session for container %s: %s", container, resp.Status)
+		}
+		return errors.Wrapf(err, "error connecting to exec session for container %s", container)
+	}
+	defer c.Close()
+
+	session := wsexec.NewSession(c)
+
+	if in != nil {
+		var eof []byte
+		if tty {
+			eof = wsexec.EOT
+		}
+		go session.CopyStdin(in, eof)
+	}
+	if resize != nil {
+		go func() {
+			for range resize {
+			}
+		}()
+	}
+
+	var w io.Writer = ioutil.Discard
+	if out != nil {
+		w = out
+	}
+	return session.CopyOutput(w)
+}
@@ -0,0 +1,130 @@
+package alibabacloud
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/wsexec"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// execStandIn is a websocket server standing in for an ECI exec session.
+// Without stdin it writes the output of the command and exits. Otherwise it
+// echoes stdin until EOT, then writes some trailing output and exits.
+type execStandIn struct {
+	t     *testing.T
+	stdin bool
+}
+
+func (s *execStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	exit := func(output string) {
+		conn.WriteMessage(websocket.BinaryMessage, []byte(output))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	}
+	if !s.stdin {
+		exit("output")
+		return
+	}
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if bytes.Equal(data, wsexec.EOT) {
+			exit(" bye")
+			return
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			return
+		}
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestExecInContainer(t *testing.T) {
+	wsServer := httptest.NewServer(&execStandIn{t: t, stdin: true})
+	defer wsServer.Close()
+
+	provider, f := newFakeECIProvider("ws" + strings.TrimPrefix(wsServer.URL, "http"))
+
+	// Resize events are dropped without blocking the client.
+	resize := make(chan remotecommand.TerminalSize)
+	go func() {
+		resize <- remotecommand.TerminalSize{Height: 40, Width: 120}
+		close(resize)
+	}()
+
+	// The output written after stdin EOF is received.
+	var out bytes.Buffer
+	err := provider.ExecInContainer("ns-pod", "", "nginx", []string{"sh"}, strings.NewReader("hello"), nopWriteCloser{&out}, nil, true, resize, 0)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(out.String(), "hello bye"))
+
+	assert.Assert(t, is.Len(f.execRequests, 1))
+	request := f.execRequests[0]
+	assert.Check(t, is.Equal(request.ContainerGroupId, "eci-1"))
+	assert.Check(t, is.Equal(request.ContainerName, "nginx"))
+	assert.Check(t, is.Equal(request.Command, `["sh"]`))
+	assert.Check(t, is.Equal(string(request.TTY), "true"))
+	assert.Check(t, is.Equal(string(request.Stdin), "true"))
+}
+
+func TestExecInContainerWithoutStdin(t *testing.T) {
+	wsServer := httptest.NewServer(&execStandIn{t: t})
+	defer wsServer.Close()
+
+	provider, f := newFakeECIProvider("ws" + strings.TrimPrefix(wsServer.URL, "http"))
+
+	var out bytes.Buffer
+	err := provider.ExecInContainer("ns-pod", "", "nginx", []string{"ls", "-l"}, nil, nopWriteCloser{&out}, nil, false, nil, 0)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(out.String(), "output"))
+
+	assert.Assert(t, is.Len(f.execRequests, 1))
+	assert.Check(t, is.Equal(f.execRequests[0].Command, `["ls","-l"]`))
+	assert.Check(t, is.Equal(string(f.execRequests[0].TTY), "false"))
+	assert.Check(t, is.Equal(string(f.execRequests[0].Stdin), "false"))
+}
+
+func TestExecInContainerDialError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	provider, _ := newFakeECIProvider("ws" + strings.TrimPrefix(server.URL, "http"))
+
+	var out bytes.Buffer
+	err := provider.ExecInContainer("ns-pod", "", "nginx", []string{"ls"}, nil, nopWriteCloser{&out}, nil, false, nil, 0)
+	assert.ErrorContains(t, err, "error connecting to exec session for container nginx: 403 Forbidden")
+}
+
+func TestExecInContainerNotFound(t *testing.T) {
+	provider, _ := newFakeECIProvider("")
+
+	err := provider.ExecInContainer("ns-other", "", "nginx", []string{"ls"}, nil, nil, nil, false, nil, 0)
+	assert.Check(t, is.ErrorContains(err, "container group ns-other not found"))
+}
@@ -0,0 +1,101 @@
+package alibabacloud
+
+import (
+	"encoding/json"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers/alibabacloud/eci"
+)
+
+// fakeECI is an eciAPI serving the container groups cgs, whose exec sessions
+// are served at webSocketURI and whose metrics are in metrics. The errors in
+// describeErrors are returned by the next calls to DescribeContainerGroups.
+type fakeECI struct {
+	eciAPI
+
+	cgs              []eci.ContainerGroup
+	describeRequests []*eci.DescribeContainerGroupsRequest
+	describeErrors   []error
+	webSocketURI     string
+	execRequests     []*eci.ExecContainerCommandRequest
+	metrics          map[string][]eci.Record
+	metricRequests   []*eci.DescribeMultiContainerGroupMetricRequest
+}
+
+func (f *fakeECI) DescribeContainerGroups(request *eci.DescribeContainerGroupsRequest) (*eci.DescribeContainerGroupsResponse, error) {
+	f.describeRequests = append(f.describeRequests, request)
+	if len(f.describeErrors) > 0 {
+		err := f.describeErrors[0]
+		f.describeErrors = f.describeErrors[1:]
+		return nil, err
+	}
+
+	response := &eci.DescribeContainerGroupsResponse{}
+	for i := range f.cgs {
+		cg := &f.cgs[i]
+		if request.ContainerGroupName != "" && request.ContainerGroupName != cg.ContainerGroupName {
+			continue
+		}
+		if request.ContainerGroupId != "" && request.ContainerGroupId != cg.ContainerGroupId {
+			continue
+		}
+		if request.Tags != nil && !hasTags(cg, *request.Tags) {
+			continue
+		}
+		response.ContainerGroups = append(response.ContainerGroups, *cg)
+	}
+	return response, nil
+}
+
+func hasTags(cg *eci.ContainerGroup, tags []eci.DescribeContainerGroupsTag) bool {
+	for _, t := range tags {
+		if getECITagValue(cg, t.Key) != t.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeECI) ExecContainerCommand(request *eci.ExecContainerCommandRequest) (*eci.ExecContainerCommandResponse, error) {
+	f.execRequests = append(f.execRequests, request)
+	return &eci.ExecContainerCommandResponse{WebSocketUri: f.webSocketURI}, nil
+}
+
+func (f *fakeECI) DescribeMultiContainerGroupMetric(request *eci.DescribeMultiContainerGroupMetricRequest) (*eci.DescribeMultiContainerGroupMetricResponse, error) {
+	f.metricRequests = append(f.metricRequests, request)
+
+	var ids []string
+	if err := json.Unmarshal([]byte(request.ContainerGroupIds), &ids); err != nil {
+		return nil, err
+	}
+	response := &eci.DescribeMultiContainerGroupMetricResponse{}
+	for _, id := range ids {
+		response.MonitorDatas = append(response.MonitorDatas, eci.MonitorData{ContainerGroupId: id, Records: f.metrics[id]})
+	}
+	return response, nil
+}
+
+func newFakeECIProvider(webSocketURI string) (*ECIProvider, *fakeECI) {
+	f := &fakeECI{
+		cgs:          []eci.ContainerGroup{newFakeContainerGroup("eci-1", "ns", "pod")},
+		webSocketURI: webSocketURI,
+	}
+	return &ECIProvider{eciClient: f, nodeName: "vk", clusterName: "default"}, f
+}
+
+func newFakeContainerGroup(id, namespace, name string) eci.ContainerGroup {
+	return eci.ContainerGroup{
+		ContainerGroupId:   id,
+		ContainerGroupName: namespace + "-" + name,
+		Status:             eciStatusRunning,
+		Containers: []eci.Container{{
+			Name:         "nginx",
+			CurrentState: eci.ContainerState{State: "Running", StartTime: "2019-05-15T03:00:00Z"},
+		}},
+		Tags: []eci.Tag{
+			{Key: "NodeName", Value: "vk"},
+			{Key: "NameSpace", Value: namespace},
+			{Key: "PodName", Value: name},
+			{Key: "UID", Value: "uid-" + id},
+		},
+	}
+}
@@ -0,0 +1,177 @@
+package alibabacloud
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/alibabacloud/eci"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+const (
+	// metricsBatchSize is the number of container groups whose metrics are
+	// requested in a single DescribeMultiContainerGroupMetric call.
+	metricsBatchSize = 20
+
+	eciStatusRunning = "Running"
+)
+
+// GetStatsSummary returns the stats summary for pods running on ECI.
+//
+// The metrics of the running container groups of the node are requested in
+// batches, and the summary is cached so that frequent scrapes don't add calls
+// to the ECI API.
+func (p *ECIProvider) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
+	return p.metrics.Get(ctx, p.statsSummary)
+}
+
+func (p *ECIProvider) statsSummary(ctx context.Context) (*stats.Summary, error) {
+	cgs, err := p.listContainerGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make(map[string]*v1.Pod)
+	ids := make([]string, 0, len(cgs))
+	for i := range cgs {
+		if cgs[i].Status != eciStatusRunning {
+			continue
+		}
+		pod, err := containerGroupToPod(&cgs[i])
+		if err != nil {
+			log.G(ctx).WithField("containerGroup", cgs[i].ContainerGroupId).WithError(err).Warn("Error converting container group to pod")
+			continue
+		}
+		pods[cgs[i].ContainerGroupId] = pod
+		ids = append(ids, cgs[i].ContainerGroupId)
+	}
+
+	s := &stats.Summary{
+		Node: stats.NodeStats{NodeName: p.nodeName},
+		Pods: make([]stats.PodStats, 0, len(ids)),
+	}
+	for start := 0; start < len(ids); start += metricsBatchSize {
+		end := start + metricsBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		datas, err := p.describeContainerGroupMetrics(ctx, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range datas {
+			pod, ok := pods[data.ContainerGroupId]
+			if !ok || len(data.Records) == 0 {
+				continue
+			}
+			// Only the last record is reported.
+			s.Pods = append(s.Pods, podStats(pod, &data.Records[len(data.Records)-1]))
+		}
+	}
+
+	return s, nil
+}
+
+func (p *ECIProvider) describeContainerGroupMetrics(ctx context.Context, ids []string) ([]eci.MonitorData, error) {
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	request := eci.CreateDescribeMultiContainerGroupMetricRequest()
+	request.ContainerGroupIds = string(b)
+
+	var response *eci.DescribeMultiContainerGroupMetricResponse
+	err = retryECI(ctx, func() (err error) {
+		response, err = p.eciClient.DescribeMultiContainerGroupMetric(request)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(wrapError(err), "error fetching container group metrics")
+	}
+	return response.MonitorDatas, nil
+}
+
+// podStats converts a metrics record of the container group of a pod.
+func podStats(pod *v1.Pod, record *eci.Record) stats.PodStats {
+	var t metav1.Time
+	if ts, err := time.Parse(timeFormat, record.Timestamp); err == nil {
+		t = metav1.NewTime(ts)
+	}
+
+	stat := stats.PodStats{
+		PodRef: stats.PodReference{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			UID:       string(pod.UID),
+		},
+		StartTime: pod.CreationTimestamp,
+		CPU:       cpuStats(t, record.CPU),
+		Memory:    memoryStats(t, record.Memory),
+	}
+
+	if interfaces := record.Network.Interfaces; len(interfaces) > 0 {
+		stat.Network = &stats.NetworkStats{Time: t}
+		for _, i := range interfaces {
+			stat.Network.Interfaces = append(stat.Network.Interfaces, stats.InterfaceStats{
+				Name:     i.Name,
+				RxBytes:  uint64Ptr(i.RxBytes),
+				RxErrors: uint64Ptr(i.RxErrors),
+				TxBytes:  uint64Ptr(i.TxBytes),
+				TxErrors: uint64Ptr(i.TxErrors),
+			})
+		}
+		// The first interface is the default one.
+		stat.Network.InterfaceStats = stat.Network.Interfaces[0]
+	}
+
+	startTimes := make(map[string]metav1.Time, len(pod.Status.ContainerStatuses))
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Running != nil {
+			startTimes[cs.Name] = cs.State.Running.StartedAt
+		}
+	}
+	for _, c := range record.Containers {
+		startTime, ok := startTimes[c.Name]
+		if !ok {
+			startTime = stat.StartTime
+		}
+		stat.Containers = append(stat.Containers, stats.ContainerStats{
+			Name:      c.Name,
+			StartTime: startTime,
+			CPU:       cpuStats(t, c.CPU),
+			Memory:    memoryStats(t, c.Memory),
+		})
+	}
+
+	return stat
+}
+
+func cpuStats(t metav1.Time, cpu eci.CPU) *stats.CPUStats {
+	return &stats.CPUStats{
+		Time:                 t,
+		UsageNanoCores:       uint64Ptr(cpu.UsageNanoCores),
+		UsageCoreNanoSeconds: uint64Ptr(cpu.UsageCoreNanoSeconds),
+	}
+}
+
+func memoryStats(t metav1.Time, memory eci.Memory) *stats.MemoryStats {
+	return &stats.MemoryStats{
+		Time:            t,
+		AvailableBytes:  uint64Ptr(memory.AvailableBytes),
+		UsageBytes:      uint64Ptr(memory.UsageBytes),
+		WorkingSetBytes: uint64Ptr(memory.WorkingSet),
+		RSSBytes:        uint64Ptr(memory.Rss),
+	}
+}
+
+func uint64Ptr(v int64) *uint64 {
+	u := uint64(v)
+	return &u
+}
@@ -0,0 +1,90 @@
+package alibabacloud
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers/alibabacloud/eci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestGetStatsSummary(t *testing.T) {
+	provider, f := newFakeECIProvider("")
+	f.metrics = map[string][]eci.Record{
+		"eci-1": {
+			{Timestamp: "2019-05-15T03:14:00Z"},
+			{
+				Timestamp: "2019-05-15T03:15:00Z",
+				CPU:       eci.CPU{UsageNanoCores: 200000000, UsageCoreNanoSeconds: 12000000000},
+				Memory:    eci.Memory{UsageBytes: 4096, WorkingSet: 2048, Rss: 1024, AvailableBytes: 8192},
+				Network: eci.Network{Interfaces: []eci.Interface{
+					{Name: "eth0", RxBytes: 100, TxBytes: 200},
+					{Name: "eth1", RxBytes: 1, TxBytes: 2},
+				}},
+				Containers: []eci.ContainerMetric{{
+					Name:   "nginx",
+					CPU:    eci.CPU{UsageNanoCores: 150000000},
+					Memory: eci.Memory{WorkingSet: 1536},
+				}},
+			},
+		},
+	}
+	stopped := newFakeContainerGroup("eci-2", "ns", "stopped")
+	stopped.Status = "Succeeded"
+	f.cgs = append(f.cgs, stopped)
+
+	summary, err := provider.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(summary.Node.NodeName, "vk"))
+	assert.Assert(t, is.Len(summary.Pods, 1), "only the running container group has stats")
+
+	stat := summary.Pods[0]
+	assert.Check(t, is.Equal(stat.PodRef.Namespace, "ns"))
+	assert.Check(t, is.Equal(stat.PodRef.Name, "pod"))
+	assert.Check(t, is.Equal(stat.PodRef.UID, "uid-eci-1"))
+	assert.Check(t, is.Equal(stat.CPU.Time.Time, time.Date(2019, 5, 15, 3, 15, 0, 0, time.UTC)))
+	assert.Check(t, is.Equal(*stat.CPU.UsageNanoCores, uint64(200000000)))
+	assert.Check(t, is.Equal(*stat.CPU.UsageCoreNanoSeconds, uint64(12000000000)))
+	assert.Check(t, is.Equal(*stat.Memory.WorkingSetBytes, uint64(2048)))
+	assert.Check(t, is.Equal(*stat.Memory.RSSBytes, uint64(1024)))
+	assert.Check(t, is.Equal(stat.Network.InterfaceStats.Name, "eth0"))
+	assert.Check(t, is.Equal(*stat.Network.RxBytes, uint64(100)))
+	assert.Check(t, is.Len(stat.Network.Interfaces, 2))
+
+	assert.Assert(t, is.Len(stat.Containers, 1))
+	assert.Check(t, is.Equal(stat.Containers[0].Name, "nginx"))
+	assert.Check(t, is.Equal(*stat.Containers[0].CPU.UsageNanoCores, uint64(150000000)))
+	assert.Check(t, is.Equal(*stat.Containers[0].Memory.WorkingSetBytes, uint64(1536)))
+	assert.Check(t, is.Equal(stat.Containers[0].StartTime.Time, time.Date(2019, 5, 15, 3, 0, 0, 0, time.UTC)))
+}
+
+func TestGetStatsSummaryBatchesAndCaches(t *testing.T) {
+	provider, f := newFakeECIProvider("")
+	f.metrics = make(map[string][]eci.Record)
+	for i := 2; i <= metricsBatchSize+5; i++ {
+		id := fmt.Sprintf("eci-%d", i)
+		f.cgs = append(f.cgs, newFakeContainerGroup(id, "ns", fmt.Sprintf("pod-%d", i)))
+	}
+	for _, cg := range f.cgs {
+		f.metrics[cg.ContainerGroupId] = []eci.Record{{Timestamp: "2019-05-15T03:15:00Z"}}
+	}
+
+	summary, err := provider.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(summary.Pods, metricsBatchSize+5))
+	assert.Check(t, is.Len(f.metricRequests, 2))
+
+	// Scrapes within the cache duration are served from the cache.
+	cached, err := provider.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, cached == summary)
+	assert.Check(t, is.Len(f.metricRequests, 2))
+
+	provider.metrics.MaxAge = time.Nanosecond
+	_, err = provider.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(f.metricRequests, 4))
+}
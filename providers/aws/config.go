@@ -44,6 +44,7 @@ type providerConfig struct {
 	ExecutionRoleArn        string
 	CloudWatchLogGroupName  string
 	PlatformVersion         string
+	SecretsBackend          string
 	OperatingSystem         string
 	CPU                     string
 	Memory                  string
@@ -106,6 +107,16 @@ func (p *FargateProvider) loadConfig(r io.Reader) error {
 		return fmt.Errorf("Execution role required if CloudWatch log group is specified")
 	}
 
+	switch config.SecretsBackend {
+	case fargate.SecretsBackendNone, fargate.SecretsBackendSecretsManager, fargate.SecretsBackendSSM:
+	default:
+		return fmt.Errorf("Invalid secrets backend %v, valid values are %q and %q",
+			config.SecretsBackend, fargate.SecretsBackendSecretsManager, fargate.SecretsBackendSSM)
+	}
+	if config.SecretsBackend != fargate.SecretsBackendNone && config.ExecutionRoleArn == "" {
+		return fmt.Errorf("Execution role required if a secrets backend is specified")
+	}
+
 	// Validate advertised capacity.
 	if q, err = resource.ParseQuantity(config.CPU); err != nil {
 		return fmt.Errorf("Invalid CPU value %v", config.CPU)
@@ -139,6 +150,7 @@ func (p *FargateProvider) loadConfig(r io.Reader) error {
 	p.executionRoleArn = config.ExecutionRoleArn
 	p.cloudWatchLogGroupName = config.CloudWatchLogGroupName
 	p.platformVersion = config.PlatformVersion
+	p.secretsBackend = config.SecretsBackend
 	p.operatingSystem = config.OperatingSystem
 	p.capacity.cpu = config.CPU
 	p.capacity.memory = config.Memory
@@ -0,0 +1,170 @@
+package aws_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	vkAWS "github.com/virtual-kubelet/virtual-kubelet/providers/aws"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate/fargatetest"
+	testutil "github.com/virtual-kubelet/virtual-kubelet/test/util"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const fakeConfig = `
+Region = "us-east-1"
+ClusterName = "vk-fake"
+Subnets = [ "subnet-1" ]
+ExecutionRoleArn = "arn:aws:iam::123456789012:role/vk-fake"
+CloudWatchLogGroupName = "/ecs/vk-fake"
+`
+
+// newFakeProvider creates a Fargate provider backed by the in-memory ECS and
+// CloudWatch Logs of the fargatetest package.
+func newFakeProvider(t *testing.T) (*vkAWS.FargateProvider, *fargatetest.ECS, *fargatetest.CloudWatchLogs) {
+	api := fargatetest.NewECS("us-east-1")
+	logsapi := fargatetest.NewCloudWatchLogs()
+	provider := newFakeProviderWithConfig(t, fakeConfig, nil, fargate.NewClient("us-east-1", api, logsapi))
+	return provider, api, logsapi
+}
+
+func newFakeProviderWithConfig(t *testing.T, config string, rm *manager.ResourceManager, client *fargate.Client) *vkAWS.FargateProvider {
+	f, err := ioutil.TempFile("", "fargate")
+	assert.NilError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(config)
+	assert.NilError(t, err)
+	assert.NilError(t, f.Close())
+
+	provider, err := vkAWS.NewFargateProvider(f.Name(), rm, "vk", "Linux", "1.2.3.4", 10250, vkAWS.WithFargateClient(client))
+	assert.NilError(t, err)
+	return provider
+}
+
+// TestFargateProviderPodLifecycleOffline runs a pod through its lifecycle
+// without AWS.
+func TestFargateProviderPodLifecycleOffline(t *testing.T) {
+	ctx := context.Background()
+	provider, api, logsapi := newFakeProvider(t)
+
+	pods, err := provider.GetPods(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(pods, 0))
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "echo", Namespace: "default", UID: "uid"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:    "echo-container",
+				Image:   "busybox",
+				Command: []string{"/bin/sh"},
+				Args:    []string{"-c", "echo Started"},
+			}},
+		},
+	}
+	assert.NilError(t, provider.CreatePod(ctx, pod))
+
+	status, err := provider.GetPodStatus(ctx, "default", "echo")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(status.Phase, v1.PodPending))
+
+	tasks, err := api.ListTasks(&ecs.ListTasksInput{Cluster: aws.String("vk-fake")})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(tasks.TaskArns, 1))
+	assert.NilError(t, api.SetTaskStatus(aws.StringValue(tasks.TaskArns[0]), "RUNNING", 0))
+
+	got, err := provider.GetPod(ctx, "default", "echo")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(got.Status.Phase, v1.PodRunning))
+	assert.Check(t, is.Equal(got.Spec.Containers[0].Image, "busybox"))
+
+	pods, err = provider.GetPods(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(pods, 1))
+
+	stream := "vk-podspec_vk-fake_default_echo_echo-container/echo-container/" + path.Base(aws.StringValue(tasks.TaskArns[0]))
+	logsapi.AddLogEvents("/ecs/vk-fake", stream, "Started")
+	logs, err := provider.GetContainerLogs(ctx, "default", "echo", "echo-container", 100)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(logs, "Started\n"))
+
+	assert.NilError(t, provider.DeletePod(ctx, pod))
+
+	_, err = provider.GetPod(ctx, "default", "echo")
+	assert.Check(t, strongerrors.IsNotFound(err))
+
+	pods, err = provider.GetPods(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(pods, 0))
+}
+
+// TestFargateProviderResolvesEnvironmentReferences creates a pod whose
+// environment references secrets, as passed to CreatePod by virtual-kubelet
+// when the provider resolves them: the references are kept and only plain
+// values are set.
+func TestFargateProviderResolvesEnvironmentReferences(t *testing.T) {
+	ctx := context.Background()
+	provider, _, _ := newFakeProvider(t)
+	var resolver providers.PodEnvironmentResolver = provider
+	assert.Check(t, !resolver.ResolvesEnvironmentReferences(), "references are resolved by virtual-kubelet without a secrets backend")
+
+	api := fargatetest.NewECS("us-east-1")
+	secretsapi := fargatetest.NewSecretsManager("us-east-1")
+	rm := testutil.FakeResourceManager(testutil.FakeSecret("default", "db", map[string]string{"password": "hunter2", "USER": "admin"}))
+	provider = newFakeProviderWithConfig(t, fakeConfig+`SecretsBackend = "secretsmanager"`+"\n", rm,
+		fargate.NewClient("us-east-1", api, fargatetest.NewCloudWatchLogs(), fargate.WithSecretsManagerAPI(secretsapi)))
+	assert.Check(t, provider.ResolvesEnvironmentReferences())
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:  "app",
+				Image: "app",
+				EnvFrom: []v1.EnvFromSource{{
+					SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "db"}},
+				}},
+				Env: []v1.EnvVar{
+					{Name: "USER", Value: "override"},
+					{Name: "DB_PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "db"},
+						Key:                  "password",
+					}}},
+				},
+			}},
+		},
+	}
+	assert.NilError(t, provider.CreatePod(ctx, pod))
+
+	defs := api.TaskDefinitions("ACTIVE")
+	assert.Assert(t, is.Len(defs, 1))
+	out, err := api.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: aws.String(defs[0])})
+	assert.NilError(t, err)
+	def := out.TaskDefinition.ContainerDefinitions[0]
+	env := make(map[string]string)
+	for _, kv := range def.Environment {
+		env[aws.StringValue(kv.Name)] = aws.StringValue(kv.Value)
+	}
+	// The variables of env override the keys of envFrom.
+	assert.Check(t, is.DeepEqual(env, map[string]string{"USER": "override"}))
+	var secrets []string
+	for _, s := range def.Secrets {
+		secrets = append(secrets, aws.StringValue(s.Name))
+	}
+	assert.Check(t, is.DeepEqual(secrets, []string{"password", "DB_PASSWORD"}))
+	assert.Check(t, is.Len(secretsapi.Secrets(), 2))
+
+	assert.NilError(t, provider.DeletePod(ctx, pod))
+	assert.Check(t, is.Len(secretsapi.Secrets(), 0))
+}
@@ -9,22 +9,58 @@ import (
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 )
 
 // Client communicates with the regional AWS Fargate service.
 type Client struct {
 	region  string
-	svc     *ecs.ECS
 	api     ecsiface.ECSAPI
 	logsapi cloudwatchlogsiface.CloudWatchLogsAPI
+
+	// The APIs secret values referenced by pods are stored with.
+	secretsapi secretsmanageriface.SecretsManagerAPI
+	ssmapi     ssmiface.SSMAPI
 }
 
-var client *Client
+// ClientOpt configures a Fargate client.
+type ClientOpt func(*Client)
 
-// NewClient creates a new Fargate client in the given region.
-func newClient(region string) (*Client, error) {
-	var client Client
+// WithSecretsManagerAPI sets the Secrets Manager API used by the
+// "secretsmanager" secrets backend.
+func WithSecretsManagerAPI(api secretsmanageriface.SecretsManagerAPI) ClientOpt {
+	return func(c *Client) {
+		c.secretsapi = api
+	}
+}
+
+// WithSSMAPI sets the Systems Manager API used by the "ssm" secrets backend.
+func WithSSMAPI(api ssmiface.SSMAPI) ClientOpt {
+	return func(c *Client) {
+		c.ssmapi = api
+	}
+}
 
+// NewClient creates a new Fargate client in the given region using the given
+// ECS and CloudWatch Logs APIs, such as the in-memory ones of the fargatetest
+// package.
+func NewClient(region string, api ecsiface.ECSAPI, logsapi cloudwatchlogsiface.CloudWatchLogsAPI, opts ...ClientOpt) *Client {
+	c := &Client{
+		region:  region,
+		api:     api,
+		logsapi: logsapi,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// newClient creates a new Fargate client in the given region.
+func newClient(region string) (*Client, error) {
 	// Initialize client session configuration.
 	config := aws.NewConfig()
 	config.Region = aws.String(region)
@@ -40,15 +76,13 @@ func newClient(region string) (*Client, error) {
 		return nil, err
 	}
 
-	// Create the Fargate service client.
-	client.region = region
-	client.svc = ecs.New(session)
-	client.api = client.svc
-
-	// Create the CloudWatch service client.
-	client.logsapi = cloudwatchlogs.New(session)
+	// Create the Fargate, CloudWatch, Secrets Manager and Systems Manager
+	// service clients.
+	client := NewClient(region, ecs.New(session), cloudwatchlogs.New(session),
+		WithSecretsManagerAPI(secretsmanager.New(session)),
+		WithSSMAPI(ssm.New(session)))
 
 	log.Println("Created Fargate service client.")
 
-	return &client, nil
+	return client, nil
 }
@@ -1,6 +1,7 @@
 package fargate
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -8,7 +9,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/cpuguy83/strongerrors"
 	k8sTypes "k8s.io/apimachinery/pkg/types"
@@ -29,6 +29,14 @@ type ClusterConfig struct {
 	ExecutionRoleArn        string
 	CloudWatchLogGroupName  string
 	PlatformVersion         string
+	// SecretsBackend is where the secret values referenced by pods are
+	// stored, one of the SecretsBackend constants.
+	SecretsBackend string
+	// ResourceManager looks up the secrets and config maps referenced by pods.
+	ResourceManager ResourceGetter
+	// Client is used to call Fargate. If nil, a client for the region is
+	// created from the default AWS credentials.
+	Client *Client
 }
 
 // Cluster represents a Fargate cluster.
@@ -43,7 +51,13 @@ type Cluster struct {
 	executionRoleArn        string
 	cloudWatchLogGroupName  string
 	platformVersion         string
+	client                  *Client
+	secrets                 secretStore
+	resources               ResourceGetter
 	pods                    map[string]*Pod
+	taskDefsMu              sync.Mutex
+	taskDefs                map[string]*taskDefinition
+	taskDefCalls            map[string]*taskDefinitionCall
 	sync.RWMutex
 }
 
@@ -61,10 +75,18 @@ func NewCluster(config *ClusterConfig) (*Cluster, error) {
 		return nil, fmt.Errorf("Fargate is not available in region %s", config.Region)
 	}
 
-	// Create the client to the regional Fargate service.
-	client, err = newClient(config.Region)
+	// Create the client to the regional Fargate service, unless one is given.
+	client := config.Client
+	if client == nil {
+		client, err = newClient(config.Region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Fargate client: %v", err)
+		}
+	}
+
+	secrets, err := newSecretStore(config.SecretsBackend, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Fargate client: %v", err)
+		return nil, err
 	}
 
 	// Initialize the cluster.
@@ -78,7 +100,12 @@ func NewCluster(config *ClusterConfig) (*Cluster, error) {
 		executionRoleArn:        config.ExecutionRoleArn,
 		cloudWatchLogGroupName:  config.CloudWatchLogGroupName,
 		platformVersion:         config.PlatformVersion,
+		client:                  client,
+		secrets:                 secrets,
+		resources:               config.ResourceManager,
 		pods:                    make(map[string]*Pod),
+		taskDefs:                make(map[string]*taskDefinition),
+		taskDefCalls:            make(map[string]*taskDefinitionCall),
 	}
 
 	// If a node name is not specified, use the Fargate cluster name.
@@ -112,7 +139,7 @@ func NewCluster(config *ClusterConfig) (*Cluster, error) {
 
 // Create creates a new Fargate cluster.
 func (c *Cluster) create() error {
-	api := client.api
+	api := c.client.api
 
 	input := &ecs.CreateClusterInput{
 		ClusterName: aws.String(c.name),
@@ -135,7 +162,7 @@ func (c *Cluster) create() error {
 
 // Describe loads information from an existing Fargate cluster.
 func (c *Cluster) describe() error {
-	api := client.api
+	api := c.client.api
 
 	input := &ecs.DescribeClustersInput{
 		Clusters: aws.StringSlice([]string{c.name}),
@@ -163,7 +190,7 @@ func (c *Cluster) describe() error {
 // Fargate. This is done during startup and whenever the local state is suspected to be out of sync
 // with the actual state in Fargate. Caching state locally minimizes the number of service calls.
 func (c *Cluster) loadPodState() error {
-	api := client.api
+	api := c.client.api
 
 	log.Printf("Loading pod state from cluster %s.", c.name)
 
@@ -213,6 +240,7 @@ func (c *Cluster) loadPodState() error {
 		describeTaskDefinitionOutput, err := api.DescribeTaskDefinition(
 			&ecs.DescribeTaskDefinitionInput{
 				TaskDefinition: task.TaskDefinitionArn,
+				Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
 			},
 		)
 
@@ -255,6 +283,7 @@ func (c *Cluster) loadPodState() error {
 		}
 
 		pods[tag] = pod
+		c.trackTaskDefinition(taskDef, describeTaskDefinitionOutput.Tags)
 	}
 
 	// Update local state.
@@ -262,6 +291,11 @@ func (c *Cluster) loadPodState() error {
 	c.pods = pods
 	c.Unlock()
 
+	// Deregister the task definitions of the pods which are gone.
+	if err := c.collectTaskDefinitions(); err != nil {
+		log.Println(err)
+	}
+
 	return nil
 }
 
@@ -296,9 +330,15 @@ func (c *Cluster) GetPods() ([]*Pod, error) {
 // InsertPod inserts a Kubernetes pod to this cluster.
 func (c *Cluster) InsertPod(pod *Pod, tag string) {
 	c.Lock()
-	defer c.Unlock()
-
+	prev, ok := c.pods[tag]
 	c.pods[tag] = pod
+	c.Unlock()
+
+	// A pod replaced before it was stopped, e.g. when starting it failed,
+	// releases its task definition.
+	if ok && prev != pod && prev.taskDefArn != "" {
+		c.releaseTaskDefinition(prev.taskDefArn)
+	}
 }
 
 // RemovePod removes a Kubernetes pod from this cluster.
@@ -309,47 +349,13 @@ func (c *Cluster) RemovePod(tag string) {
 	delete(c.pods, tag)
 }
 
-// GetContainerLogs returns the logs of a container from this cluster.
+// GetContainerLogs returns the last tail lines of the logs of a container
+// from this cluster.
 func (c *Cluster) GetContainerLogs(namespace, podName, containerName string, tail int) (string, error) {
-	if c.cloudWatchLogGroupName == "" {
-		return "", fmt.Errorf("logs not configured, please specify a \"CloudWatchLogGroupName\"")
-	}
-
-	prefix := fmt.Sprintf("%s_%s", buildTaskDefinitionTag(c.name, namespace, podName), containerName)
-	describeResult, err := client.logsapi.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{
-		LogGroupName:        aws.String(c.cloudWatchLogGroupName),
-		LogStreamNamePrefix: aws.String(prefix),
-	})
+	var logs strings.Builder
+	err := c.StreamContainerLogs(context.Background(), namespace, podName, containerName, LogOptions{Tail: tail}, &logs)
 	if err != nil {
 		return "", err
 	}
-
-	// Nothing logged yet.
-	if len(describeResult.LogStreams) == 0 {
-		return "", nil
-	}
-
-	logs := ""
-
-	err = client.logsapi.GetLogEventsPages(&cloudwatchlogs.GetLogEventsInput{
-		Limit:         aws.Int64(int64(tail)),
-		LogGroupName:  aws.String(c.cloudWatchLogGroupName),
-		LogStreamName: describeResult.LogStreams[0].LogStreamName,
-	}, func(page *cloudwatchlogs.GetLogEventsOutput, lastPage bool) bool {
-		for _, event := range page.Events {
-			logs += *event.Message
-			logs += "\n"
-		}
-
-		// Due to a issue in the aws-sdk last page is never true, but the we can stop
-		// as soon as no further results are returned.
-		// See https://github.com/aws/aws-sdk-ruby/pull/730.
-		return len(page.Events) > 0
-	})
-
-	if err != nil {
-		return "", err
-	}
-
-	return logs, nil
+	return logs.String(), nil
 }
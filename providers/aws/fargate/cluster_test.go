@@ -0,0 +1,113 @@
+package fargate
+
+import (
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate/fargatetest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testRegion   = "us-east-1"
+	testLogGroup = "/ecs/vk"
+)
+
+func newTestCluster(t *testing.T, api *fargatetest.ECS, logsapi *fargatetest.CloudWatchLogs) *Cluster {
+	cluster, err := NewCluster(&ClusterConfig{
+		Region:                 testRegion,
+		Name:                   "vk",
+		NodeName:               "vk-node",
+		Subnets:                []string{"subnet-1"},
+		CloudWatchLogGroupName: testLogGroup,
+		Client:                 NewClient(testRegion, api, logsapi),
+	})
+	assert.NilError(t, err)
+	return cluster
+}
+
+// testLogStream returns the name of the log stream of a container of the
+// pod's current task.
+func testLogStream(pod *Pod, containerName string) string {
+	return fmt.Sprintf("%s_%s/%s/%s", pod.buildTaskDefinitionTag(), containerName, containerName, path.Base(pod.taskArn))
+}
+
+func newTestPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			UID:       "uid",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:    "nginx",
+				Image:   "nginx",
+				Command: []string{"nginx"},
+				Args:    []string{"-g", "daemon off;"},
+				Env:     []corev1.EnvVar{{Name: "A", Value: "a"}},
+			}},
+		},
+	}
+}
+
+// TestClusterPodLifecycle runs a pod through its lifecycle against the
+// in-memory ECS and CloudWatch Logs.
+func TestClusterPodLifecycle(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	logsapi := fargatetest.NewCloudWatchLogs()
+	cluster := newTestCluster(t, api, logsapi)
+	assert.Check(t, is.Equal(cluster.arn, "arn:aws:ecs:us-east-1:123456789012:cluster/vk"))
+
+	pod, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.NilError(t, pod.Start())
+
+	// The pod is pending until its task runs.
+	assert.Check(t, is.Equal(pod.GetStatus().Phase, corev1.PodPending))
+	assert.NilError(t, api.SetTaskStatus(pod.taskArn, taskStatusRunning, 0))
+
+	spec, err := cluster.pods[pod.buildTaskDefinitionTag()].GetSpec()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(spec.Name, "web"))
+	assert.Check(t, is.Equal(spec.Spec.NodeName, "vk-node"))
+	assert.Assert(t, is.Len(spec.Spec.Containers, 1))
+	assert.Check(t, is.DeepEqual(spec.Spec.Containers[0].Args, []string{"-g", "daemon off;"}))
+	assert.Check(t, is.Equal(spec.Status.Phase, corev1.PodRunning))
+	assert.Check(t, spec.Status.PodIP != "", "the pod should have the IP address of its ENI")
+
+	// The logs of the container are read from the log stream of its task.
+	logsapi.AddLogEvents(testLogGroup, testLogStream(pod, "nginx"), "one", "two", "three")
+
+	logs, err := cluster.GetContainerLogs("default", "web", "nginx", 2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(logs, "two\nthree\n"))
+
+	logs, err = cluster.GetContainerLogs("default", "other", "nginx", 2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(logs, ""))
+
+	// A restarted provider finds the running pod.
+	restarted := newTestCluster(t, api, logsapi)
+	found, err := restarted.GetPod("default", "web")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(found.taskArn, pod.taskArn))
+	assert.Check(t, is.Equal(string(found.uid), pod.buildTaskTag()))
+
+	// Stopping the pod stops its task and deregisters its task definition.
+	assert.NilError(t, pod.Stop())
+	_, err = cluster.GetPod("default", "web")
+	assert.Check(t, err != nil)
+	assert.Check(t, is.Equal(pod.GetStatus().Phase, corev1.PodSucceeded))
+	assert.Check(t, is.Len(api.TaskDefinitions("ACTIVE"), 0))
+	assert.Check(t, is.Len(api.TaskDefinitions("INACTIVE"), 1))
+
+	restarted = newTestCluster(t, api, logsapi)
+	pods, err := restarted.GetPods()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(pods, 0))
+}
@@ -52,9 +52,13 @@ func newContainer(spec *corev1.Container) (*container, error) {
 		cntr.definition.WorkingDirectory = aws.String(spec.WorkingDir)
 	}
 
-	// Add environment variables.
+	// Add environment variables. Those set from secrets and config maps are
+	// added by their pod.
 	if spec.Env != nil {
 		for _, env := range spec.Env {
+			if env.ValueFrom != nil {
+				continue
+			}
 			cntr.definition.Environment = append(
 				cntr.definition.Environment,
 				&ecs.KeyValuePair{
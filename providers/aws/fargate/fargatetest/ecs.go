@@ -0,0 +1,435 @@
+// Package fargatetest provides in-memory stand-ins for the ECS and CloudWatch
+// Logs APIs used by the Fargate provider, so that it can be tested without AWS.
+package fargatetest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+const (
+	// AccountID is the AWS account ID used in the ARNs of the fakes.
+	AccountID = "123456789012"
+
+	clusterStatusActive        = "ACTIVE"
+	taskDefinitionStatusActive = "ACTIVE"
+	taskDefinitionStatusGone   = "INACTIVE"
+	taskStatusProvisioning     = "PROVISIONING"
+	taskStatusStopped          = "STOPPED"
+)
+
+// ECS is an in-memory implementation of the ECS API calls made by the Fargate
+// provider. Calling any other method panics.
+//
+// Tasks are started in the PROVISIONING status, SetTaskStatus moves them
+// through their lifecycle.
+type ECS struct {
+	// ecsiface.ECSAPI is embedded to implement the interface; it is nil.
+	ecsiface.ECSAPI
+
+	region string
+
+	mu              sync.Mutex
+	clusters        map[string]*ecs.Cluster
+	taskDefinitions map[string]*ecs.TaskDefinition
+	revisions       map[string]int64
+	taskDefTags     map[string][]*ecs.Tag
+	tasks           map[string]*ecs.Task
+	taskClusters    map[string]string
+	nextID          int
+}
+
+// NewECS creates an empty ECS stand-in for the region.
+func NewECS(region string) *ECS {
+	return &ECS{
+		region:          region,
+		clusters:        make(map[string]*ecs.Cluster),
+		taskDefinitions: make(map[string]*ecs.TaskDefinition),
+		revisions:       make(map[string]int64),
+		taskDefTags:     make(map[string][]*ecs.Tag),
+		tasks:           make(map[string]*ecs.Task),
+		taskClusters:    make(map[string]string),
+	}
+}
+
+func (f *ECS) arn(resource string) string {
+	return fmt.Sprintf("arn:aws:ecs:%s:%s:%s", f.region, AccountID, resource)
+}
+
+// clusterName returns the name of the cluster identified by a name or an ARN.
+func clusterName(cluster *string) string {
+	name := aws.StringValue(cluster)
+	if name == "" {
+		return "default"
+	}
+	if i := strings.LastIndex(name, "cluster/"); i >= 0 {
+		return name[i+len("cluster/"):]
+	}
+	return name
+}
+
+// CreateCluster creates a cluster, or returns the existing one.
+func (f *ECS) CreateCluster(input *ecs.CreateClusterInput) (*ecs.CreateClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := clusterName(input.ClusterName)
+	c, ok := f.clusters[name]
+	if !ok {
+		c = &ecs.Cluster{
+			ClusterArn:  aws.String(f.arn("cluster/" + name)),
+			ClusterName: aws.String(name),
+			Status:      aws.String(clusterStatusActive),
+		}
+		f.clusters[name] = c
+	}
+	return &ecs.CreateClusterOutput{Cluster: c}, nil
+}
+
+// DescribeClusters describes the clusters, reporting the missing ones as
+// failures.
+func (f *ECS) DescribeClusters(input *ecs.DescribeClustersInput) (*ecs.DescribeClustersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output := &ecs.DescribeClustersOutput{}
+	for _, cluster := range input.Clusters {
+		if c, ok := f.clusters[clusterName(cluster)]; ok {
+			output.Clusters = append(output.Clusters, c)
+			continue
+		}
+		output.Failures = append(output.Failures, &ecs.Failure{
+			Arn:    aws.String(f.arn("cluster/" + clusterName(cluster))),
+			Reason: aws.String("MISSING"),
+		})
+	}
+	return output, nil
+}
+
+// RegisterTaskDefinition registers a new revision of the task definition
+// family.
+func (f *ECS) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	family := aws.StringValue(input.Family)
+	if family == "" {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "family is required", nil)
+	}
+	if len(input.ContainerDefinitions) == 0 {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "container definitions are required", nil)
+	}
+
+	f.revisions[family]++
+	revision := f.revisions[family]
+
+	td := &ecs.TaskDefinition{
+		TaskDefinitionArn:       aws.String(f.arn(fmt.Sprintf("task-definition/%s:%d", family, revision))),
+		Family:                  input.Family,
+		Revision:                aws.Int64(revision),
+		Status:                  aws.String(taskDefinitionStatusActive),
+		ContainerDefinitions:    input.ContainerDefinitions,
+		Cpu:                     input.Cpu,
+		Memory:                  input.Memory,
+		ExecutionRoleArn:        input.ExecutionRoleArn,
+		TaskRoleArn:             input.TaskRoleArn,
+		NetworkMode:             input.NetworkMode,
+		RequiresCompatibilities: input.RequiresCompatibilities,
+		Volumes:                 input.Volumes,
+	}
+	f.taskDefinitions[aws.StringValue(td.TaskDefinitionArn)] = td
+	f.taskDefTags[aws.StringValue(td.TaskDefinitionArn)] = input.Tags
+
+	return &ecs.RegisterTaskDefinitionOutput{TaskDefinition: td, Tags: input.Tags}, nil
+}
+
+// taskDefinition returns the task definition identified by an ARN, a
+// family:revision or the latest active revision of a family.
+// f.mu must be held.
+func (f *ECS) taskDefinition(id string) (*ecs.TaskDefinition, error) {
+	if td, ok := f.taskDefinitions[id]; ok {
+		return td, nil
+	}
+
+	family, revision := id, f.revisions[id]
+	if i := strings.LastIndex(id, ":"); i >= 0 && !strings.HasPrefix(id, "arn:") {
+		family = id[:i]
+		fmt.Sscanf(id[i+1:], "%d", &revision)
+	}
+	if td, ok := f.taskDefinitions[f.arn(fmt.Sprintf("task-definition/%s:%d", family, revision))]; ok {
+		return td, nil
+	}
+
+	return nil, awserr.New(ecs.ErrCodeClientException, "Unable to describe task definition.", nil)
+}
+
+// DescribeTaskDefinition describes a task definition, and its tags if
+// requested.
+func (f *ECS) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	td, err := f.taskDefinition(aws.StringValue(input.TaskDefinition))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ecs.DescribeTaskDefinitionOutput{TaskDefinition: td}
+	for _, field := range aws.StringValueSlice(input.Include) {
+		if field == ecs.TaskDefinitionFieldTags {
+			output.Tags = f.taskDefTags[aws.StringValue(td.TaskDefinitionArn)]
+		}
+	}
+	return output, nil
+}
+
+// ListTaskDefinitions lists the task definitions with the family prefix and
+// status, in a single page.
+func (f *ECS) ListTaskDefinitions(input *ecs.ListTaskDefinitionsInput) (*ecs.ListTaskDefinitionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := aws.StringValue(input.Status)
+	if status == "" {
+		status = ecs.TaskDefinitionStatusActive
+	}
+
+	var arns []string
+	for arn, td := range f.taskDefinitions {
+		if aws.StringValue(td.Status) == status && strings.HasPrefix(aws.StringValue(td.Family), aws.StringValue(input.FamilyPrefix)) {
+			arns = append(arns, arn)
+		}
+	}
+	sort.Strings(arns)
+
+	// The next token is the index of the first ARN of the page.
+	start := 0
+	if token := aws.StringValue(input.NextToken); token != "" {
+		var err error
+		if start, err = strconv.Atoi(token); err != nil || start > len(arns) {
+			return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "invalid next token", nil)
+		}
+	}
+	end := len(arns)
+	if max := int(aws.Int64Value(input.MaxResults)); max > 0 && start+max < end {
+		end = start + max
+	}
+
+	output := &ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: aws.StringSlice(arns[start:end])}
+	if end < len(arns) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+// ListTaskDefinitionsPages calls fn with the pages of ListTaskDefinitions
+// until it returns false.
+func (f *ECS) ListTaskDefinitionsPages(input *ecs.ListTaskDefinitionsInput, fn func(*ecs.ListTaskDefinitionsOutput, bool) bool) error {
+	in := *input
+	for {
+		output, err := f.ListTaskDefinitions(&in)
+		if err != nil {
+			return err
+		}
+		lastPage := output.NextToken == nil
+		if !fn(output, lastPage) || lastPage {
+			return nil
+		}
+		in.NextToken = output.NextToken
+	}
+}
+
+// DeregisterTaskDefinition marks a task definition INACTIVE.
+func (f *ECS) DeregisterTaskDefinition(input *ecs.DeregisterTaskDefinitionInput) (*ecs.DeregisterTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	td, err := f.taskDefinition(aws.StringValue(input.TaskDefinition))
+	if err != nil {
+		return nil, err
+	}
+	td.Status = aws.String(taskDefinitionStatusGone)
+	return &ecs.DeregisterTaskDefinitionOutput{TaskDefinition: td}, nil
+}
+
+// TaskDefinitions returns the ARNs of the registered task definitions with
+// the given status, ACTIVE or INACTIVE.
+func (f *ECS) TaskDefinitions(status string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var arns []string
+	for arn, td := range f.taskDefinitions {
+		if aws.StringValue(td.Status) == status {
+			arns = append(arns, arn)
+		}
+	}
+	return arns
+}
+
+// RunTask starts a task of the task definition in the PROVISIONING status.
+func (f *ECS) RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster := clusterName(input.Cluster)
+	if _, ok := f.clusters[cluster]; !ok {
+		return nil, awserr.New(ecs.ErrCodeClusterNotFoundException, "Cluster not found.", nil)
+	}
+
+	td, err := f.taskDefinition(aws.StringValue(input.TaskDefinition))
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(td.Status) != taskDefinitionStatusActive {
+		return nil, awserr.New(ecs.ErrCodeClientException, "TaskDefinition is inactive", nil)
+	}
+
+	f.nextID++
+	now := time.Now()
+	task := &ecs.Task{
+		TaskArn:           aws.String(f.arn(fmt.Sprintf("task/%s/%032d", cluster, f.nextID))),
+		ClusterArn:        f.clusters[cluster].ClusterArn,
+		TaskDefinitionArn: td.TaskDefinitionArn,
+		StartedBy:         input.StartedBy,
+		LaunchType:        input.LaunchType,
+		LastStatus:        aws.String(taskStatusProvisioning),
+		DesiredStatus:     aws.String(ecs.DesiredStatusRunning),
+		Cpu:               td.Cpu,
+		Memory:            td.Memory,
+		CreatedAt:         &now,
+		Attachments: []*ecs.Attachment{{
+			Type: aws.String("ElasticNetworkInterface"),
+			Details: []*ecs.KeyValuePair{{
+				Name:  aws.String("privateIPv4Address"),
+				Value: aws.String(fmt.Sprintf("10.0.%d.%d", f.nextID/250, f.nextID%250+1)),
+			}},
+		}},
+	}
+	for _, cd := range td.ContainerDefinitions {
+		task.Containers = append(task.Containers, &ecs.Container{
+			Name:       cd.Name,
+			TaskArn:    task.TaskArn,
+			LastStatus: aws.String(taskStatusProvisioning),
+		})
+	}
+
+	f.tasks[aws.StringValue(task.TaskArn)] = task
+	f.taskClusters[aws.StringValue(task.TaskArn)] = cluster
+
+	return &ecs.RunTaskOutput{Tasks: []*ecs.Task{task}}, nil
+}
+
+// SetTaskStatus sets the status of a task and of its containers, as Fargate
+// does when the task makes progress. Stopped containers exit with exitCode.
+func (f *ECS) SetTaskStatus(taskArn string, status string, exitCode int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	task, ok := f.tasks[taskArn]
+	if !ok {
+		return fmt.Errorf("task %s not found", taskArn)
+	}
+
+	f.setTaskStatus(task, status, exitCode)
+	return nil
+}
+
+func (f *ECS) setTaskStatus(task *ecs.Task, status string, exitCode int64) {
+	task.LastStatus = aws.String(status)
+	for _, c := range task.Containers {
+		c.LastStatus = aws.String(status)
+		if status == taskStatusStopped {
+			c.ExitCode = aws.Int64(exitCode)
+		}
+	}
+	if status == taskStatusStopped {
+		now := time.Now()
+		task.DesiredStatus = aws.String(ecs.DesiredStatusStopped)
+		task.StoppedAt = &now
+	}
+}
+
+// StopTask stops a task.
+func (f *ECS) StopTask(input *ecs.StopTaskInput) (*ecs.StopTaskOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	task, ok := f.tasks[aws.StringValue(input.Task)]
+	if !ok || f.taskClusters[aws.StringValue(input.Task)] != clusterName(input.Cluster) {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "The referenced task was not found.", nil)
+	}
+
+	task.StoppedReason = input.Reason
+	f.setTaskStatus(task, taskStatusStopped, 0)
+
+	return &ecs.StopTaskOutput{Task: task}, nil
+}
+
+// DescribeTasks describes the tasks, reporting the missing ones as failures.
+func (f *ECS) DescribeTasks(input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster := clusterName(input.Cluster)
+	output := &ecs.DescribeTasksOutput{}
+	for _, arn := range input.Tasks {
+		if task, ok := f.tasks[aws.StringValue(arn)]; ok && f.taskClusters[aws.StringValue(arn)] == cluster {
+			output.Tasks = append(output.Tasks, task)
+			continue
+		}
+		output.Failures = append(output.Failures, &ecs.Failure{
+			Arn:    arn,
+			Reason: aws.String("MISSING"),
+		})
+	}
+	return output, nil
+}
+
+// ListTasks lists the tasks of a cluster, filtered by desired status and
+// started by, in a single page.
+func (f *ECS) ListTasks(input *ecs.ListTasksInput) (*ecs.ListTasksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster := clusterName(input.Cluster)
+	if _, ok := f.clusters[cluster]; !ok {
+		return nil, awserr.New(ecs.ErrCodeClusterNotFoundException, "Cluster not found.", nil)
+	}
+
+	desiredStatus := aws.StringValue(input.DesiredStatus)
+	if desiredStatus == "" {
+		desiredStatus = ecs.DesiredStatusRunning
+	}
+
+	output := &ecs.ListTasksOutput{}
+	for arn, task := range f.tasks {
+		if f.taskClusters[arn] != cluster || aws.StringValue(task.DesiredStatus) != desiredStatus {
+			continue
+		}
+		if input.StartedBy != nil && aws.StringValue(task.StartedBy) != aws.StringValue(input.StartedBy) {
+			continue
+		}
+		output.TaskArns = append(output.TaskArns, aws.String(arn))
+	}
+	return output, nil
+}
+
+// ListTasksPages calls fn with the single page of ListTasks.
+func (f *ECS) ListTasksPages(input *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool) error {
+	output, err := f.ListTasks(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
@@ -0,0 +1,160 @@
+package fargatetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+)
+
+// CloudWatchLogs is an in-memory implementation of the CloudWatch Logs API
+// calls made by the Fargate provider. Calling any other method panics.
+type CloudWatchLogs struct {
+	// cloudwatchlogsiface.CloudWatchLogsAPI is embedded to implement the
+	// interface; it is nil.
+	cloudwatchlogsiface.CloudWatchLogsAPI
+
+	mu     sync.Mutex
+	groups map[string]map[string][]*cloudwatchlogs.OutputLogEvent
+}
+
+// NewCloudWatchLogs creates an empty CloudWatch Logs stand-in.
+func NewCloudWatchLogs() *CloudWatchLogs {
+	return &CloudWatchLogs{
+		groups: make(map[string]map[string][]*cloudwatchlogs.OutputLogEvent),
+	}
+}
+
+// AddLogEvents appends messages logged now to a log stream, creating the log
+// group and stream as needed.
+func (f *CloudWatchLogs) AddLogEvents(group, stream string, messages ...string) {
+	f.AddLogEventsAt(group, stream, time.Now(), messages...)
+}
+
+// AddLogEventsAt appends messages logged at t to a log stream, creating the
+// log group and stream as needed.
+func (f *CloudWatchLogs) AddLogEventsAt(group, stream string, t time.Time, messages ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	streams, ok := f.groups[group]
+	if !ok {
+		streams = make(map[string][]*cloudwatchlogs.OutputLogEvent)
+		f.groups[group] = streams
+	}
+
+	ts := aws.TimeUnixMilli(t)
+	for _, m := range messages {
+		streams[stream] = append(streams[stream], &cloudwatchlogs.OutputLogEvent{
+			Message:       aws.String(m),
+			Timestamp:     aws.Int64(ts),
+			IngestionTime: aws.Int64(ts),
+		})
+	}
+}
+
+// DescribeLogStreams lists the streams of a log group with the given prefix,
+// in a single page.
+func (f *CloudWatchLogs) DescribeLogStreams(input *cloudwatchlogs.DescribeLogStreamsInput) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	streams, ok := f.groups[aws.StringValue(input.LogGroupName)]
+	if !ok {
+		return nil, awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "The specified log group does not exist.", nil)
+	}
+
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	for name, events := range streams {
+		if !strings.HasPrefix(name, aws.StringValue(input.LogStreamNamePrefix)) {
+			continue
+		}
+		stream := &cloudwatchlogs.LogStream{LogStreamName: aws.String(name)}
+		if len(events) > 0 {
+			stream.FirstEventTimestamp = events[0].Timestamp
+			stream.LastEventTimestamp = events[len(events)-1].Timestamp
+		}
+		output.LogStreams = append(output.LogStreams, stream)
+	}
+	sort.Slice(output.LogStreams, func(i, j int) bool {
+		return aws.StringValue(output.LogStreams[i].LogStreamName) < aws.StringValue(output.LogStreams[j].LogStreamName)
+	})
+	return output, nil
+}
+
+// DescribeLogStreamsPages calls fn with the single page of DescribeLogStreams.
+func (f *CloudWatchLogs) DescribeLogStreamsPages(input *cloudwatchlogs.DescribeLogStreamsInput, fn func(*cloudwatchlogs.DescribeLogStreamsOutput, bool) bool) error {
+	output, err := f.DescribeLogStreams(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+// GetLogEvents returns the events of a log stream following NextToken, or
+// from its start if StartFromHead is set, or else its last events. At most
+// Limit events are returned. Like CloudWatch Logs, the forward token of the
+// last page is the token it was requested with.
+func (f *CloudWatchLogs) GetLogEvents(input *cloudwatchlogs.GetLogEventsInput) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events, ok := f.groups[aws.StringValue(input.LogGroupName)][aws.StringValue(input.LogStreamName)]
+	if !ok {
+		return nil, awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "The specified log stream does not exist.", nil)
+	}
+
+	limit := len(events)
+	if input.Limit != nil && int(*input.Limit) < limit {
+		limit = int(*input.Limit)
+	}
+
+	var start int
+	switch {
+	case input.NextToken != nil:
+		if _, err := fmt.Sscanf(*input.NextToken, "f/%d", &start); err != nil || start > len(events) {
+			return nil, awserr.New(cloudwatchlogs.ErrCodeInvalidParameterException, "The specified nextToken is invalid.", nil)
+		}
+	case aws.BoolValue(input.StartFromHead):
+		for start < len(events) && aws.Int64Value(events[start].Timestamp) < aws.Int64Value(input.StartTime) {
+			start++
+		}
+	default:
+		start = len(events) - limit
+	}
+	end := start + limit
+	if end > len(events) {
+		end = len(events)
+	}
+
+	return &cloudwatchlogs.GetLogEventsOutput{
+		Events:            append([]*cloudwatchlogs.OutputLogEvent(nil), events[start:end]...),
+		NextForwardToken:  aws.String(fmt.Sprintf("f/%d", end)),
+		NextBackwardToken: aws.String(fmt.Sprintf("b/%d", start)),
+	}, nil
+}
+
+// GetLogEventsPages calls fn with the pages of GetLogEvents until fn returns
+// false or the forward token stops changing.
+func (f *CloudWatchLogs) GetLogEventsPages(input *cloudwatchlogs.GetLogEventsInput, fn func(*cloudwatchlogs.GetLogEventsOutput, bool) bool) error {
+	page := *input
+	for {
+		output, err := f.GetLogEvents(&page)
+		if err != nil {
+			return err
+		}
+
+		lastPage := page.NextToken != nil && aws.StringValue(output.NextForwardToken) == aws.StringValue(page.NextToken)
+		if !fn(output, lastPage) || lastPage {
+			return nil
+		}
+		page.NextToken = output.NextForwardToken
+	}
+}
@@ -0,0 +1,192 @@
+package fargatetest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// SecretsManager is an in-memory implementation of the Secrets Manager API
+// calls made by the Fargate provider. Calling any other method panics.
+type SecretsManager struct {
+	// secretsmanageriface.SecretsManagerAPI is embedded to implement the
+	// interface; it is nil.
+	secretsmanageriface.SecretsManagerAPI
+
+	region string
+
+	mu      sync.Mutex
+	secrets map[string]*secret
+	nextID  int
+}
+
+type secret struct {
+	arn   string
+	value string
+}
+
+// NewSecretsManager creates an empty Secrets Manager stand-in for the region.
+func NewSecretsManager(region string) *SecretsManager {
+	return &SecretsManager{
+		region:  region,
+		secrets: make(map[string]*secret),
+	}
+}
+
+// secret returns the secret identified by a name or an ARN.
+// f.mu must be held.
+func (f *SecretsManager) secret(id string) (*secret, error) {
+	if s, ok := f.secrets[id]; ok {
+		return s, nil
+	}
+	for _, s := range f.secrets {
+		if s.arn == id {
+			return s, nil
+		}
+	}
+	return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+}
+
+// CreateSecret creates a secret.
+func (f *SecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	if _, ok := f.secrets[name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, "The operation failed because the secret "+name+" already exists.", nil)
+	}
+
+	// Secrets Manager suffixes the ARNs of secrets with 6 random characters.
+	f.nextID++
+	s := &secret{
+		arn:   fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s-%06d", f.region, AccountID, name, f.nextID),
+		value: aws.StringValue(input.SecretString),
+	}
+	f.secrets[name] = s
+
+	return &secretsmanager.CreateSecretOutput{
+		ARN:  aws.String(s.arn),
+		Name: input.Name,
+	}, nil
+}
+
+// PutSecretValue sets the value of a secret.
+func (f *SecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.secret(aws.StringValue(input.SecretId))
+	if err != nil {
+		return nil, err
+	}
+	s.value = aws.StringValue(input.SecretString)
+
+	return &secretsmanager.PutSecretValueOutput{ARN: aws.String(s.arn)}, nil
+}
+
+// DeleteSecret deletes a secret immediately.
+func (f *SecretsManager) DeleteSecret(input *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.secret(aws.StringValue(input.SecretId))
+	if err != nil {
+		return nil, err
+	}
+	for name, other := range f.secrets {
+		if other == s {
+			delete(f.secrets, name)
+		}
+	}
+
+	return &secretsmanager.DeleteSecretOutput{ARN: aws.String(s.arn)}, nil
+}
+
+// Secrets returns the values of the secrets by name.
+func (f *SecretsManager) Secrets() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets := make(map[string]string, len(f.secrets))
+	for name, s := range f.secrets {
+		secrets[name] = s.value
+	}
+	return secrets
+}
+
+// SSM is an in-memory implementation of the Systems Manager Parameter Store
+// API calls made by the Fargate provider. Calling any other method panics.
+type SSM struct {
+	// ssmiface.SSMAPI is embedded to implement the interface; it is nil.
+	ssmiface.SSMAPI
+
+	mu         sync.Mutex
+	parameters map[string]*ssm.Parameter
+}
+
+// NewSSM creates an empty Parameter Store stand-in.
+func NewSSM() *SSM {
+	return &SSM{
+		parameters: make(map[string]*ssm.Parameter),
+	}
+}
+
+// PutParameter creates or, if Overwrite is set, updates a parameter.
+func (f *SSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	if aws.StringValue(input.Value) == "" {
+		return nil, awserr.New("ValidationException", "1 validation error detected: Value at 'value' failed to satisfy constraint", nil)
+	}
+
+	p, ok := f.parameters[name]
+	if ok && !aws.BoolValue(input.Overwrite) {
+		return nil, awserr.New(ssm.ErrCodeParameterAlreadyExists, "The parameter already exists.", nil)
+	}
+	if !ok {
+		p = &ssm.Parameter{Name: input.Name, Version: aws.Int64(0)}
+		f.parameters[name] = p
+	}
+	p.Type = input.Type
+	p.Value = input.Value
+	p.Version = aws.Int64(aws.Int64Value(p.Version) + 1)
+
+	return &ssm.PutParameterOutput{Version: p.Version}, nil
+}
+
+// DeleteParameter deletes a parameter.
+func (f *SSM) DeleteParameter(input *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	if _, ok := f.parameters[name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "Parameter not found.", nil)
+	}
+	delete(f.parameters, name)
+
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+// Parameters returns the names of the parameters, sorted.
+func (f *SSM) Parameters() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.parameters))
+	for name := range f.parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
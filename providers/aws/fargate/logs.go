@@ -0,0 +1,235 @@
+package fargate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// logPollInterval is how often new events are read from CloudWatch Logs when
+// following logs.
+var logPollInterval = 2 * time.Second
+
+// LogOptions are the options of a container logs request.
+type LogOptions struct {
+	// Tail is the number of lines to return from the end of the logs, all
+	// lines are returned if it is not positive.
+	Tail int
+	// Follow keeps reading the logs until the task stops or the context is
+	// done.
+	Follow bool
+	// Timestamps prefixes each line with its RFC3339 timestamp.
+	Timestamps bool
+	// SinceTime only returns the lines logged at or after it, if set.
+	SinceTime time.Time
+}
+
+// StreamContainerLogs writes the logs of a container from this cluster to w.
+//
+// The logs are read from the CloudWatch Logs stream of the pod's current task.
+// When following, new events are polled for until the task has stopped and all
+// its events were written, or ctx is done.
+func (c *Cluster) StreamContainerLogs(ctx context.Context, namespace, podName, containerName string, opts LogOptions, w io.Writer) error {
+	if c.cloudWatchLogGroupName == "" {
+		return fmt.Errorf("logs not configured, please specify a \"CloudWatchLogGroupName\"")
+	}
+
+	stream, err := c.findLogStream(namespace, podName, containerName)
+	for err == nil && stream == "" {
+		// Nothing logged yet.
+		if !opts.Follow || c.taskStopped(namespace, podName) {
+			return nil
+		}
+		if err = waitLogPoll(ctx); err != nil {
+			return err
+		}
+		stream, err = c.findLogStream(namespace, podName, containerName)
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := c.writeLogEvents(stream, opts, w)
+	if err != nil || !opts.Follow {
+		return err
+	}
+
+	for {
+		if err := waitLogPoll(ctx); err != nil {
+			return err
+		}
+
+		// Check whether the task has stopped before reading, so that the
+		// events logged until it stopped are written.
+		stopped := c.taskStopped(namespace, podName)
+
+		output, err := c.client.logsapi.GetLogEvents(&cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  aws.String(c.cloudWatchLogGroupName),
+			LogStreamName: aws.String(stream),
+			NextToken:     aws.String(token),
+			StartFromHead: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeLogLines(w, output.Events, opts.Timestamps); err != nil {
+			return err
+		}
+
+		if stopped && aws.StringValue(output.NextForwardToken) == token {
+			return nil
+		}
+		token = aws.StringValue(output.NextForwardToken)
+	}
+}
+
+// findLogStream returns the name of the log stream of a container, or an empty
+// string if it does not exist yet.
+//
+// Containers log to the stream named after the ID of the pod's current task.
+// If the pod is unknown, e.g. because it was deleted, the stream logged to
+// last is used.
+func (c *Cluster) findLogStream(namespace, podName, containerName string) (string, error) {
+	// The awslogs driver names streams "<prefix>/<container>/<task ID>".
+	prefix := fmt.Sprintf("%s_%s/%s/", buildTaskDefinitionTag(c.name, namespace, podName), containerName, containerName)
+
+	var name string
+	if pod, err := c.GetPod(namespace, podName); err == nil && pod.taskArn != "" {
+		name = prefix + pod.taskArn[strings.LastIndex(pod.taskArn, "/")+1:]
+		prefix = name
+	}
+
+	var stream string
+	var last int64 = -1
+	err := c.client.logsapi.DescribeLogStreamsPages(&cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName:        aws.String(c.cloudWatchLogGroupName),
+		LogStreamNamePrefix: aws.String(prefix),
+	}, func(page *cloudwatchlogs.DescribeLogStreamsOutput, lastPage bool) bool {
+		for _, s := range page.LogStreams {
+			switch {
+			case name != "":
+				if aws.StringValue(s.LogStreamName) == name {
+					stream = name
+					return false
+				}
+			case aws.Int64Value(s.LastEventTimestamp) > last:
+				stream = aws.StringValue(s.LogStreamName)
+				last = aws.Int64Value(s.LastEventTimestamp)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return stream, nil
+}
+
+// writeLogEvents writes the events of a log stream selected by opts and
+// returns the forward token to read the next events with.
+func (c *Cluster) writeLogEvents(stream string, opts LogOptions, w io.Writer) (string, error) {
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(c.cloudWatchLogGroupName),
+		LogStreamName: aws.String(stream),
+	}
+
+	// Without a start time, the last lines are read in a single request.
+	if opts.Tail > 0 && opts.SinceTime.IsZero() {
+		input.Limit = aws.Int64(int64(opts.Tail))
+		output, err := c.client.logsapi.GetLogEvents(input)
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(output.NextForwardToken), writeLogLines(w, output.Events, opts.Timestamps)
+	}
+
+	input.StartFromHead = aws.Bool(true)
+	if !opts.SinceTime.IsZero() {
+		input.StartTime = aws.Int64(aws.TimeUnixMilli(opts.SinceTime))
+	}
+
+	var token string
+	var tail []*cloudwatchlogs.OutputLogEvent
+	var werr error
+	err := c.client.logsapi.GetLogEventsPages(input, func(page *cloudwatchlogs.GetLogEventsOutput, lastPage bool) bool {
+		// The forward token of the last page is the one it was read with.
+		if aws.StringValue(page.NextForwardToken) == token {
+			return false
+		}
+		token = aws.StringValue(page.NextForwardToken)
+
+		if opts.Tail <= 0 {
+			werr = writeLogLines(w, page.Events, opts.Timestamps)
+			return werr == nil
+		}
+		tail = append(tail, page.Events...)
+		if len(tail) > opts.Tail {
+			tail = tail[len(tail)-opts.Tail:]
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if werr != nil {
+		return "", werr
+	}
+
+	return token, writeLogLines(w, tail, opts.Timestamps)
+}
+
+// writeLogLines writes log events to w as lines, in a single write.
+func writeLogLines(w io.Writer, events []*cloudwatchlogs.OutputLogEvent, timestamps bool) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		if timestamps {
+			ts := time.Unix(0, aws.Int64Value(event.Timestamp)*int64(time.Millisecond)).UTC()
+			buf.WriteString(ts.Format(time.RFC3339Nano))
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(aws.StringValue(event.Message))
+		buf.WriteByte('\n')
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// taskStopped returns whether the task of a pod has stopped, or the pod is
+// gone.
+func (c *Cluster) taskStopped(namespace, podName string) bool {
+	pod, err := c.GetPod(namespace, podName)
+	if err != nil {
+		return true
+	}
+	if pod.taskArn == "" {
+		return false
+	}
+
+	task, err := pod.describe()
+	if err != nil {
+		return false
+	}
+	return aws.StringValue(task.LastStatus) == taskStatusStopped
+}
+
+// waitLogPoll waits until logs should be polled again or ctx is done.
+func waitLogPoll(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(logPollInterval):
+		return nil
+	}
+}
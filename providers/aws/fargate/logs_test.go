@@ -0,0 +1,131 @@
+package fargate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate/fargatetest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// syncBuilder is a strings.Builder safe for concurrent use.
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestStreamContainerLogsOptions(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	logsapi := fargatetest.NewCloudWatchLogs()
+	cluster := newTestCluster(t, api, logsapi)
+
+	pod, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.NilError(t, pod.Start())
+
+	// The stream of a previous task of the pod is ignored.
+	prefix := pod.buildTaskDefinitionTag() + "_nginx/nginx/"
+	logsapi.AddLogEvents(testLogGroup, prefix+"previous", "stale")
+
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream := testLogStream(pod, "nginx")
+	logsapi.AddLogEventsAt(testLogGroup, stream, t0, "one")
+	logsapi.AddLogEventsAt(testLogGroup, stream, t0.Add(time.Second), "two")
+	logsapi.AddLogEventsAt(testLogGroup, stream, t0.Add(2*time.Second), "three")
+
+	for _, tc := range []struct {
+		name string
+		opts LogOptions
+		logs string
+	}{
+		{name: "all", logs: "one\ntwo\nthree\n"},
+		{name: "tail", opts: LogOptions{Tail: 1}, logs: "three\n"},
+		{name: "since", opts: LogOptions{SinceTime: t0.Add(time.Second)}, logs: "two\nthree\n"},
+		{name: "since and tail", opts: LogOptions{SinceTime: t0.Add(time.Second), Tail: 1}, logs: "three\n"},
+		{
+			name: "timestamps",
+			opts: LogOptions{Tail: 2, Timestamps: true},
+			logs: "2019-01-01T00:00:01Z two\n2019-01-01T00:00:02Z three\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs strings.Builder
+			assert.NilError(t, cluster.StreamContainerLogs(context.Background(), "default", "web", "nginx", tc.opts, &logs))
+			assert.Check(t, is.Equal(logs.String(), tc.logs))
+		})
+	}
+}
+
+func TestStreamContainerLogsFollow(t *testing.T) {
+	defer func(d time.Duration) { logPollInterval = d }(logPollInterval)
+	logPollInterval = time.Millisecond
+
+	api := fargatetest.NewECS(testRegion)
+	logsapi := fargatetest.NewCloudWatchLogs()
+	cluster := newTestCluster(t, api, logsapi)
+
+	pod, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.NilError(t, pod.Start())
+
+	var logs syncBuilder
+	done := make(chan error)
+	go func() {
+		done <- cluster.StreamContainerLogs(context.Background(), "default", "web", "nginx", LogOptions{Follow: true}, &logs)
+	}()
+
+	// Following waits for the stream to be created and for new events.
+	stream := testLogStream(pod, "nginx")
+	logsapi.AddLogEvents(testLogGroup, stream, "one")
+	waitForLogs(t, &logs, "one\n")
+	logsapi.AddLogEvents(testLogGroup, stream, "two")
+	waitForLogs(t, &logs, "one\ntwo\n")
+
+	// Following stops once the task has stopped.
+	assert.NilError(t, api.SetTaskStatus(pod.taskArn, taskStatusStopped, 0))
+	select {
+	case err := <-done:
+		assert.NilError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("logs are still followed after the task stopped")
+	}
+
+	// Following stops when the context is done.
+	pod, err = NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.NilError(t, pod.Start())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- cluster.StreamContainerLogs(ctx, "default", "web", "nginx", LogOptions{Follow: true}, &logs)
+	}()
+	cancel()
+	assert.Check(t, is.Equal(<-done, context.Canceled))
+}
+
+func waitForLogs(t *testing.T, logs *syncBuilder, expected string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for logs.String() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected logs %q, got %q", expected, logs.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -59,7 +59,9 @@ type Pod struct {
 
 // NewPod creates a new Kubernetes pod on Fargate.
 func NewPod(cluster *Cluster, pod *corev1.Pod) (*Pod, error) {
-	api := client.api
+	if err := validateVolumes(pod); err != nil {
+		return nil, err
+	}
 
 	// Initialize the pod.
 	fgPod := &Pod{
@@ -85,6 +87,21 @@ func NewPod(cluster *Cluster, pod *corev1.Pod) (*Pod, error) {
 		// Create a container definition.
 		cntr, err := newContainer(&containerSpec)
 		if err != nil {
+			fgPod.deleteSecrets()
+			return nil, err
+		}
+
+		// Insert the container to its pod, so that its secrets are deleted
+		// if the pod can't be created.
+		fgPod.containers[containerSpec.Name] = cntr
+
+		// Add the environment variables set from secrets and config maps.
+		sources, err := fgPod.resolveEnv(&containerSpec)
+		if err == nil {
+			err = fgPod.setEnvSources(cntr, sources)
+		}
+		if err != nil {
+			fgPod.deleteSecrets()
 			return nil, err
 		}
 
@@ -97,9 +114,6 @@ func NewPod(cluster *Cluster, pod *corev1.Pod) (*Pod, error) {
 		fgPod.taskCPU += *cntr.definition.Cpu
 		fgPod.taskMemory += *cntr.definition.Memory
 
-		// Insert the container to its pod.
-		fgPod.containers[containerSpec.Name] = cntr
-
 		// Insert container definition to the task definition.
 		taskDef.ContainerDefinitions = append(taskDef.ContainerDefinitions, &cntr.definition)
 	}
@@ -107,6 +121,7 @@ func NewPod(cluster *Cluster, pod *corev1.Pod) (*Pod, error) {
 	// Set task resource limits.
 	err := fgPod.mapTaskSize()
 	if err != nil {
+		fgPod.deleteSecrets()
 		return nil, err
 	}
 
@@ -124,18 +139,14 @@ func NewPod(cluster *Cluster, pod *corev1.Pod) (*Pod, error) {
 		fgPod.taskRoleArn = val
 	}
 
-	// Register the task definition with Fargate.
-	log.Printf("RegisterTaskDefinition input:%+v", taskDef)
-	output, err := api.RegisterTaskDefinition(taskDef)
-	log.Printf("RegisterTaskDefinition err:%+v output:%+v", err, output)
+	// Register the task definition with Fargate, or reuse an identical one.
+	fgPod.taskDefArn, err = cluster.acquireTaskDefinition(taskDef)
 	if err != nil {
+		fgPod.deleteSecrets()
 		err = fmt.Errorf("failed to register task definition: %v", err)
 		return nil, err
 	}
 
-	// Save the registered task definition ARN.
-	fgPod.taskDefArn = *output.TaskDefinition.TaskDefinitionArn
-
 	if cluster != nil {
 		cluster.InsertPod(fgPod, tag)
 	}
@@ -165,7 +176,7 @@ func NewPodFromTag(cluster *Cluster, tag string) (*Pod, error) {
 
 // Start deploys and runs a Kubernetes pod on Fargate.
 func (pod *Pod) Start() error {
-	api := client.api
+	api := pod.cluster.client.api
 
 	// Pods always get an ENI with a private IPv4 address in customer subnet.
 	// Assign a public IPv4 address to the ENI only if requested.
@@ -210,7 +221,7 @@ func (pod *Pod) Start() error {
 
 // Stop stops a running Kubernetes pod on Fargate.
 func (pod *Pod) Stop() error {
-	api := client.api
+	api := pod.cluster.client.api
 
 	// Stop the task.
 	stopTaskInput := &ecs.StopTaskInput{
@@ -227,13 +238,11 @@ func (pod *Pod) Stop() error {
 		return err
 	}
 
-	// Deregister the task definition.
-	_, err = api.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{
-		TaskDefinition: aws.String(pod.taskDefArn),
-	})
-	if err != nil {
-		log.Printf("Failed to deregister task definition: %v", err)
-	}
+	// Deregister the task definition unless another pod uses it.
+	pod.cluster.releaseTaskDefinition(pod.taskDefArn)
+
+	// Delete the secrets referenced by the task definition.
+	pod.deleteSecrets()
 
 	// Remove the pod from its cluster.
 	if pod.cluster != nil {
@@ -326,7 +335,7 @@ func (pod *Pod) mapTaskSize() error {
 
 // Describe retrieves the status of a Kubernetes pod from Fargate.
 func (pod *Pod) describe() (*ecs.Task, error) {
-	api := client.api
+	api := pod.cluster.client.api
 
 	// Describe the task.
 	describeTasksInput := &ecs.DescribeTasksInput{
@@ -0,0 +1,369 @@
+package fargate
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/cpuguy83/strongerrors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// Secrets backends the secret values referenced by pods are stored in.
+	SecretsBackendNone           = ""
+	SecretsBackendSecretsManager = "secretsmanager"
+	SecretsBackendSSM            = "ssm"
+
+	// secretNamePrefix prefixes the names of the secrets and parameters
+	// owned by pods.
+	secretNamePrefix = "/virtual-kubelet/"
+
+	// secretOwnerTagKey is the tag recording the pod owning a secret.
+	secretOwnerTagKey = "virtual-kubelet.io/pod"
+)
+
+// ResourceGetter looks up the secrets and config maps referenced by pods.
+// It is implemented by manager.ResourceManager.
+type ResourceGetter interface {
+	GetSecret(name, namespace string) (*corev1.Secret, error)
+	GetConfigMap(name, namespace string) (*corev1.ConfigMap, error)
+}
+
+// secretStore stores secret values, which ECS injects into containers.
+type secretStore interface {
+	// put stores the value under name and returns the reference ECS reads it
+	// from. An existing value is overwritten.
+	put(name, owner, value string) (valueFrom string, err error)
+	// delete deletes a value stored by put.
+	delete(valueFrom string) error
+}
+
+// newSecretStore returns the secret store of the backend, or nil if secrets
+// are disabled.
+func newSecretStore(backend string, client *Client) (secretStore, error) {
+	switch backend {
+	case SecretsBackendNone:
+		return nil, nil
+	case SecretsBackendSecretsManager:
+		if client.secretsapi == nil {
+			return nil, fmt.Errorf("the Fargate client has no Secrets Manager API")
+		}
+		return &secretsManagerStore{api: client.secretsapi}, nil
+	case SecretsBackendSSM:
+		if client.ssmapi == nil {
+			return nil, fmt.Errorf("the Fargate client has no Systems Manager API")
+		}
+		return &ssmStore{api: client.ssmapi}, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q", backend)
+	}
+}
+
+// secretsManagerStore stores secret values as AWS Secrets Manager secrets.
+type secretsManagerStore struct {
+	api secretsmanageriface.SecretsManagerAPI
+}
+
+func (s *secretsManagerStore) put(name, owner, value string) (string, error) {
+	created, err := s.api.CreateSecret(&secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: aws.String(value),
+		Tags: []*secretsmanager.Tag{{
+			Key:   aws.String(secretOwnerTagKey),
+			Value: aws.String(owner),
+		}},
+	})
+	if err == nil {
+		return aws.StringValue(created.ARN), nil
+	}
+	if e, ok := err.(awserr.Error); !ok || e.Code() != secretsmanager.ErrCodeResourceExistsException {
+		return "", err
+	}
+
+	// The secret is left over from a pod with the same name.
+	updated, err := s.api.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(value),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(updated.ARN), nil
+}
+
+func (s *secretsManagerStore) delete(valueFrom string) error {
+	_, err := s.api.DeleteSecret(&secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(valueFrom),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if e, ok := err.(awserr.Error); ok && e.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return nil
+	}
+	return err
+}
+
+// ssmStore stores secret values as SecureString Systems Manager parameters.
+type ssmStore struct {
+	api ssmiface.SSMAPI
+}
+
+func (s *ssmStore) put(name, owner, value string) (string, error) {
+	_, err := s.api.PutParameter(&ssm.PutParameterInput{
+		Name:        aws.String(name),
+		Description: aws.String(fmt.Sprintf("Owned by virtual-kubelet pod %s", owner)),
+		Type:        aws.String(ssm.ParameterTypeSecureString),
+		Value:       aws.String(value),
+		Overwrite:   aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *ssmStore) delete(valueFrom string) error {
+	_, err := s.api.DeleteParameter(&ssm.DeleteParameterInput{
+		Name: aws.String(valueFrom),
+	})
+	if e, ok := err.(awserr.Error); ok && e.Code() == ssm.ErrCodeParameterNotFound {
+		return nil
+	}
+	return err
+}
+
+// secretName returns the name of the secret storing the value of an
+// environment variable of a container of the pod with the given tag.
+func secretName(tag, containerName, envName string) string {
+	return secretNamePrefix + tag + "/" + containerName + "/" + envName
+}
+
+// isOwnedSecret returns whether the ECS secret reference points to a secret
+// stored for the pod with the given tag.
+func isOwnedSecret(valueFrom, tag string) bool {
+	return strings.Contains(valueFrom, secretNamePrefix+tag+"/")
+}
+
+// envSource is an environment variable whose value comes from a secret or a
+// config map.
+type envSource struct {
+	name   string
+	value  string
+	secret bool
+}
+
+// resolveEnv returns the environment variables of the container set from
+// secrets and config maps. As with the kubelet, the variables of env override
+// the keys of the envFrom sources, later sources override earlier ones and keys
+// which aren't valid variable names are skipped.
+func (pod *Pod) resolveEnv(spec *corev1.Container) ([]envSource, error) {
+	declared := make(map[string]bool, len(spec.Env))
+	for _, env := range spec.Env {
+		declared[env.Name] = true
+	}
+
+	fromSources := make(map[string]envSource)
+	for _, from := range spec.EnvFrom {
+		switch {
+		case from.SecretRef != nil:
+			secret, err := pod.getSecret(from.SecretRef.Name, from.SecretRef.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if secret == nil {
+				continue
+			}
+			for k, v := range secret.Data {
+				fromSources[from.Prefix+k] = envSource{name: from.Prefix + k, value: string(v), secret: true}
+			}
+		case from.ConfigMapRef != nil:
+			configMap, err := pod.getConfigMap(from.ConfigMapRef.Name, from.ConfigMapRef.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if configMap == nil {
+				continue
+			}
+			for k, v := range configMap.Data {
+				fromSources[from.Prefix+k] = envSource{name: from.Prefix + k, value: v}
+			}
+		}
+	}
+
+	sources := make([]envSource, 0, len(fromSources))
+	for name, src := range fromSources {
+		if declared[name] || len(validation.IsEnvVarName(name)) != 0 {
+			continue
+		}
+		sources = append(sources, src)
+	}
+	// Keep the order stable, as it is part of the task definition.
+	sort.Slice(sources, func(i, j int) bool { return sources[i].name < sources[j].name })
+
+	for _, env := range spec.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+
+		switch {
+		case env.ValueFrom.SecretKeyRef != nil:
+			ref := env.ValueFrom.SecretKeyRef
+			secret, err := pod.getSecret(ref.Name, ref.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if secret == nil {
+				continue
+			}
+			value, ok := secret.Data[ref.Key]
+			if !ok {
+				if ref.Optional != nil && *ref.Optional {
+					continue
+				}
+				return nil, strongerrors.InvalidArgument(fmt.Errorf("secret %s/%s has no key %s", pod.namespace, ref.Name, ref.Key))
+			}
+			sources = append(sources, envSource{name: env.Name, value: string(value), secret: true})
+		case env.ValueFrom.ConfigMapKeyRef != nil:
+			ref := env.ValueFrom.ConfigMapKeyRef
+			configMap, err := pod.getConfigMap(ref.Name, ref.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if configMap == nil {
+				continue
+			}
+			value, ok := configMap.Data[ref.Key]
+			if !ok {
+				if ref.Optional != nil && *ref.Optional {
+					continue
+				}
+				return nil, strongerrors.InvalidArgument(fmt.Errorf("config map %s/%s has no key %s", pod.namespace, ref.Name, ref.Key))
+			}
+			sources = append(sources, envSource{name: env.Name, value: value})
+		}
+	}
+
+	return sources, nil
+}
+
+// getSecret returns the named secret of the namespace of the pod, or nil if it
+// is optional and doesn't exist.
+func (pod *Pod) getSecret(name string, optional *bool) (*corev1.Secret, error) {
+	if pod.cluster.resources == nil {
+		return nil, fmt.Errorf("cannot resolve secret %s/%s without a resource manager", pod.namespace, name)
+	}
+	secret, err := pod.cluster.resources.GetSecret(name, pod.namespace)
+	if err != nil || secret == nil {
+		if optional != nil && *optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get secret %s/%s: %v", pod.namespace, name, err)
+	}
+	return secret, nil
+}
+
+// getConfigMap returns the named config map of the namespace of the pod, or
+// nil if it is optional and doesn't exist.
+func (pod *Pod) getConfigMap(name string, optional *bool) (*corev1.ConfigMap, error) {
+	if pod.cluster.resources == nil {
+		return nil, fmt.Errorf("cannot resolve config map %s/%s without a resource manager", pod.namespace, name)
+	}
+	configMap, err := pod.cluster.resources.GetConfigMap(name, pod.namespace)
+	if err != nil || configMap == nil {
+		if optional != nil && *optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get config map %s/%s: %v", pod.namespace, name, err)
+	}
+	return configMap, nil
+}
+
+// setEnvSources adds the environment variables set from secrets and config
+// maps to the container definition. Secret values are stored in the secrets
+// backend of the cluster and referenced by the definition, so that they never
+// appear in the task definition.
+func (pod *Pod) setEnvSources(cntr *container, sources []envSource) error {
+	for _, src := range sources {
+		if !src.secret || src.value == "" {
+			cntr.definition.Environment = append(cntr.definition.Environment, &ecs.KeyValuePair{
+				Name:  aws.String(src.name),
+				Value: aws.String(src.value),
+			})
+			continue
+		}
+
+		if pod.cluster.secrets == nil {
+			return strongerrors.InvalidArgument(fmt.Errorf(
+				"pod %s/%s sets %s from a secret, which requires a secrets backend", pod.namespace, pod.name, src.name))
+		}
+
+		name := secretName(pod.buildTaskDefinitionTag(), aws.StringValue(cntr.definition.Name), src.name)
+		valueFrom, err := pod.cluster.secrets.put(name, pod.namespace+"/"+pod.name, src.value)
+		if err != nil {
+			return fmt.Errorf("failed to store secret %s: %v", name, err)
+		}
+
+		cntr.definition.Secrets = append(cntr.definition.Secrets, &ecs.Secret{
+			Name:      aws.String(src.name),
+			ValueFrom: aws.String(valueFrom),
+		})
+	}
+
+	return nil
+}
+
+// deleteSecrets deletes the secrets stored for the containers of the pod.
+func (pod *Pod) deleteSecrets() {
+	if pod.cluster.secrets == nil {
+		return
+	}
+
+	tag := pod.buildTaskDefinitionTag()
+	for _, cntr := range pod.containers {
+		for _, secret := range cntr.definition.Secrets {
+			valueFrom := aws.StringValue(secret.ValueFrom)
+			if !isOwnedSecret(valueFrom, tag) {
+				continue
+			}
+			if err := pod.cluster.secrets.delete(valueFrom); err != nil {
+				log.Printf("Failed to delete secret %s: %v", valueFrom, err)
+			}
+		}
+	}
+}
+
+// serviceAccountMountPath is where the service account token volume added to
+// pods by Kubernetes is mounted.
+const serviceAccountMountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// validateVolumes rejects the pods mounting secret or config map volumes,
+// which Fargate can't mount. The service account token volume added by
+// Kubernetes is ignored, like all the other volumes.
+func validateVolumes(pod *corev1.Pod) error {
+	serviceAccountVolumes := make(map[string]bool)
+	for _, c := range pod.Spec.Containers {
+		for _, m := range c.VolumeMounts {
+			if m.MountPath == serviceAccountMountPath {
+				serviceAccountVolumes[m.Name] = true
+			}
+		}
+	}
+
+	for _, v := range pod.Spec.Volumes {
+		if (v.Secret != nil || v.ConfigMap != nil) && !serviceAccountVolumes[v.Name] {
+			return strongerrors.InvalidArgument(fmt.Errorf(
+				"pod %s/%s volume %s: Fargate does not support secret and config map volumes, "+
+					"reference their keys from environment variables instead", pod.Namespace, pod.Name, v.Name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package fargate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate/fargatetest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeResources struct {
+	secrets    map[string]*corev1.Secret
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func (r *fakeResources) GetSecret(name, namespace string) (*corev1.Secret, error) {
+	if s, ok := r.secrets[namespace+"/"+name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+}
+
+func (r *fakeResources) GetConfigMap(name, namespace string) (*corev1.ConfigMap, error) {
+	if c, ok := r.configMaps[namespace+"/"+name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("config map %s/%s not found", namespace, name)
+}
+
+func newSecretsTestCluster(t *testing.T, api *fargatetest.ECS, backend string, opts ...ClientOpt) *Cluster {
+	cluster, err := NewCluster(&ClusterConfig{
+		Region:         testRegion,
+		Name:           "vk",
+		Subnets:        []string{"subnet-1"},
+		SecretsBackend: backend,
+		ResourceManager: &fakeResources{
+			secrets: map[string]*corev1.Secret{
+				"default/db": {Data: map[string][]byte{"password": []byte("hunter2"), "user": []byte("admin")}},
+			},
+			configMaps: map[string]*corev1.ConfigMap{
+				"default/settings": {Data: map[string]string{"level": "debug"}},
+			},
+		},
+		Client: NewClient(testRegion, api, fargatetest.NewCloudWatchLogs(), opts...),
+	})
+	assert.NilError(t, err)
+	return cluster
+}
+
+func newSecretsTestPod() *corev1.Pod {
+	optional := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "app",
+				Image: "app",
+				Env: []corev1.EnvVar{
+					{Name: "PLAIN", Value: "plain"},
+					{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "db"},
+						Key:                  "password",
+					}}},
+					{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+						Key:                  "level",
+					}}},
+					{Name: "MISSING", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+						Key:                  "key",
+						Optional:             &optional,
+					}}},
+				},
+				EnvFrom: []corev1.EnvFromSource{{
+					Prefix:    "DB_",
+					SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}},
+				}},
+			}},
+		},
+	}
+}
+
+func TestPodSecretsManagerSecrets(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	secretsapi := fargatetest.NewSecretsManager(testRegion)
+	cluster := newSecretsTestCluster(t, api, SecretsBackendSecretsManager, WithSecretsManagerAPI(secretsapi))
+
+	pod, err := NewPod(cluster, newSecretsTestPod())
+	assert.NilError(t, err)
+
+	prefix := "/virtual-kubelet/" + pod.buildTaskDefinitionTag() + "/app/"
+	assert.Check(t, is.DeepEqual(secretsapi.Secrets(), map[string]string{
+		prefix + "DB_PASSWORD": "hunter2",
+		prefix + "DB_password": "hunter2",
+		prefix + "DB_user":     "admin",
+	}))
+
+	// The task definition references the secrets, and has none of their
+	// values.
+	def := pod.containers["app"].definition
+	env := make(map[string]string)
+	for _, kv := range def.Environment {
+		env[aws.StringValue(kv.Name)] = aws.StringValue(kv.Value)
+	}
+	assert.Check(t, is.DeepEqual(env, map[string]string{"PLAIN": "plain", "LEVEL": "debug"}))
+
+	secrets := make(map[string]string)
+	for _, s := range def.Secrets {
+		secrets[aws.StringValue(s.Name)] = aws.StringValue(s.ValueFrom)
+	}
+	assert.Check(t, is.Len(secrets, 3))
+	assert.Check(t, strings.HasPrefix(secrets["DB_PASSWORD"], "arn:aws:secretsmanager:us-east-1:123456789012:secret:"+prefix+"DB_PASSWORD-"))
+
+	// The secrets are deleted with the pod, also after a restart.
+	assert.NilError(t, pod.Start())
+	restarted := newSecretsTestCluster(t, api, SecretsBackendSecretsManager, WithSecretsManagerAPI(secretsapi))
+	found, err := restarted.GetPod("default", "web")
+	assert.NilError(t, err)
+	assert.NilError(t, found.Stop())
+	assert.Check(t, is.Len(secretsapi.Secrets(), 0))
+}
+
+func TestPodSSMSecrets(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	ssmapi := fargatetest.NewSSM()
+	cluster := newSecretsTestCluster(t, api, SecretsBackendSSM, WithSSMAPI(ssmapi))
+
+	pod, err := NewPod(cluster, newSecretsTestPod())
+	assert.NilError(t, err)
+
+	prefix := "/virtual-kubelet/" + pod.buildTaskDefinitionTag() + "/app/"
+	assert.Check(t, is.DeepEqual(ssmapi.Parameters(), []string{prefix + "DB_PASSWORD", prefix + "DB_password", prefix + "DB_user"}))
+
+	assert.NilError(t, pod.Start())
+	assert.NilError(t, pod.Stop())
+	assert.Check(t, is.Len(ssmapi.Parameters(), 0))
+}
+
+func TestPodSecretsWithoutBackend(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	cluster := newSecretsTestCluster(t, api, SecretsBackendNone)
+
+	_, err := NewPod(cluster, newSecretsTestPod())
+	assert.Check(t, strongerrors.IsInvalidArgument(err))
+	assert.Check(t, is.Len(api.TaskDefinitions("ACTIVE"), 0))
+
+	// Pods without secrets don't need one.
+	pod := newSecretsTestPod()
+	env := pod.Spec.Containers[0].Env
+	pod.Spec.Containers[0].Env = []corev1.EnvVar{env[0], env[2]}
+	pod.Spec.Containers[0].EnvFrom = nil
+	_, err = NewPod(cluster, pod)
+	assert.NilError(t, err)
+}
+
+func TestPodSecretsCleanedUpOnFailure(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	secretsapi := fargatetest.NewSecretsManager(testRegion)
+	cluster := newSecretsTestCluster(t, api, SecretsBackendSecretsManager, WithSecretsManagerAPI(secretsapi))
+
+	// The second container references a missing secret.
+	pod := newSecretsTestPod()
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+		Name:  "sidecar",
+		Image: "sidecar",
+		Env: []corev1.EnvVar{{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+			Key:                  "token",
+		}}}},
+	})
+
+	_, err := NewPod(cluster, pod)
+	assert.Check(t, err != nil)
+	assert.Check(t, is.Len(secretsapi.Secrets(), 0))
+}
+
+func TestPodSecretVolumesRejected(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	cluster := newSecretsTestCluster(t, api, SecretsBackendNone)
+
+	pod := newSecretsTestPod()
+	pod.Spec.Containers[0].Env = nil
+	pod.Spec.Containers[0].EnvFrom = nil
+	pod.Spec.Volumes = []corev1.Volume{{
+		Name:         "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}}},
+	}}
+	_, err := NewPod(cluster, pod)
+	assert.Check(t, strongerrors.IsInvalidArgument(err))
+	assert.Check(t, is.Len(api.TaskDefinitions("ACTIVE"), 0))
+
+	// The service account token volume is ignored.
+	pod.Spec.Volumes = []corev1.Volume{{
+		Name:         "default-token-abcde",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "default-token-abcde"}},
+	}}
+	pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "default-token-abcde", MountPath: serviceAccountMountPath}}
+	_, err = NewPod(cluster, pod)
+	assert.NilError(t, err)
+}
+
+func TestPodResolveEnvFrom(t *testing.T) {
+	cluster := newSecretsTestCluster(t, fargatetest.NewECS(testRegion), SecretsBackendNone)
+	cluster.resources.(*fakeResources).configMaps["default/overrides"] = &corev1.ConfigMap{
+		Data: map[string]string{"level": "info", "1invalid": "skipped"},
+	}
+	pod := &Pod{cluster: cluster, namespace: "default", name: "web"}
+
+	optional := true
+	sources, err := pod.resolveEnv(&corev1.Container{
+		EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Optional: &optional}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "overrides"}}},
+			{Prefix: "DB_", SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}},
+		},
+		Env: []corev1.EnvVar{{Name: "DB_user", Value: "root"}},
+	})
+	assert.NilError(t, err)
+	// Later sources override earlier ones, env overrides envFrom and invalid
+	// names are skipped.
+	var got []string
+	for _, src := range sources {
+		got = append(got, fmt.Sprintf("%s=%s secret=%t", src.name, src.value, src.secret))
+	}
+	assert.Check(t, is.DeepEqual(got, []string{"DB_password=hunter2 secret=true", "level=info secret=false"}))
+}
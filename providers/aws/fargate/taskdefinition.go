@@ -0,0 +1,358 @@
+package fargate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	// taskDefinitionHashTagKey is the tag recording the hash of the
+	// registered task definition, used to reuse identical revisions.
+	taskDefinitionHashTagKey = "virtual-kubelet.io/spec-hash"
+
+	// taskDefinitionClusterTagKey is the tag recording the name of the
+	// cluster a task definition is registered for.
+	taskDefinitionClusterTagKey = "virtual-kubelet.io/cluster"
+
+	// taskDefinitionsPageSize is the number of revisions listed per call.
+	taskDefinitionsPageSize = 100
+)
+
+// maxCollectedTaskDefinitions bounds the number of revisions deregistered at
+// startup, so that a backlog of unused revisions doesn't delay it.
+var maxCollectedTaskDefinitions = 500
+
+var (
+	taskDefinitionsCount = stats.Int64("virtual-kubelet/fargate/task_definitions", "Number of active task definition revisions registered for pods", stats.UnitDimensionless)
+
+	keyCluster, _ = tag.NewKey("cluster")
+
+	// TaskDefinitionsView reports the number of active task definition
+	// revisions registered for the pods of a cluster, tagged by cluster.
+	//
+	// Register it with `view.Register` to collect the metric.
+	TaskDefinitionsView = &view.View{
+		Name:        "virtual-kubelet/fargate/task_definitions",
+		Description: "Number of active task definition revisions registered for pods, by cluster",
+		Measure:     taskDefinitionsCount,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{keyCluster},
+	}
+)
+
+// taskDefinition is an active task definition revision registered for pods.
+type taskDefinition struct {
+	family string
+	hash   string
+	// pods is the number of pods using the revision.
+	pods int
+}
+
+// hashTaskDefinition returns a hash of the task-relevant parts of a task
+// definition, which is the same for identical pod specs.
+func hashTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (string, error) {
+	in := *input
+	in.Tags = nil
+
+	// The JSON encoding is stable: fields are encoded in order and map keys
+	// are sorted.
+	b, err := json.Marshal(&in)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// taskDefinitionCall is a lookup or registration of a revision in progress,
+// keyed by task definition family and hash in Cluster.taskDefCalls.
+type taskDefinitionCall struct {
+	done chan struct{}
+	// pods is the number of pods waiting for the call, including the one
+	// making it.
+	pods int
+	arn  string
+	err  error
+}
+
+// acquireTaskDefinition returns the ARN of an active revision of the task
+// definition, registering one unless an identical revision exists.
+// The revision is deregistered once every pod acquiring it released it.
+// ECS is called without holding c.taskDefsMu. Pods acquiring the same task
+// definition meanwhile wait for the result of the first call.
+func (c *Cluster) acquireTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (string, error) {
+	hash, err := hashTaskDefinition(input)
+	if err != nil {
+		return "", err
+	}
+	family := aws.StringValue(input.Family)
+	key := family + "/" + hash
+
+	c.taskDefsMu.Lock()
+	for arn, td := range c.taskDefs {
+		if td.family == family && td.hash == hash {
+			td.pods++
+			c.taskDefsMu.Unlock()
+			log.Printf("Reusing task definition %s.", arn)
+			return arn, nil
+		}
+	}
+	if call, ok := c.taskDefCalls[key]; ok {
+		call.pods++
+		c.taskDefsMu.Unlock()
+		<-call.done
+		return call.arn, call.err
+	}
+	call := &taskDefinitionCall{done: make(chan struct{}), pods: 1}
+	c.taskDefCalls[key] = call
+	c.taskDefsMu.Unlock()
+
+	call.arn, call.err = c.findOrRegisterTaskDefinition(input, family, hash)
+
+	// The revision is tracked before the waiting pods return, so that it is
+	// not deregistered while any of them uses it.
+	c.taskDefsMu.Lock()
+	delete(c.taskDefCalls, key)
+	if call.err == nil {
+		c.taskDefs[call.arn] = &taskDefinition{family: family, hash: hash, pods: call.pods}
+		c.recordTaskDefinitions()
+	}
+	c.taskDefsMu.Unlock()
+	close(call.done)
+
+	return call.arn, call.err
+}
+
+// findOrRegisterTaskDefinition returns the ARN of an active revision of the
+// family registered with the hash, registering one if there is none.
+func (c *Cluster) findOrRegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput, family, hash string) (string, error) {
+	// The revision may have been registered before the provider restarted.
+	arn, err := c.findTaskDefinition(family, hash)
+	if err != nil {
+		return "", err
+	}
+	if arn != "" {
+		log.Printf("Reusing task definition %s.", arn)
+		return arn, nil
+	}
+
+	in := *input
+	in.Tags = append(in.Tags,
+		&ecs.Tag{
+			Key:   aws.String(taskDefinitionHashTagKey),
+			Value: aws.String(hash),
+		},
+		&ecs.Tag{
+			Key:   aws.String(taskDefinitionClusterTagKey),
+			Value: aws.String(c.name),
+		},
+	)
+
+	output, err := c.client.api.RegisterTaskDefinition(&in)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.TaskDefinition.TaskDefinitionArn), nil
+}
+
+// findTaskDefinition returns the ARN of an active revision of the family
+// registered with the hash, or an empty string if there is none.
+func (c *Cluster) findTaskDefinition(family, hash string) (string, error) {
+	arns, err := c.listTaskDefinitions(family)
+	if err != nil {
+		return "", err
+	}
+
+	for _, arn := range arns {
+		def, tags, err := c.describeTaskDefinition(arn)
+		if err != nil {
+			return "", err
+		}
+		if aws.StringValue(def.Family) == family && tagValue(tags, taskDefinitionHashTagKey) == hash {
+			return arn, nil
+		}
+	}
+	return "", nil
+}
+
+// listTaskDefinitions returns the ARNs of the active revisions of the
+// families starting with familyPrefix.
+func (c *Cluster) listTaskDefinitions(familyPrefix string) ([]string, error) {
+	var arns []string
+	err := c.client.api.ListTaskDefinitionsPages(
+		&ecs.ListTaskDefinitionsInput{
+			FamilyPrefix: aws.String(familyPrefix),
+			Status:       aws.String(ecs.TaskDefinitionStatusActive),
+		},
+		func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+			arns = append(arns, aws.StringValueSlice(page.TaskDefinitionArns)...)
+			return !lastPage
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task definitions: %v", err)
+	}
+	return arns, nil
+}
+
+// describeTaskDefinition returns a revision and its tags.
+func (c *Cluster) describeTaskDefinition(arn string) (*ecs.TaskDefinition, []*ecs.Tag, error) {
+	output, err := c.client.api.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(arn),
+		Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe task definition %s: %v", arn, err)
+	}
+	return output.TaskDefinition, output.Tags, nil
+}
+
+// tagValue returns the value of the tag with the key, or an empty string.
+func tagValue(tags []*ecs.Tag, key string) string {
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
+
+// releaseTaskDefinition releases a revision acquired by a pod, and deregisters
+// it if no other pod uses it.
+func (c *Cluster) releaseTaskDefinition(arn string) {
+	c.taskDefsMu.Lock()
+	defer c.taskDefsMu.Unlock()
+
+	if td, ok := c.taskDefs[arn]; ok {
+		td.pods--
+		if td.pods > 0 {
+			return
+		}
+		delete(c.taskDefs, arn)
+		c.recordTaskDefinitions()
+	}
+
+	c.deregisterTaskDefinition(arn)
+}
+
+func (c *Cluster) deregisterTaskDefinition(arn string) {
+	_, err := c.client.api.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{
+		TaskDefinition: aws.String(arn),
+	})
+	if err != nil {
+		log.Printf("Failed to deregister task definition %s: %v", arn, err)
+		return
+	}
+	log.Printf("Deregistered task definition %s.", arn)
+}
+
+// trackTaskDefinition records that a pod found running on the cluster uses
+// the revision.
+func (c *Cluster) trackTaskDefinition(def *ecs.TaskDefinition, tags []*ecs.Tag) {
+	c.taskDefsMu.Lock()
+	defer c.taskDefsMu.Unlock()
+
+	arn := aws.StringValue(def.TaskDefinitionArn)
+	td, ok := c.taskDefs[arn]
+	if !ok {
+		td = &taskDefinition{
+			family: aws.StringValue(def.Family),
+			hash:   tagValue(tags, taskDefinitionHashTagKey),
+		}
+		c.taskDefs[arn] = td
+	}
+	td.pods++
+}
+
+// collectTaskDefinitions deregisters the active revisions registered for
+// pods of the cluster which no pod uses anymore, at most
+// maxCollectedTaskDefinitions of them. The others are deregistered at the
+// next start.
+// The revisions of the cluster are found by their family rather than their
+// cluster tag, so that the revisions registered before the tag was added are
+// collected too.
+func (c *Cluster) collectTaskDefinitions() error {
+	c.taskDefsMu.Lock()
+	c.recordTaskDefinitions()
+	c.taskDefsMu.Unlock()
+
+	var (
+		unused    []string
+		truncated bool
+	)
+	err := c.client.api.ListTaskDefinitionsPages(
+		&ecs.ListTaskDefinitionsInput{
+			FamilyPrefix: aws.String(taskDefFamilyPrefix + "_" + c.name + "_"),
+			Status:       aws.String(ecs.TaskDefinitionStatusActive),
+			MaxResults:   aws.Int64(taskDefinitionsPageSize),
+		},
+		func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+			c.taskDefsMu.Lock()
+			defer c.taskDefsMu.Unlock()
+
+			for _, arn := range aws.StringValueSlice(page.TaskDefinitionArns) {
+				if _, ok := c.taskDefs[arn]; ok || !c.ownsTaskDefinition(arn) {
+					continue
+				}
+				if len(unused) == maxCollectedTaskDefinitions {
+					truncated = true
+					return false
+				}
+				unused = append(unused, arn)
+			}
+			return !lastPage
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to list task definitions: %v", err)
+	}
+	if truncated {
+		log.Printf("Deregistering the first %d unused task definitions of cluster %s, the others are deregistered at the next start.", len(unused), c.name)
+	}
+
+	for _, arn := range unused {
+		// A pod may have acquired the revision since it was listed.
+		c.taskDefsMu.Lock()
+		if _, ok := c.taskDefs[arn]; !ok {
+			c.deregisterTaskDefinition(arn)
+		}
+		c.taskDefsMu.Unlock()
+	}
+
+	return nil
+}
+
+// ownsTaskDefinition returns whether the revision was registered for a pod of
+// the cluster. Its family is made of the cluster, namespace and name of the
+// pod, none of which contain the separator.
+func (c *Cluster) ownsTaskDefinition(arn string) bool {
+	// The ARN ends with task-definition/<family>:<revision>.
+	family := arn[strings.LastIndex(arn, "/")+1:]
+	if i := strings.LastIndex(family, ":"); i >= 0 {
+		family = family[:i]
+	}
+
+	data := strings.Split(family, "_")
+	return len(data) == 4 && data[0] == taskDefFamilyPrefix && data[1] == c.name
+}
+
+// recordTaskDefinitions records the number of active task definitions.
+// c.taskDefsMu must be held.
+func (c *Cluster) recordTaskDefinitions() {
+	ctx, err := tag.New(context.Background(), tag.Upsert(keyCluster, c.name))
+	if err != nil {
+		return
+	}
+	stats.Record(ctx, taskDefinitionsCount.M(int64(len(c.taskDefs))))
+}
@@ -0,0 +1,214 @@
+package fargate
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate/fargatetest"
+	"go.opencensus.io/stats/view"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func registerTestTaskDefinition(t *testing.T, api *fargatetest.ECS, clusterName, family string) string {
+	output, err := api.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+		Family:               aws.String(family),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("c"), Image: aws.String("c")}},
+		Tags:                 []*ecs.Tag{{Key: aws.String(taskDefinitionClusterTagKey), Value: aws.String(clusterName)}},
+	})
+	assert.NilError(t, err)
+	return aws.StringValue(output.TaskDefinition.TaskDefinitionArn)
+}
+
+func activeTaskDefinitions(api *fargatetest.ECS) []string {
+	arns := api.TaskDefinitions("ACTIVE")
+	sort.Strings(arns)
+	return arns
+}
+
+func TestTaskDefinitionReuse(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	cluster := newTestCluster(t, api, fargatetest.NewCloudWatchLogs())
+
+	// A pod created again with the same spec, e.g. after it failed to start,
+	// reuses the revision of the first attempt.
+	first, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	second, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(second.taskDefArn, first.taskDefArn))
+	assert.Check(t, is.Len(activeTaskDefinitions(api), 1))
+
+	// A different spec registers a new revision, and the revision nobody
+	// uses anymore is deregistered.
+	spec := newTestPod()
+	spec.Spec.Containers[0].Image = "nginx:2"
+	third, err := NewPod(cluster, spec)
+	assert.NilError(t, err)
+	assert.Check(t, third.taskDefArn != first.taskDefArn)
+	assert.Check(t, is.DeepEqual(activeTaskDefinitions(api), []string{third.taskDefArn}))
+
+	assert.NilError(t, third.Start())
+	assert.NilError(t, third.Stop())
+	assert.Check(t, is.Len(activeTaskDefinitions(api), 0))
+}
+
+func TestTaskDefinitionReuseFromECS(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	cluster := newTestCluster(t, api, fargatetest.NewCloudWatchLogs())
+
+	first, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+
+	// The revision registered before the provider lost track of it, e.g.
+	// because it restarted, is found by its spec hash.
+	cluster.taskDefs = make(map[string]*taskDefinition)
+	cluster.pods = make(map[string]*Pod)
+	second, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(second.taskDefArn, first.taskDefArn))
+	assert.Check(t, is.Len(activeTaskDefinitions(api), 1))
+}
+
+func TestTaskDefinitionCollection(t *testing.T) {
+	assert.NilError(t, view.Register(TaskDefinitionsView))
+	defer view.Unregister(TaskDefinitionsView)
+
+	api := fargatetest.NewECS(testRegion)
+	logsapi := fargatetest.NewCloudWatchLogs()
+	cluster := newTestCluster(t, api, logsapi)
+
+	pod, err := NewPod(cluster, newTestPod())
+	assert.NilError(t, err)
+	assert.NilError(t, pod.Start())
+
+	// Revisions left over by pods which are gone, e.g. because the provider
+	// was stopped before deregistering them, and revisions of other
+	// clusters, including those whose family starts like the families of
+	// the cluster.
+	orphan := registerTestTaskDefinition(t, api, "vk", buildTaskDefinitionTag("vk", "default", "gone"))
+	other := registerTestTaskDefinition(t, api, "vk_default", buildTaskDefinitionTag("vk_default", "default", "web"))
+
+	// At startup, the revisions of the running pods are kept and the others
+	// of the cluster are deregistered.
+	restarted := newTestCluster(t, api, logsapi)
+	assert.Check(t, is.DeepEqual(activeTaskDefinitions(api), []string{other, pod.taskDefArn}))
+	assert.Check(t, orphan != pod.taskDefArn)
+
+	rows, err := view.RetrieveData(TaskDefinitionsView.Name)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(rows, 1))
+	assert.Check(t, is.Equal(rows[0].Data.(*view.LastValueData).Value, float64(1)))
+
+	// The revisions of the running pods are reused.
+	found, err := restarted.GetPod("default", "web")
+	assert.NilError(t, err)
+	again, err := NewPod(restarted, newTestPod())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(again.taskDefArn, found.taskDefArn))
+
+	assert.NilError(t, again.Start())
+	assert.NilError(t, again.Stop())
+	assert.Check(t, is.DeepEqual(activeTaskDefinitions(api), []string{other}))
+
+	rows, err = view.RetrieveData(TaskDefinitionsView.Name)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(rows[0].Data.(*view.LastValueData).Value, float64(0)))
+}
+
+// blockingECS blocks the registrations of the task definitions of a family
+// until unblock is closed.
+type blockingECS struct {
+	*fargatetest.ECS
+	family        string
+	unblock       chan struct{}
+	registrations chan string
+}
+
+func (f *blockingECS) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	f.registrations <- aws.StringValue(input.Family)
+	if aws.StringValue(input.Family) == f.family {
+		<-f.unblock
+	}
+	return f.ECS.RegisterTaskDefinition(input)
+}
+
+func newTestTaskDefinitionInput(family string) *ecs.RegisterTaskDefinitionInput {
+	return &ecs.RegisterTaskDefinitionInput{
+		Family:               aws.String(family),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("c"), Image: aws.String("c")}},
+	}
+}
+
+func TestTaskDefinitionConcurrentAcquire(t *testing.T) {
+	api := fargatetest.NewECS(testRegion)
+	cluster := newTestCluster(t, api, fargatetest.NewCloudWatchLogs())
+	blocking := &blockingECS{ECS: api, family: "slow", unblock: make(chan struct{}), registrations: make(chan string, 10)}
+	cluster.client.api = blocking
+
+	type result struct {
+		arn string
+		err error
+	}
+	results := make(chan result)
+	for i := 0; i < 3; i++ {
+		go func() {
+			arn, err := cluster.acquireTaskDefinition(newTestTaskDefinitionInput("slow"))
+			results <- result{arn, err}
+		}()
+	}
+	assert.Check(t, is.Equal(<-blocking.registrations, "slow"))
+
+	// Other task definitions are registered while the registration is in
+	// progress.
+	_, err := cluster.acquireTaskDefinition(newTestTaskDefinitionInput("fast"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(<-blocking.registrations, "fast"))
+
+	// The pods acquiring the same task definition share its registration.
+	close(blocking.unblock)
+	var arns []string
+	for i := 0; i < 3; i++ {
+		r := <-results
+		assert.NilError(t, r.err)
+		arns = append(arns, r.arn)
+	}
+	assert.Check(t, is.DeepEqual(arns, []string{arns[0], arns[0], arns[0]}))
+	assert.Check(t, is.Len(blocking.registrations, 0))
+	assert.Check(t, is.Equal(cluster.taskDefs[arns[0]].pods, 3))
+	assert.Check(t, is.Len(cluster.taskDefCalls, 0))
+
+	// The revision is deregistered once all of them released it.
+	for i := 0; i < 3; i++ {
+		assert.Check(t, is.Contains(activeTaskDefinitions(api), arns[0]))
+		cluster.releaseTaskDefinition(arns[0])
+	}
+	assert.Check(t, is.Contains(api.TaskDefinitions("INACTIVE"), arns[0]))
+}
+
+func TestTaskDefinitionCollectionBound(t *testing.T) {
+	defer func(max int) { maxCollectedTaskDefinitions = max }(maxCollectedTaskDefinitions)
+	maxCollectedTaskDefinitions = 120
+
+	api := fargatetest.NewECS(testRegion)
+	logsapi := fargatetest.NewCloudWatchLogs()
+
+	// Revisions registered before the cluster tag was added have no tags.
+	legacy, err := api.RegisterTaskDefinition(newTestTaskDefinitionInput(buildTaskDefinitionTag("vk", "default", "legacy")))
+	assert.NilError(t, err)
+	for i := 0; i < 150; i++ {
+		registerTestTaskDefinition(t, api, "vk", buildTaskDefinitionTag("vk", "default", fmt.Sprintf("gone-%d", i)))
+	}
+
+	// The revisions are listed by pages of taskDefinitionsPageSize, and at
+	// most maxCollectedTaskDefinitions are deregistered at a time.
+	newTestCluster(t, api, logsapi)
+	assert.Check(t, is.Len(activeTaskDefinitions(api), 31))
+
+	newTestCluster(t, api, logsapi)
+	assert.Check(t, is.Len(activeTaskDefinitions(api), 0))
+	assert.Check(t, is.Contains(api.TaskDefinitions("INACTIVE"), aws.StringValue(legacy.TaskDefinition.TaskDefinitionArn)))
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate"
 
 	corev1 "k8s.io/api/core/v1"
@@ -38,7 +39,24 @@ type FargateProvider struct {
 	executionRoleArn        string
 	cloudWatchLogGroupName  string
 	platformVersion         string
+	secretsBackend          string
 	lastTransitionTime      time.Time
+
+	// client is used instead of a client created from the default AWS
+	// credentials when set.
+	client *fargate.Client
+}
+
+// FargateProviderOpt configures a Fargate provider.
+type FargateProviderOpt func(*FargateProvider) error
+
+// WithFargateClient makes the provider call Fargate with the given client,
+// such as one backed by the fakes of the fargatetest package.
+func WithFargateClient(client *fargate.Client) FargateProviderOpt {
+	return func(p *FargateProvider) error {
+		p.client = client
+		return nil
+	}
 }
 
 // Capacity represents the provisioned capacity on a Fargate cluster.
@@ -60,7 +78,8 @@ func NewFargateProvider(
 	nodeName string,
 	operatingSystem string,
 	internalIP string,
-	daemonEndpointPort int32) (*FargateProvider, error) {
+	daemonEndpointPort int32,
+	opts ...FargateProviderOpt) (*FargateProvider, error) {
 
 	// Create the Fargate provider.
 	log.Println("Creating Fargate provider.")
@@ -73,6 +92,12 @@ func NewFargateProvider(
 		daemonEndpointPort: daemonEndpointPort,
 	}
 
+	for _, opt := range opts {
+		if err := opt(&p); err != nil {
+			return nil, err
+		}
+	}
+
 	// Read the Fargate provider configuration file.
 	err := p.loadConfigFile(config)
 	if err != nil {
@@ -93,6 +118,11 @@ func NewFargateProvider(
 		ExecutionRoleArn:        p.executionRoleArn,
 		CloudWatchLogGroupName:  p.cloudWatchLogGroupName,
 		PlatformVersion:         p.platformVersion,
+		SecretsBackend:          p.secretsBackend,
+		Client:                  p.client,
+	}
+	if rm != nil {
+		clusterConfig.ResourceManager = rm
 	}
 
 	p.cluster, err = fargate.NewCluster(&clusterConfig)
@@ -127,6 +157,14 @@ func (p *FargateProvider) CreatePod(ctx context.Context, pod *corev1.Pod) error
 	return nil
 }
 
+// ResolvesEnvironmentReferences implements providers.PodEnvironmentResolver.
+// With a secrets backend, the references to secrets and config maps are
+// resolved by the Fargate cluster, which stores the values of secrets in the
+// backend instead of the task definitions.
+func (p *FargateProvider) ResolvesEnvironmentReferences() bool {
+	return p.secretsBackend != fargate.SecretsBackendNone
+}
+
 // UpdatePod takes a Kubernetes Pod and updates it within the provider.
 func (p *FargateProvider) UpdatePod(ctx context.Context, pod *corev1.Pod) error {
 	log.Printf("Received UpdatePod request for %s/%s.\n", pod.Namespace, pod.Name)
@@ -179,6 +217,18 @@ func (p *FargateProvider) GetContainerLogs(ctx context.Context, namespace, podNa
 	return p.cluster.GetContainerLogs(namespace, podName, containerName, tail)
 }
 
+// StreamContainerLogs writes the logs of a container by name from the provider
+// to w, following them if requested.
+func (p *FargateProvider) StreamContainerLogs(ctx context.Context, namespace, podName, containerName string, opts providers.ContainerLogOpts, w io.Writer) error {
+	log.Printf("Received StreamContainerLogs request for %s/%s/%s.\n", namespace, podName, containerName)
+	return p.cluster.StreamContainerLogs(ctx, namespace, podName, containerName, fargate.LogOptions{
+		Tail:       opts.Tail,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+		SinceTime:  opts.SinceTime,
+	}, w)
+}
+
 // GetPodFullName retrieves the full pod name as defined in the provider context.
 func (p *FargateProvider) GetPodFullName(namespace string, pod string) string {
 	return ""
@@ -66,16 +66,11 @@ var (
 	logGroupName     *string
 )
 
-// TestMain wraps the tests with the extra setup and teardown of AWS resources.
-func TestMain(m *testing.M) {
+// setupE2E creates the AWS resources used by the E2E tests and returns the
+// function deleting them.
+func setupE2E() (func(), error) {
 	var err error
 
-	// Skip the tests in this package if the environment variable is set.
-	if os.Getenv(envSkipTests) == "1" {
-		fmt.Println("Skipping AWS E2E tests.")
-		os.Exit(0)
-	}
-
 	// Query the test region.
 	region, ok := os.LookupEnv(envTestRegion)
 	if ok {
@@ -99,8 +94,7 @@ func TestMain(m *testing.M) {
 	// Internet access is required to pull public images from the docker registry.
 	subnetID, err = createVpcWithInternetAccess(ec2Client)
 	if err != nil {
-		fmt.Printf("Failed to create VPC: %+v\n", err)
-		os.Exit(-1)
+		return nil, fmt.Errorf("failed to create VPC: %+v", err)
 	}
 
 	// Create the AWS CloudWatch Logs log group used by containers.
@@ -109,8 +103,7 @@ func TestMain(m *testing.M) {
 		LogGroupName: logGroupName,
 	})
 	if err != nil {
-		fmt.Printf("Failed to create CloudWatch Logs log group: %+v\n", err)
-		os.Exit(-1)
+		return nil, fmt.Errorf("failed to create CloudWatch Logs log group: %+v", err)
 	}
 
 	// Create the role used by Fargate to write logs and pull ECR images.
@@ -120,8 +113,7 @@ func TestMain(m *testing.M) {
 		AssumeRolePolicyDocument: aws.String(executorRoleAssumePolicy),
 	})
 	if err != nil {
-		fmt.Printf("Failed to create task execution role: %+v", err)
-		os.Exit(-1)
+		return nil, fmt.Errorf("failed to create task execution role: %+v", err)
 	}
 
 	// Attach the default policy allowing log writes and ECR pulls.
@@ -130,49 +122,57 @@ func TestMain(m *testing.M) {
 		RoleName:  executorRoleName,
 	})
 	if err != nil {
-		fmt.Printf("Failed to attach role policy: %+v", err)
-		os.Exit(-1)
+		return nil, fmt.Errorf("failed to attach role policy: %+v", err)
 	}
 
-	// Run the tests.
-	exitCode := m.Run()
+	teardown := func() {
+		// Delete the task execution role.
+		iamClient.DetachRolePolicy(&iam.DetachRolePolicyInput{
+			PolicyArn: aws.String("arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"),
+			RoleName:  executorRoleName,
+		})
+		if err != nil {
+			fmt.Printf("Failed to delete task execution role: %+v", err)
+		}
 
-	// Delete the task execution role.
-	iamClient.DetachRolePolicy(&iam.DetachRolePolicyInput{
-		PolicyArn: aws.String("arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"),
-		RoleName:  executorRoleName,
-	})
-	if err != nil {
-		fmt.Printf("Failed to delete task execution role: %+v", err)
-	}
+		// Delete the role.
+		_, err = iamClient.DeleteRole(&iam.DeleteRoleInput{
+			RoleName: executorRoleName,
+		})
+		if err != nil {
+			fmt.Printf("Failed to delete task execution role: %+v", err)
+		}
 
-	// Delete the role.
-	_, err = iamClient.DeleteRole(&iam.DeleteRoleInput{
-		RoleName: executorRoleName,
-	})
-	if err != nil {
-		fmt.Printf("Failed to delete task execution role: %+v", err)
+		// Delete the log group.
+		_, err = cloudwatchClient.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{
+			LogGroupName: logGroupName,
+		})
+		if err != nil {
+			fmt.Printf("Failed to delete CloudWatch Logs log group: %+v\n", err)
+		}
+
+		// Delete the test VPC.
+		err = deleteVpc(ec2Client)
+		if err != nil {
+			fmt.Printf("Failed to delete VPC: %+v\n", err)
+		}
 	}
+	return teardown, nil
+}
 
-	// Delete the log group.
-	_, err = cloudwatchClient.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{
-		LogGroupName: logGroupName,
-	})
-	if err != nil {
-		fmt.Printf("Failed to delete CloudWatch Logs log group: %+v\n", err)
+// TestAWSFargateProviderPodLifecycle validates basic pod lifecycle by starting and stopping a pod.
+func TestAWSFargateProviderPodLifecycle(t *testing.T) {
+	// Skip the E2E tests in this package if the environment variable is set.
+	if os.Getenv(envSkipTests) == "1" {
+		t.Skip("AWS E2E tests are disabled")
 	}
 
-	// Delete the test VPC.
-	err = deleteVpc(ec2Client)
+	teardown, err := setupE2E()
 	if err != nil {
-		fmt.Printf("Failed to delete VPC: %+v\n", err)
+		t.Fatal(err)
 	}
+	defer teardown()
 
-	os.Exit(exitCode)
-}
-
-// TestAWSFargateProviderPodLifecycle validates basic pod lifecycle by starting and stopping a pod.
-func TestAWSFargateProviderPodLifecycle(t *testing.T) {
 	// Create a cluster for the E2E test.
 	createResponse, err := ecsClient.CreateCluster(&ecs.CreateClusterInput{
 		ClusterName: aws.String(testName),
@@ -9,17 +9,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"net"
-	"net/http"
 	"os"
 	"reflect"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/cpuguy83/strongerrors"
 	client "github.com/virtual-kubelet/azure-aci/client"
 	"github.com/virtual-kubelet/azure-aci/client/aci"
 	"github.com/virtual-kubelet/azure-aci/client/network"
@@ -32,7 +29,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
-	"k8s.io/client-go/tools/remotecommand"
 	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
 )
 
@@ -72,6 +68,7 @@ type ACIProvider struct {
 	pods               string
 	gpu                string
 	gpuSKUs            []aci.GPUSKU
+	regionLimits       map[string]containerGroupMaxResources
 	internalIP         string
 	daemonEndpointPort int32
 	diagnostics        *aci.ContainerGroupDiagnostics
@@ -87,6 +84,12 @@ type ACIProvider struct {
 	metricsSync     sync.Mutex
 	metricsSyncTime time.Time
 	lastMetric      *stats.Summary
+
+	// targets are ordered by decreasing priority.
+	targets []target
+	// containerGroupTargets is keyed by container group name.
+	containerGroupTargetsMu sync.Mutex
+	containerGroupTargets   map[string]target
 }
 
 // AuthConfig is the secret returned from an ImageRegistryCredential
@@ -138,233 +141,164 @@ func isValidACIRegion(region string) bool {
 }
 
 // NewACIProvider creates a new ACIProvider.
+// The settings are read from the TOML provider config at the config path, and
+// can be overridden by environment variables, see providerConfig.
 func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operatingSystem string, internalIP string, daemonEndpointPort int32) (*ACIProvider, error) {
-	var p ACIProvider
-	var err error
-
-	p.resourceManager = rm
-
-	if config != "" {
-		f, err := os.Open(config)
-		if err != nil {
-			return nil, err
-		}
-		defer f.Close()
-
-		if err := p.loadConfig(f); err != nil {
-			return nil, err
-		}
-	}
-
-	var azAuth *client.Authentication
-
-	if authFilepath := os.Getenv("AZURE_AUTH_LOCATION"); authFilepath != "" {
-		auth, err := client.NewAuthenticationFromFile(authFilepath)
-		if err != nil {
-			return nil, err
-		}
-
-		azAuth = auth
-	}
-
-	if acsFilepath := os.Getenv("ACS_CREDENTIAL_LOCATION"); acsFilepath != "" {
-		acsCredential, err := NewAcsCredential(acsFilepath)
-		if err != nil {
-			return nil, err
-		}
-
-		if acsCredential != nil {
-			if acsCredential.Cloud != client.PublicCloud.Name {
-				return nil, fmt.Errorf("ACI only supports Public Azure. '%v' is not supported", acsCredential.Cloud)
-			}
-
-			azAuth = client.NewAuthentication(
-				acsCredential.Cloud,
-				acsCredential.ClientID,
-				acsCredential.ClientSecret,
-				acsCredential.SubscriptionID,
-				acsCredential.TenantID)
-
-			p.resourceGroup = acsCredential.ResourceGroup
-			p.region = acsCredential.Region
-
-			p.vnetName = acsCredential.VNetName
-			p.vnetResourceGroup = acsCredential.VNetResourceGroup
-			if p.vnetResourceGroup == "" {
-				p.vnetResourceGroup = p.resourceGroup
-			}
-		}
+	c, err := loadConfig(config, os.Getenv)
+	if err != nil {
+		return nil, err
 	}
 
-	if clientID := os.Getenv("AZURE_CLIENT_ID"); clientID != "" {
-		azAuth.ClientID = clientID
+	p := ACIProvider{
+		resourceManager:    rm,
+		resourceGroup:      c.ResourceGroup,
+		region:             c.Region,
+		nodeName:           nodeName,
+		operatingSystem:    operatingSystem,
+		cpu:                c.CPU,
+		memory:             c.Memory,
+		pods:               c.Pods,
+		internalIP:         internalIP,
+		daemonEndpointPort: daemonEndpointPort,
+		vnetName:           c.VNetName,
+		vnetResourceGroup:  c.VNetResourceGroup,
+		extraUserAgent:     c.ExtraUserAgent,
+		targets:            newTargets(c),
+	}
+
+	p.regionLimits, err = parseRegionLimits(c.RegionLimits)
+	if err != nil {
+		return nil, err
 	}
 
-	if clientSecret := os.Getenv("AZURE_CLIENT_SECRET"); clientSecret != "" {
-		azAuth.ClientSecret = clientSecret
+	azAuth, err := newAuthentication(c.Auth)
+	if err != nil {
+		return nil, err
 	}
 
-	if tenantID := os.Getenv("AZURE_TENANT_ID"); tenantID != "" {
-		azAuth.TenantID = tenantID
+	p.aciClient, err = aci.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return nil, err
 	}
 
-	if subscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID"); subscriptionID != "" {
-		azAuth.SubscriptionID = subscriptionID
+	p.diagnostics, err = newDiagnostics(c.LogAnalytics, nodeName)
+	if err != nil {
+		return nil, err
 	}
 
-	p.extraUserAgent = os.Getenv("ACI_EXTRA_USER_AGENT")
-
-	p.aciClient, err = aci.NewClient(azAuth, p.extraUserAgent)
-	if err != nil {
+	if err := p.setupCapacity(context.TODO(), c.GPU); err != nil {
 		return nil, err
 	}
 
-	// If the log analytics file has been specified, load workspace credentials from the file
-	if logAnalyticsAuthFile := os.Getenv("LOG_ANALYTICS_AUTH_LOCATION"); logAnalyticsAuthFile != "" {
-		p.diagnostics, err = aci.NewContainerGroupDiagnosticsFromFile(logAnalyticsAuthFile)
-		if err != nil {
-			return nil, err
-		}
+	if c.SubnetName != "" && p.vnetName == "" {
+		log.G(context.TODO()).WithField("subnet", c.SubnetName).Warn("Ignoring the subnet as no virtual network is set")
 	}
+	if c.SubnetName != "" && p.vnetName != "" {
+		p.subnetName = c.SubnetName
+		p.subnetCIDR = c.SubnetCIDR
 
-	// If we have both the log analytics workspace id and key, add them to the provider
-	// Environment variables overwrite the values provided in the file
-	if logAnalyticsID := os.Getenv("LOG_ANALYTICS_ID"); logAnalyticsID != "" {
-		if logAnalyticsKey := os.Getenv("LOG_ANALYTICS_KEY"); logAnalyticsKey != "" {
-			p.diagnostics, err = aci.NewContainerGroupDiagnostics(logAnalyticsID, logAnalyticsKey)
-			if err != nil {
-				return nil, err
-			}
+		if err := p.setupNetworkProfile(azAuth); err != nil {
+			return nil, fmt.Errorf("error setting up network profile: %v", err)
 		}
-	}
 
-	if clusterResourceID := os.Getenv("CLUSTER_RESOURCE_ID"); clusterResourceID != "" {
-		if p.diagnostics != nil && p.diagnostics.LogAnalytics != nil {
-			p.diagnostics.LogAnalytics.LogType = aci.LogAnlyticsLogTypeContainerInsights
-			p.diagnostics.LogAnalytics.Metadata = map[string]string{
-				aci.LogAnalyticsMetadataKeyClusterResourceID: clusterResourceID,
-				aci.LogAnalyticsMetadataKeyNodeName:          nodeName,
-			}
+		p.kubeProxyExtension, err = getKubeProxyExtension(serviceAccountSecretMountPath, c.MasterURI, c.ClusterCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("error creating kube proxy extension: %v", err)
 		}
-	}
 
-	if rg := os.Getenv("ACI_RESOURCE_GROUP"); rg != "" {
-		p.resourceGroup = rg
-	}
-	if p.resourceGroup == "" {
-		return nil, errors.New("Resource group can not be empty please set ACI_RESOURCE_GROUP")
+		p.kubeDNSIP = c.KubeDNSIP
 	}
 
-	if r := os.Getenv("ACI_REGION"); r != "" {
-		p.region = r
-	}
-	if p.region == "" {
-		return nil, errors.New("Region can not be empty please set ACI_REGION")
-	}
-	if r := p.region; !isValidACIRegion(r) {
-		unsupportedRegionMessage := fmt.Sprintf("Region %s is invalid. Current supported regions are: %s",
-			r, strings.Join(validAciRegions, ", "))
+	return &p, nil
+}
 
-		return nil, errors.New(unsupportedRegionMessage)
+// newAuthentication creates the Azure credentials from the auth file, if any,
+// with the client ID, secret, tenant and subscription of the config taking
+// precedence over the file.
+func newAuthentication(c authConfig) (*client.Authentication, error) {
+	auth := client.NewAuthentication(client.PublicCloud.Name, c.ClientID, c.ClientSecret, c.SubscriptionID, c.TenantID)
+	if c.Location == "" {
+		return auth, nil
 	}
 
-	if err := p.setupCapacity(context.TODO()); err != nil {
+	auth, err := client.NewAuthenticationFromFile(c.Location)
+	if err != nil {
 		return nil, err
 	}
-
-	p.operatingSystem = operatingSystem
-	p.nodeName = nodeName
-	p.internalIP = internalIP
-	p.daemonEndpointPort = daemonEndpointPort
-
-	if subnetName := os.Getenv("ACI_SUBNET_NAME"); p.vnetName != "" && subnetName != "" {
-		p.subnetName = subnetName
+	if c.ClientID != "" {
+		auth.ClientID = c.ClientID
 	}
-	if subnetCIDR := os.Getenv("ACI_SUBNET_CIDR"); subnetCIDR != "" {
-		if p.subnetName == "" {
-			return nil, fmt.Errorf("subnet CIDR defined but no subnet name, subnet name is required to set a subnet CIDR")
-		}
-		if _, _, err := net.ParseCIDR(subnetCIDR); err != nil {
-			return nil, fmt.Errorf("error parsing provided subnet range: %v", err)
-		}
-		p.subnetCIDR = subnetCIDR
+	if c.ClientSecret != "" {
+		auth.ClientSecret = c.ClientSecret
 	}
+	if c.TenantID != "" {
+		auth.TenantID = c.TenantID
+	}
+	if c.SubscriptionID != "" {
+		auth.SubscriptionID = c.SubscriptionID
+	}
+	return auth, nil
+}
 
-	if p.subnetName != "" {
-		if err := p.setupNetworkProfile(azAuth); err != nil {
-			return nil, fmt.Errorf("error setting up network profile: %v", err)
-		}
-
-		masterURI := os.Getenv("MASTER_URI")
-		if masterURI == "" {
-			masterURI = "10.0.0.1"
-		}
+// newDiagnostics creates the log analytics settings of the container groups,
+// the workspace ID and key of the config take precedence over the auth file.
+func newDiagnostics(c logAnalyticsConfig, nodeName string) (*aci.ContainerGroupDiagnostics, error) {
+	var (
+		diagnostics *aci.ContainerGroupDiagnostics
+		err         error
+	)
 
-		clusterCIDR := os.Getenv("CLUSTER_CIDR")
-		if clusterCIDR == "" {
-			clusterCIDR = "10.240.0.0/16"
+	if c.AuthLocation != "" {
+		diagnostics, err = aci.NewContainerGroupDiagnosticsFromFile(c.AuthLocation)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		p.kubeProxyExtension, err = getKubeProxyExtension(serviceAccountSecretMountPath, masterURI, clusterCIDR)
+	if c.WorkspaceID != "" && c.WorkspaceKey != "" {
+		diagnostics, err = aci.NewContainerGroupDiagnostics(c.WorkspaceID, c.WorkspaceKey)
 		if err != nil {
-			return nil, fmt.Errorf("error creating kube proxy extension: %v", err)
+			return nil, err
 		}
+	}
 
-		p.kubeDNSIP = "10.0.0.10"
-		if kubeDNSIP := os.Getenv("KUBE_DNS_IP"); kubeDNSIP != "" {
-			p.kubeDNSIP = kubeDNSIP
+	if c.ClusterResourceID != "" && diagnostics != nil && diagnostics.LogAnalytics != nil {
+		diagnostics.LogAnalytics.LogType = aci.LogAnlyticsLogTypeContainerInsights
+		diagnostics.LogAnalytics.Metadata = map[string]string{
+			aci.LogAnalyticsMetadataKeyClusterResourceID: c.ClusterResourceID,
+			aci.LogAnalyticsMetadataKeyNodeName:          nodeName,
 		}
 	}
 
-	return &p, err
+	return diagnostics, nil
 }
 
-func (p *ACIProvider) setupCapacity(ctx context.Context) error {
+func (p *ACIProvider) setupCapacity(ctx context.Context, gpu string) error {
 	ctx, span := trace.StartSpan(ctx, "setupCapacity")
 	defer span.End()
 	logger := log.G(ctx).WithField("method", "setupCapacity")
 
- 	// Set sane defaults for Capacity in case config is not supplied
-	p.cpu = "800"
-	p.memory = "4Ti"
-	p.pods = "800"
-
- 	if cpuQuota := os.Getenv("ACI_QUOTA_CPU"); cpuQuota != "" {
-		p.cpu = cpuQuota
-	}
-
- 	if memoryQuota := os.Getenv("ACI_QUOTA_MEMORY"); memoryQuota != "" {
-		p.memory = memoryQuota
-	}
-
- 	if podsQuota := os.Getenv("ACI_QUOTA_POD"); podsQuota != "" {
-		p.pods = podsQuota
-	}
-
- 	metadata, err := p.aciClient.GetResourceProviderMetadata(ctx)
+	metadata, err := p.aciClient.GetResourceProviderMetadata(ctx)
 
- 	if err != nil {
+	if err != nil {
 		msg := "Unable to fetch the ACI metadata"
 		logger.WithError(err).Error(msg)
 		return err
 	}
 
- 	if metadata == nil || metadata.GPURegionalSKUs == nil {
+	if metadata == nil || metadata.GPURegionalSKUs == nil {
 		logger.Warn("ACI GPU capacity is not enabled. GPU capacity will be disabled")
 		return nil
 	}
 
- 	for _, regionalSKU := range metadata.GPURegionalSKUs {
+	for _, regionalSKU := range metadata.GPURegionalSKUs {
 		if strings.EqualFold(regionalSKU.Location, p.region) && len(regionalSKU.SKUs) != 0 {
-			p.gpu = "100"
-			if gpu := os.Getenv("ACI_QUOTA_GPU"); gpu != "" {
-				p.gpu = gpu
-			}
+			p.gpu = gpu
 			p.gpuSKUs = regionalSKU.SKUs
 		}
 	}
 
- 	return nil
+	return nil
 }
 
 func (p *ACIProvider) setupNetworkProfile(auth *client.Authentication) error {
@@ -536,25 +470,63 @@ func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
+	targets, err := p.candidateTargets(pod)
+	if err != nil {
+		return err
+	}
+
+	containerGroup, err := p.newContainerGroup(pod)
+	if err != nil {
+		return err
+	}
+
+	// Try the targets by decreasing priority, falling back to the next one
+	// when the container group doesn't fit in, or there is no capacity in, a
+	// region.
+	name := containerGroupName(pod)
+	for i, t := range targets {
+		// Reject pods which can't fit in a container group before calling ARM.
+		err = p.validateContainerGroupResources(containerGroup.Containers, t.Region)
+		if err == nil {
+			containerGroup.Location = t.Region
+			_, err = p.aciClient.CreateContainerGroup(ctx, t.ResourceGroup, name, *containerGroup)
+			if err == nil {
+				p.setContainerGroupTarget(name, t)
+				return nil
+			}
+			if !isCapacityError(err) {
+				return err
+			}
+		}
+
+		if i < len(targets)-1 {
+			log.G(ctx).WithField("target", t).WithError(err).Warnf("Falling back to target %s", targets[i+1])
+		}
+	}
+	return err
+}
+
+// newContainerGroup converts the pod to a container group, its location is set
+// by the caller.
+func (p *ACIProvider) newContainerGroup(pod *v1.Pod) (*aci.ContainerGroup, error) {
 	var containerGroup aci.ContainerGroup
-	containerGroup.Location = p.region
 	containerGroup.RestartPolicy = aci.ContainerGroupRestartPolicy(pod.Spec.RestartPolicy)
 	containerGroup.ContainerGroupProperties.OsType = aci.OperatingSystemTypes(p.OperatingSystem())
 
 	// get containers
 	containers, err := p.getContainers(pod)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// get registry creds
 	creds, err := p.getImagePullSecrets(pod)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// get volumes
 	volumes, err := p.getVolumes(pod)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// assign all the things
 	containerGroup.ContainerGroupProperties.Containers = containers
@@ -562,7 +534,7 @@ func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	containerGroup.ContainerGroupProperties.ImageRegistryCredentials = creds
 	containerGroup.ContainerGroupProperties.Diagnostics = p.getDiagnostics(pod)
 
-	filterServiceAccountSecretVolume(p.operatingSystem, &containerGroup)
+	filterServiceAccountSecretVolume(p.operatingSystem, pod, &containerGroup)
 
 	// create ipaddress if containerPort is used
 	count := 0
@@ -603,14 +575,7 @@ func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 
 	p.amendVnetResources(&containerGroup, pod)
 
-	_, err = p.aciClient.CreateContainerGroup(
-		ctx,
-		p.resourceGroup,
-		containerGroupName(pod),
-		containerGroup,
-	)
-
-	return err
+	return &containerGroup, nil
 }
 
 func (p *ACIProvider) amendVnetResources(containerGroup *aci.ContainerGroup, pod *v1.Pod) {
@@ -738,7 +703,20 @@ func (p *ACIProvider) DeletePod(ctx context.Context, pod *v1.Pod) error {
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
-	err := p.aciClient.DeleteContainerGroup(ctx, p.resourceGroup, fmt.Sprintf("%s-%s", pod.Namespace, pod.Name))
+	name := containerGroupName(pod)
+	resourceGroup := pod.Annotations[aciResourceGroupAnnotation]
+	if resourceGroup == "" {
+		t, err := p.containerGroupTarget(ctx, name)
+		if err != nil {
+			return err
+		}
+		resourceGroup = t.ResourceGroup
+	}
+
+	err := p.aciClient.DeleteContainerGroup(ctx, resourceGroup, name)
+	if err == nil {
+		p.forgetContainerGroupTarget(name)
+	}
 	return wrapError(err)
 }
 
@@ -749,9 +727,9 @@ func (p *ACIProvider) GetPod(ctx context.Context, namespace, name string) (*v1.P
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
-	cg, status, err := p.aciClient.GetContainerGroup(ctx, p.resourceGroup, fmt.Sprintf("%s-%s", namespace, name))
+	cg, t, err := p.getContainerGroup(ctx, fmt.Sprintf("%s-%s", namespace, name))
 	if err != nil {
-		if status != nil && *status == http.StatusNotFound {
+		if strongerrors.IsNotFound(err) {
 			return nil, nil
 		}
 		return nil, err
@@ -761,7 +739,12 @@ func (p *ACIProvider) GetPod(ctx context.Context, namespace, name string) (*v1.P
 		return nil, nil
 	}
 
-	return containerGroupToPod(cg)
+	pod, err := containerGroupToPod(cg)
+	if err != nil {
+		return nil, err
+	}
+	setTargetAnnotations(pod, t)
+	return pod, nil
 }
 
 // GetContainerLogs returns the logs of a pod by name that is running inside ACI.
@@ -771,7 +754,7 @@ func (p *ACIProvider) GetContainerLogs(ctx context.Context, namespace, podName,
 	ctx = addAzureAttributes(ctx, span, p)
 
 	logContent := ""
-	cg, _, err := p.aciClient.GetContainerGroup(ctx, p.resourceGroup, fmt.Sprintf("%s-%s", namespace, podName))
+	cg, t, err := p.getContainerGroup(ctx, fmt.Sprintf("%s-%s", namespace, podName))
 	if err != nil {
 		return logContent, err
 	}
@@ -783,7 +766,7 @@ func (p *ACIProvider) GetContainerLogs(ctx context.Context, namespace, podName,
 	retry := 10
 	var retries int
 	for retries = 0; retries < retry; retries++ {
-		cLogs, err := p.aciClient.GetContainerLogs(ctx, p.resourceGroup, cg.Name, containerName, tail)
+		cLogs, err := p.aciClient.GetContainerLogs(ctx, t.ResourceGroup, cg.Name, containerName, tail)
 		if err != nil {
 			log.G(ctx).WithField("method", "GetContainerLogs").WithError(err).Debug("Error getting container logs, retrying")
 			time.Sleep(5000 * time.Millisecond)
@@ -800,84 +783,6 @@ func (p *ACIProvider) GetPodFullName(namespace string, pod string) string {
 	return fmt.Sprintf("%s-%s", namespace, pod)
 }
 
-// ExecInContainer executes a command in a container in the pod, copying data
-// between in/out/err and the container's stdin/stdout/stderr.
-func (p *ACIProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
-	// Cleanup on exit
-	if out != nil {
-		defer out.Close()
-	}
-	if errstream != nil {
-		defer errstream.Close()
-	}
-
-	cg, _, err := p.aciClient.GetContainerGroup(context.TODO(), p.resourceGroup, name)
-	if err != nil {
-		return err
-	}
-
-	// Set default terminal size
-	terminalSize := remotecommand.TerminalSize{
-		Height: 60,
-		Width:  120,
-	}
-
-	if resize != nil {
-		terminalSize = <-resize // Receive terminal resize event if resize stream is present
-	}
-
-	ts := aci.TerminalSizeRequest{Height: int(terminalSize.Height), Width: int(terminalSize.Width)}
-	xcrsp, err := p.aciClient.LaunchExec(p.resourceGroup, cg.Name, container, cmd[0], ts)
-	if err != nil {
-		return err
-	}
-
-	wsURI  := xcrsp.WebSocketURI
-	password := xcrsp.Password
-
-	c, _, _ := websocket.DefaultDialer.Dial(wsURI, nil)
-	c.WriteMessage(websocket.TextMessage, []byte(password)) // Websocket password needs to be sent before WS terminal is active
-
-	// Cleanup on exit
-	defer c.Close()
-	defer out.Close()
-
-	if in != nil {
-		// Inputstream
-		go func() {
-			for {
-				var msg = make([]byte, 512)
-				n, err := in.Read(msg)
-				if err == io.EOF {
-					// Handle EOF
-				}
-				if err != nil {
-					// Handle errors
-					return
-				}
-				if n > 0 { // Only call WriteMessage if there is data to send
-					c.WriteMessage(websocket.BinaryMessage, msg[:n])
-				}
-
-			}
-		}()
-	}
-
-	if out != nil {
-		//Outputstream
-		for {
-			_, cr, err := c.NextReader()
-			if err != nil {
-				// Handle errors
-				break
-			}
-			io.Copy(out, cr)
-		}
-	}
-
-	return nil
-}
-
 // GetPodStatus returns the status of a pod by name that is running inside ACI
 // returns nil if a pod by that name is not found.
 func (p *ACIProvider) GetPodStatus(ctx context.Context, namespace, name string) (*v1.PodStatus, error) {
@@ -903,28 +808,34 @@ func (p *ACIProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
-	cgs, err := p.aciClient.ListContainerGroups(ctx, p.resourceGroup)
-	if err != nil {
-		return nil, err
-	}
-	pods := make([]*v1.Pod, 0, len(cgs.Value))
-
-	for _, cg := range cgs.Value {
-		c := cg
-		if cg.Tags["NodeName"] != p.nodeName {
-			continue
+	pods := make([]*v1.Pod, 0)
+	for _, resourceGroup := range p.resourceGroups() {
+		cgs, err := p.aciClient.ListContainerGroups(ctx, resourceGroup)
+		if err != nil {
+			return nil, err
 		}
 
-		p, err := containerGroupToPod(&c)
-		if err != nil {
-			log.G(ctx).WithFields(log.Fields{
-				"name": c.Name,
-				"id":   c.ID,
-			}).WithError(err).Error("error converting container group to pod")
+		for _, cg := range cgs.Value {
+			c := cg
+			if cg.Tags["NodeName"] != p.nodeName {
+				continue
+			}
 
-			continue
+			pod, err := containerGroupToPod(&c)
+			if err != nil {
+				log.G(ctx).WithFields(log.Fields{
+					"name": c.Name,
+					"id":   c.ID,
+				}).WithError(err).Error("error converting container group to pod")
+
+				continue
+			}
+
+			t := target{ResourceGroup: resourceGroup, Region: c.Location}
+			p.setContainerGroupTarget(c.Name, t)
+			setTargetAnnotations(pod, t)
+			pods = append(pods, pod)
 		}
-		pods = append(pods, p)
 	}
 
 	return pods, nil
@@ -1176,19 +1087,29 @@ func (p *ACIProvider) getContainers(pod *v1.Pod) ([]aci.Container, error) {
 			}
 		}
 
+		// Like Kubernetes, default the requests to the limits when only the
+		// limits are set.
+		cpu, ok := container.Resources.Requests[v1.ResourceCPU]
+		if !ok {
+			cpu, ok = container.Resources.Limits[v1.ResourceCPU]
+		}
 		// NOTE(robbiezhang): ACI CPU request must be times of 10m
 		cpuRequest := 1.00
-		if _, ok := container.Resources.Requests[v1.ResourceCPU]; ok {
-			cpuRequest = float64(container.Resources.Requests.Cpu().MilliValue()/10.00) / 100.00
+		if ok {
+			cpuRequest = float64(cpu.MilliValue()/10.00) / 100.00
 			if cpuRequest < 0.01 {
 				cpuRequest = 0.01
 			}
 		}
 
+		memory, ok := container.Resources.Requests[v1.ResourceMemory]
+		if !ok {
+			memory, ok = container.Resources.Limits[v1.ResourceMemory]
+		}
 		// NOTE(robbiezhang): ACI memory request must be times of 0.1 GB
 		memoryRequest := 1.50
-		if _, ok := container.Resources.Requests[v1.ResourceMemory]; ok {
-			memoryRequest = float64(container.Resources.Requests.Memory().Value()/100000000.00) / 10.00
+		if ok {
+			memoryRequest = float64(memory.Value()/100000000.00) / 10.00
 			if memoryRequest < 0.10 {
 				memoryRequest = 0.10
 			}
@@ -1317,8 +1238,15 @@ func getProbe(probe *v1.Probe) (*aci.ContainerProbe, error) {
 }
 
 func (p *ACIProvider) getVolumes(pod *v1.Pod) ([]aci.Volume, error) {
+	filtered := filteredServiceAccountVolumes(p.operatingSystem, pod)
 	volumes := make([]aci.Volume, 0, len(pod.Spec.Volumes))
 	for _, v := range pod.Spec.Volumes {
+		// Skip the service account volumes which are filtered out of the
+		// container group anyway, so no token is requested for them.
+		if filtered[v.Name] {
+			continue
+		}
+
 		// Handle the case for the AzureFile volume.
 		if v.AzureFile != nil {
 			secret, err := p.resourceManager.GetSecret(v.AzureFile.SecretName, pod.Namespace)
@@ -1412,6 +1340,23 @@ func (p *ACIProvider) getVolumes(pod *v1.Pod) ([]aci.Volume, error) {
 			continue
 		}
 
+		// Handle the case for Projected volume, including the service account
+		// token volume.
+		if v.Projected != nil {
+			paths, err := p.getProjectedVolumePaths(pod, v.Projected)
+			if err != nil {
+				return nil, fmt.Errorf("error projecting volume %s of Pod %s: %v", v.Name, pod.Name, err)
+			}
+
+			if len(paths) != 0 {
+				volumes = append(volumes, aci.Volume{
+					Name:   v.Name,
+					Secret: paths,
+				})
+			}
+			continue
+		}
+
 		// If we've made it this far we have found a volume type that isn't supported
 		return nil, fmt.Errorf("Pod %s requires volume %s which is of an unsupported type", pod.Name, v.Name)
 	}
@@ -1628,11 +1573,14 @@ func aciContainerStateToContainerState(cs aci.ContainerState) v1.ContainerState
 	}
 }
 
-// Filters service account secret volume for Windows.
+// Filters service account secret volume for Windows, and for pods which opt
+// out of mounting the service account token.
 // Service account secret volume gets automatically turned on if not specified otherwise.
 // ACI doesn't support secret volume for Windows, so we need to filter it.
-func filterServiceAccountSecretVolume(osType string, containerGroup *aci.ContainerGroup) {
-	if strings.EqualFold(osType, "Windows") {
+func filterServiceAccountSecretVolume(osType string, pod *v1.Pod, containerGroup *aci.ContainerGroup) {
+	windows := strings.EqualFold(osType, "Windows")
+	automount := pod.Spec.AutomountServiceAccountToken == nil || *pod.Spec.AutomountServiceAccountToken
+	if windows || !automount {
 		serviceAccountSecretVolumeName := make(map[string]bool)
 
 		for index, container := range containerGroup.ContainerGroupProperties.Containers {
@@ -1652,7 +1600,11 @@ func filterServiceAccountSecretVolume(osType string, containerGroup *aci.Contain
 		}
 
 		l := log.G(context.TODO()).WithField("containerGroup", containerGroup.Name)
-		l.Infof("Ignoring service account secret volumes '%v' for Windows", reflect.ValueOf(serviceAccountSecretVolumeName).MapKeys())
+		if windows {
+			l.Infof("Ignoring service account secret volumes '%v' for Windows", reflect.ValueOf(serviceAccountSecretVolumeName).MapKeys())
+		} else {
+			l.Infof("Ignoring service account secret volumes '%v' as automountServiceAccountToken is disabled", reflect.ValueOf(serviceAccountSecretVolumeName).MapKeys())
+		}
 
 		volumes := make([]aci.Volume, 0, len(containerGroup.ContainerGroupProperties.Volumes))
 		for _, volume := range containerGroup.ContainerGroupProperties.Volumes {
@@ -16,13 +16,16 @@ type ACIMock struct {
 	OnCreate             func(string, string, string, *aci.ContainerGroup) (int, interface{})
 	OnGetContainerGroups func(string, string) (int, interface{})
 	OnGetContainerGroup  func(string, string, string) (int, interface{})
+	OnDelete             func(string, string, string) (int, interface{})
 	OnGetRPManifest      func() (int, interface{})
+	OnLaunchExec         func(string, string, string, string, *aci.ExecRequest) (int, interface{})
 }
 
 const (
 	containerGroupsRoute   = "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroup}/providers/Microsoft.ContainerInstance/containerGroups"
 	containerGroupRoute    = containerGroupsRoute + "/{containerGroup}"
 	containerGroupLogRoute = containerGroupRoute + "/containers/{containerName}/logs"
+	containerExecRoute     = containerGroupRoute + "/containers/{containerName}/exec"
 	resourceProviderRoute  = "/providers/Microsoft.ContainerInstance"
 )
 
@@ -86,6 +89,26 @@ func (mock *ACIMock) start() {
 			w.WriteHeader(http.StatusNotImplemented)
 		}).Methods("GET")
 
+	router.HandleFunc(
+		containerGroupRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription, _ := mux.Vars(r)["subscriptionId"]
+			resourceGroup, _ := mux.Vars(r)["resourceGroup"]
+			containerGroup, _ := mux.Vars(r)["containerGroup"]
+
+			if mock.OnDelete != nil {
+				statusCode, response := mock.OnDelete(subscription, resourceGroup, containerGroup)
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				json.NewEncoder(b).Encode(response)
+				w.Write(b.Bytes())
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("DELETE")
+
 	router.HandleFunc(
 		containerGroupsRoute,
 		func(w http.ResponseWriter, r *http.Request) {
@@ -105,6 +128,32 @@ func (mock *ACIMock) start() {
 			w.WriteHeader(http.StatusNotImplemented)
 		}).Methods("GET")
 
+	router.HandleFunc(
+		containerExecRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription, _ := mux.Vars(r)["subscriptionId"]
+			resourceGroup, _ := mux.Vars(r)["resourceGroup"]
+			containerGroup, _ := mux.Vars(r)["containerGroup"]
+			containerName, _ := mux.Vars(r)["containerName"]
+
+			var xc aci.ExecRequest
+			if err := json.NewDecoder(r.Body).Decode(&xc); err != nil {
+				panic(err)
+			}
+
+			if mock.OnLaunchExec != nil {
+				statusCode, response := mock.OnLaunchExec(subscription, resourceGroup, containerGroup, containerName, &xc)
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				json.NewEncoder(b).Encode(response)
+				w.Write(b.Bytes())
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("POST")
+
 	router.HandleFunc(
 		resourceProviderRoute,
 		func(w http.ResponseWriter, r *http.Request) {
@@ -4,71 +4,293 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	client "github.com/virtual-kubelet/azure-aci/client"
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
+// Defaults for the provider config.
+const (
+	defaultCPU         = "800"
+	defaultMemory      = "4Ti"
+	defaultPods        = "800"
+	defaultGPU         = "100"
+	defaultMasterURI   = "10.0.0.1"
+	defaultClusterCIDR = "10.240.0.0/16"
+	defaultKubeDNSIP   = "10.0.0.10"
+)
+
+// providerConfig is the TOML schema of the provider config.
+// Every setting can be overridden by the environment variable listed in
+// envOverrides.
 type providerConfig struct {
 	ResourceGroup   string
 	Region          string
 	OperatingSystem string
-	CPU             string
-	Memory          string
-	Pods            string
-	SubnetName      string
-	SubnetCIDR      string
+	ExtraUserAgent  string
+
+	// The capacity of the node.
+	CPU    string
+	Memory string
+	Pods   string
+	// GPU is only reported when ACI provides GPUs in the region.
+	GPU string
+
+	// The subnet to deploy container groups in, only used when the virtual
+	// network is set in the ACS credential file or VNetName.
+	SubnetName        string
+	SubnetCIDR        string
+	VNetName          string
+	VNetResourceGroup string
+	// Used to configure kube-proxy and DNS for container groups deployed in a subnet.
+	MasterURI   string
+	ClusterCIDR string
+	KubeDNSIP   string
+
+	RegionLimits map[string]regionLimitsConfig
+
+	// Targets are the resource groups and regions container groups can be
+	// created in, replacing ResourceGroup and Region which then default to the
+	// target with the highest priority. See targets.go.
+	Targets []target
+
+	Auth         authConfig
+	LogAnalytics logAnalyticsConfig
+}
+
+type authConfig struct {
+	// Location is the path of an Azure SDK auth file.
+	Location string
+	// ACSCredentialLocation is the path of an ACS/AKS credential file
+	// (azure.json). Its settings take precedence over the provider config,
+	// but not over the environment.
+	ACSCredentialLocation string
+
+	ClientID       string
+	ClientSecret   string
+	TenantID       string
+	SubscriptionID string
+}
+
+type logAnalyticsConfig struct {
+	// AuthLocation is the path of a log analytics workspace credential file.
+	AuthLocation      string
+	WorkspaceID       string
+	WorkspaceKey      string
+	ClusterResourceID string
 }
 
-func (p *ACIProvider) loadConfig(r io.Reader) error {
-	var config providerConfig
-	if _, err := toml.DecodeReader(r, &config); err != nil {
-		return err
-	}
-	p.region = config.Region
-	p.resourceGroup = config.ResourceGroup
-
-	// Default to 20 mcpu
-	p.cpu = "20"
-	if config.CPU != "" {
-		p.cpu = config.CPU
-	}
-	// Default to 100Gi
-	p.memory = "100Gi"
-	if config.Memory != "" {
-		p.memory = config.Memory
-	}
-	// Default to 20 pods
-	p.pods = "20"
-	if config.Pods != "" {
-		p.pods = config.Pods
-	}
-
-	// Default to Linux if the operating system was not defined in the config.
-	if config.OperatingSystem == "" {
-		config.OperatingSystem = providers.OperatingSystemLinux
-	} else {
-		// Validate operating system from config.
-		ok, _ := providers.ValidOperatingSystems[config.OperatingSystem]
-		if !ok {
-			return fmt.Errorf("%q is not a valid operating system, try one of the following instead: %s", config.OperatingSystem, strings.Join(providers.ValidOperatingSystems.Names(), " | "))
+// envOverrides maps the environment variables to the settings they override.
+func (c *providerConfig) envOverrides() map[string]*string {
+	return map[string]*string{
+		"ACI_RESOURCE_GROUP":          &c.ResourceGroup,
+		"ACI_REGION":                  &c.Region,
+		"ACI_EXTRA_USER_AGENT":        &c.ExtraUserAgent,
+		"ACI_QUOTA_CPU":               &c.CPU,
+		"ACI_QUOTA_MEMORY":            &c.Memory,
+		"ACI_QUOTA_POD":               &c.Pods,
+		"ACI_QUOTA_GPU":               &c.GPU,
+		"ACI_SUBNET_NAME":             &c.SubnetName,
+		"ACI_SUBNET_CIDR":             &c.SubnetCIDR,
+		"MASTER_URI":                  &c.MasterURI,
+		"CLUSTER_CIDR":                &c.ClusterCIDR,
+		"KUBE_DNS_IP":                 &c.KubeDNSIP,
+		"AZURE_AUTH_LOCATION":         &c.Auth.Location,
+		"ACS_CREDENTIAL_LOCATION":     &c.Auth.ACSCredentialLocation,
+		"AZURE_CLIENT_ID":             &c.Auth.ClientID,
+		"AZURE_CLIENT_SECRET":         &c.Auth.ClientSecret,
+		"AZURE_TENANT_ID":             &c.Auth.TenantID,
+		"AZURE_SUBSCRIPTION_ID":       &c.Auth.SubscriptionID,
+		"LOG_ANALYTICS_AUTH_LOCATION": &c.LogAnalytics.AuthLocation,
+		"LOG_ANALYTICS_ID":            &c.LogAnalytics.WorkspaceID,
+		"LOG_ANALYTICS_KEY":           &c.LogAnalytics.WorkspaceKey,
+		"CLUSTER_RESOURCE_ID":         &c.LogAnalytics.ClusterResourceID,
+	}
+}
+
+func (c *providerConfig) applyEnv(getenv func(string) string) {
+	for name, v := range c.envOverrides() {
+		if value := getenv(name); value != "" {
+			*v = value
 		}
 	}
+}
+
+// ValidateConfig loads the provider config at path, applies the environment
+// overrides and validates the result, without contacting Azure.
+// All the problems found are reported in the returned error.
+func ValidateConfig(path string) error {
+	_, err := loadConfig(path, os.Getenv)
+	return err
+}
 
-	// default subnet name
-	if config.SubnetName != "" {
-		p.subnetName = config.SubnetName
+// loadConfig loads the provider config at path, if any, and applies the ACS
+// credential file and the environment overrides before validating it.
+func loadConfig(path string, getenv func(string) string) (*providerConfig, error) {
+	c := &providerConfig{}
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		c, err = decodeConfig(f)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding provider config %s: %v", path, err)
+		}
 	}
-	if config.SubnetCIDR != "" {
-		if config.SubnetName == "" {
-			return fmt.Errorf("subnet CIDR is set but no subnet name provided, must provide a subnet name in order to set a subnet CIDR")
+
+	if err := c.resolve(getenv); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// decodeConfig decodes a config file. The defaults are set by resolve, once
+// the environment overrides are applied.
+func decodeConfig(r io.Reader) (*providerConfig, error) {
+	var c providerConfig
+	if _, err := toml.DecodeReader(r, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// resolve merges the ACS credential file and the environment overrides into
+// the config, and sets the defaults.
+func (c *providerConfig) resolve(getenv func(string) string) error {
+	if location := getenv("ACS_CREDENTIAL_LOCATION"); location != "" {
+		c.Auth.ACSCredentialLocation = location
+	}
+
+	if c.Auth.ACSCredentialLocation != "" {
+		acsCredential, err := NewAcsCredential(c.Auth.ACSCredentialLocation)
+		if err != nil {
+			return err
 		}
-		if _, _, err := net.ParseCIDR(config.SubnetCIDR); err != nil {
-			return fmt.Errorf("error parsing provided subnet CIDR: %v", err)
+		if acsCredential.Cloud != client.PublicCloud.Name {
+			return fmt.Errorf("ACI only supports Public Azure. '%v' is not supported", acsCredential.Cloud)
 		}
+
+		c.Auth.ClientID = acsCredential.ClientID
+		c.Auth.ClientSecret = acsCredential.ClientSecret
+		c.Auth.TenantID = acsCredential.TenantID
+		c.Auth.SubscriptionID = acsCredential.SubscriptionID
+		c.ResourceGroup = acsCredential.ResourceGroup
+		c.Region = acsCredential.Region
+		c.VNetName = acsCredential.VNetName
+		c.VNetResourceGroup = acsCredential.VNetResourceGroup
+	}
+
+	c.applyEnv(getenv)
+
+	if targets := sortTargets(c.Targets); len(targets) > 0 {
+		setDefault(&c.ResourceGroup, targets[0].ResourceGroup)
+		setDefault(&c.Region, targets[0].Region)
 	}
 
-	p.operatingSystem = config.OperatingSystem
+	setDefault(&c.OperatingSystem, providers.OperatingSystemLinux)
+	setDefault(&c.CPU, defaultCPU)
+	setDefault(&c.Memory, defaultMemory)
+	setDefault(&c.Pods, defaultPods)
+	setDefault(&c.GPU, defaultGPU)
+	setDefault(&c.VNetResourceGroup, c.ResourceGroup)
+	setDefault(&c.MasterURI, defaultMasterURI)
+	setDefault(&c.ClusterCIDR, defaultClusterCIDR)
+	setDefault(&c.KubeDNSIP, defaultKubeDNSIP)
 	return nil
 }
+
+func setDefault(v *string, def string) {
+	if *v == "" {
+		*v = def
+	}
+}
+
+// validate checks the whole config and reports all the problems found.
+func (c *providerConfig) validate() error {
+	var errs []error
+
+	if c.ResourceGroup == "" {
+		errs = append(errs, fmt.Errorf("resource group can not be empty, set ResourceGroup or ACI_RESOURCE_GROUP"))
+	}
+	if c.Region == "" {
+		errs = append(errs, fmt.Errorf("region can not be empty, set Region or ACI_REGION"))
+	} else if !isValidACIRegion(c.Region) {
+		errs = append(errs, fmt.Errorf("region %s is invalid. Current supported regions are: %s", c.Region, strings.Join(validAciRegions, ", ")))
+	}
+
+	if ok := providers.ValidOperatingSystems[c.OperatingSystem]; !ok {
+		errs = append(errs, fmt.Errorf("%q is not a valid operating system, try one of the following instead: %s", c.OperatingSystem, strings.Join(providers.ValidOperatingSystems.Names(), " | ")))
+	}
+
+	for _, q := range []struct{ name, value string }{{"CPU", c.CPU}, {"memory", c.Memory}, {"pods", c.Pods}, {"GPU", c.GPU}} {
+		if _, err := resource.ParseQuantity(q.value); err != nil {
+			errs = append(errs, fmt.Errorf("error parsing %s capacity %q: %v", q.name, q.value, err))
+		}
+	}
+
+	if c.SubnetCIDR != "" {
+		if c.SubnetName == "" {
+			errs = append(errs, fmt.Errorf("subnet CIDR is set but no subnet name provided, must provide a subnet name in order to set a subnet CIDR"))
+		}
+		if _, _, err := net.ParseCIDR(c.SubnetCIDR); err != nil {
+			errs = append(errs, fmt.Errorf("error parsing provided subnet CIDR: %v", err))
+		}
+	}
+	if _, _, err := net.ParseCIDR(c.ClusterCIDR); err != nil {
+		errs = append(errs, fmt.Errorf("error parsing provided cluster CIDR: %v", err))
+	}
+	if net.ParseIP(c.KubeDNSIP) == nil {
+		errs = append(errs, fmt.Errorf("kube DNS IP %q is not a valid IP address", c.KubeDNSIP))
+	}
+
+	regions := make(map[string]bool)
+	for i, t := range c.Targets {
+		if t.ResourceGroup == "" {
+			errs = append(errs, fmt.Errorf("target %d: resource group can not be empty", i))
+		}
+		if !isValidACIRegion(t.Region) {
+			errs = append(errs, fmt.Errorf("target %d: region %q is invalid. Current supported regions are: %s", i, t.Region, strings.Join(validAciRegions, ", ")))
+		}
+		regions[normalizeRegion(t.Region)] = true
+	}
+	// The network profile, and so the subnet, is regional.
+	if c.VNetName != "" && len(regions) > 1 {
+		errs = append(errs, fmt.Errorf("targets in several regions can't be used with the virtual network %s", c.VNetName))
+	}
+
+	if _, err := parseRegionLimits(c.RegionLimits); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Auth.Location != "" {
+		if _, err := client.NewAuthenticationFromFile(c.Auth.Location); err != nil {
+			errs = append(errs, err)
+		}
+	} else if c.Auth.ClientID == "" || c.Auth.ClientSecret == "" || c.Auth.TenantID == "" || c.Auth.SubscriptionID == "" {
+		errs = append(errs, fmt.Errorf("no credentials provided, set Auth.Location (AZURE_AUTH_LOCATION), Auth.ACSCredentialLocation (ACS_CREDENTIAL_LOCATION) or the client ID, client secret, tenant ID and subscription ID"))
+	}
+
+	la := c.LogAnalytics
+	if la.AuthLocation != "" {
+		if _, err := os.Stat(la.AuthLocation); err != nil {
+			errs = append(errs, fmt.Errorf("error reading log analytics auth file: %v", err))
+		}
+	}
+	if (la.WorkspaceID == "") != (la.WorkspaceKey == "") {
+		errs = append(errs, fmt.Errorf("both the log analytics workspace ID and key must be set"))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -2,8 +2,14 @@ package azure
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
 )
 
 const cfg = `
@@ -11,39 +17,26 @@ Region = "westus"
 ResourceGroup = "virtual-kubeletrg"
 CPU = "100"
 Memory = "100Gi"
-Pods = "20"`
-
-func TestConfig(t *testing.T) {
-	br := bytes.NewReader([]byte(cfg))
-	var p ACIProvider
-	err := p.loadConfig(br)
-	if err != nil {
-		t.Error(err)
-	}
-	wanted := "westus"
-	if p.region != wanted {
-		t.Errorf("Wanted %s, got %s.", wanted, p.region)
-	}
+Pods = "20"
 
-	wanted = "virtual-kubeletrg"
-	if p.resourceGroup != wanted {
-		t.Errorf("Wanted %s, got %s.", wanted, p.resourceGroup)
-	}
+[Auth]
+ClientID = "client"
+ClientSecret = "secret"
+TenantID = "tenant"
+SubscriptionID = "subscription"`
 
-	wanted = "100"
-	if p.cpu != wanted {
-		t.Errorf("Wanted %s, got %s.", wanted, p.cpu)
-	}
-
-	wanted = "100Gi"
-	if p.memory != wanted {
-		t.Errorf("Wanted %s, got %s.", wanted, p.memory)
-	}
+func TestConfig(t *testing.T) {
+	c, err := decodeConfig(bytes.NewReader([]byte(cfg)))
+	assert.NilError(t, err)
+	assert.NilError(t, c.resolve(fakeEnv(nil)))
+	assert.NilError(t, c.validate())
 
-	wanted = "20"
-	if p.pods != wanted {
-		t.Errorf("Wanted %s, got %s.", wanted, p.pods)
-	}
+	assert.Check(t, is.Equal(c.Region, "westus"))
+	assert.Check(t, is.Equal(c.ResourceGroup, "virtual-kubeletrg"))
+	assert.Check(t, is.Equal(c.CPU, "100"))
+	assert.Check(t, is.Equal(c.Memory, "100Gi"))
+	assert.Check(t, is.Equal(c.Pods, "20"))
+	assert.Check(t, is.Equal(c.Auth.ClientID, "client"))
 }
 
 const cfgBad = `
@@ -52,17 +45,12 @@ ResourceGroup = "virtual-kubeletrg"
 OperatingSystem = "noop"`
 
 func TestBadConfig(t *testing.T) {
-	br := bytes.NewReader([]byte(cfgBad))
-	var p ACIProvider
-	err := p.loadConfig(br)
-	if err == nil {
-		t.Fatal("expected loadConfig to fail with bad operating system option")
-	}
-
-	if !strings.Contains(err.Error(), "is not a valid operating system") {
-		t.Fatalf("expected loadConfig to fail with 'is not a valid operating system' but got: %v", err)
+	c, err := decodeConfig(bytes.NewReader([]byte(cfgBad)))
+	assert.NilError(t, err)
+	assert.NilError(t, c.resolve(fakeEnv(nil)))
 
-	}
+	err = c.validate()
+	assert.ErrorContains(t, err, "is not a valid operating system")
 }
 
 const defCfg = `
@@ -70,25 +58,152 @@ Region = "westus"
 ResourceGroup = "virtual-kubeletrg"`
 
 func TestDefaultedConfig(t *testing.T) {
-	br := bytes.NewReader([]byte(defCfg))
-	var p ACIProvider
-	err := p.loadConfig(br)
-	if err != nil {
-		t.Error(err)
-	}
+	c, err := decodeConfig(bytes.NewReader([]byte(defCfg)))
+	assert.NilError(t, err)
+	assert.NilError(t, c.resolve(fakeEnv(nil)))
+
 	// Test that defaults work with no settings in config.
-	wanted := "20"
-	if p.cpu != wanted {
-		t.Errorf("Wanted default %s, got %s.", wanted, p.cpu)
-	}
+	assert.Check(t, is.Equal(c.OperatingSystem, "Linux"))
+	assert.Check(t, is.Equal(c.CPU, defaultCPU))
+	assert.Check(t, is.Equal(c.Memory, defaultMemory))
+	assert.Check(t, is.Equal(c.Pods, defaultPods))
+	assert.Check(t, is.Equal(c.VNetResourceGroup, "virtual-kubeletrg"))
+	assert.Check(t, is.Equal(c.KubeDNSIP, defaultKubeDNSIP))
+}
+
+func TestConfigEnvOverrides(t *testing.T) {
+	c, err := decodeConfig(bytes.NewReader([]byte(cfg)))
+	assert.NilError(t, err)
+	assert.NilError(t, c.resolve(fakeEnv(map[string]string{
+		"ACI_REGION":        "eastus",
+		"ACI_QUOTA_CPU":     "50",
+		"AZURE_CLIENT_ID":   "env-client",
+		"LOG_ANALYTICS_ID":  "workspace",
+		"LOG_ANALYTICS_KEY": "key",
+	})))
+	assert.NilError(t, c.validate())
+
+	assert.Check(t, is.Equal(c.Region, "eastus"))
+	assert.Check(t, is.Equal(c.ResourceGroup, "virtual-kubeletrg"))
+	assert.Check(t, is.Equal(c.CPU, "50"))
+	assert.Check(t, is.Equal(c.Auth.ClientID, "env-client"))
+	assert.Check(t, is.Equal(c.Auth.ClientSecret, "secret"))
+	assert.Check(t, is.Equal(c.LogAnalytics.WorkspaceID, "workspace"))
+}
+
+func TestConfigACSCredential(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aci-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
 
-	wanted = "100Gi"
-	if p.memory != wanted {
-		t.Errorf("Wanted default %s, got %s.", wanted, p.memory)
+	acsPath := filepath.Join(dir, "azure.json")
+	assert.NilError(t, ioutil.WriteFile(acsPath, []byte(`{
+		"cloud": "AzurePublicCloud",
+		"tenantId": "acs-tenant",
+		"subscriptionId": "acs-subscription",
+		"aadClientId": "acs-client",
+		"aadClientSecret": "acs-secret",
+		"resourceGroup": "acs-rg",
+		"location": "westus2",
+		"vnetName": "acs-vnet"
+	}`), 0600))
+
+	c, err := decodeConfig(bytes.NewReader([]byte(cfg)))
+	assert.NilError(t, err)
+	assert.NilError(t, c.resolve(fakeEnv(map[string]string{
+		"ACS_CREDENTIAL_LOCATION": acsPath,
+		"ACI_REGION":              "eastus",
+	})))
+	assert.NilError(t, c.validate())
+
+	// The ACS credential file takes precedence over the config, but not over
+	// the environment.
+	assert.Check(t, is.Equal(c.Auth.ClientID, "acs-client"))
+	assert.Check(t, is.Equal(c.ResourceGroup, "acs-rg"))
+	assert.Check(t, is.Equal(c.VNetName, "acs-vnet"))
+	assert.Check(t, is.Equal(c.VNetResourceGroup, "acs-rg"))
+	assert.Check(t, is.Equal(c.Region, "eastus"))
+}
+
+const cfgTargets = `
+VNetName = "vnet"
+
+[[Targets]]
+ResourceGroup = "rg-west"
+Region = "westus"
+
+[[Targets]]
+ResourceGroup = "rg-east"
+Region = "eastus"
+Priority = 10
+
+[Auth]
+ClientID = "client"
+ClientSecret = "secret"
+TenantID = "tenant"
+SubscriptionID = "subscription"`
+
+func TestConfigTargets(t *testing.T) {
+	c, err := decodeConfig(bytes.NewReader([]byte(cfgTargets)))
+	assert.NilError(t, err)
+	assert.NilError(t, c.resolve(fakeEnv(nil)))
+
+	// The resource group and region default to the target with the highest
+	// priority.
+	assert.Check(t, is.Equal(c.ResourceGroup, "rg-east"))
+	assert.Check(t, is.Equal(c.Region, "eastus"))
+	assert.Check(t, is.DeepEqual(newTargets(c), []target{
+		{ResourceGroup: "rg-east", Region: "eastus", Priority: 10},
+		{ResourceGroup: "rg-west", Region: "westus"},
+	}))
+
+	// The virtual network is regional.
+	assert.ErrorContains(t, c.validate(), "targets in several regions can't be used with the virtual network vnet")
+
+	c.VNetName = ""
+	assert.NilError(t, c.validate())
+}
+
+const cfgInvalid = `
+Region = "atlantis"
+CPU = "lots"
+SubnetCIDR = "10.0.0.0"
+KubeDNSIP = "dns"
+
+[LogAnalytics]
+WorkspaceID = "workspace"
+
+[RegionLimits.eastus]
+Memory = "plenty"`
+
+func TestValidateConfigAggregatesErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aci-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	assert.NilError(t, ioutil.WriteFile(path, []byte(cfgInvalid), 0600))
+
+	_, err = loadConfig(path, fakeEnv(nil))
+	assert.Assert(t, err != nil)
+
+	for _, msg := range []string{
+		"resource group can not be empty",
+		"region atlantis is invalid",
+		`error parsing CPU capacity "lots"`,
+		"subnet CIDR is set but no subnet name provided",
+		"error parsing provided subnet CIDR",
+		`kube DNS IP "dns" is not a valid IP address`,
+		"error parsing memory limit for region eastus",
+		"no credentials provided",
+		"both the log analytics workspace ID and key must be set",
+	} {
+		assert.Check(t, strings.Contains(err.Error(), msg), "missing %q in: %v", msg, err)
 	}
+}
 
-	wanted = "20"
-	if p.pods != wanted {
-		t.Errorf("Wanted default %s, got %s.", wanted, p.pods)
+func fakeEnv(env map[string]string) func(string) string {
+	return func(name string) string {
+		return env[name]
 	}
 }
@@ -0,0 +1,131 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/wsexec"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// The terminal size used when the client does not send one.
+	defaultExecTerminalHeight = 60
+	defaultExecTerminalWidth  = 120
+
+	// How long to wait for the client to send the initial terminal size.
+	execInitialResizeTimeout = time.Second
+)
+
+// ExecInContainer executes a command in a container in the pod, copying data
+// between in/out/err and the container's stdin/stdout/stderr.
+//
+// ACI multiplexes stdout and stderr on a single websocket, so all output of
+// the command is written to out.
+// Terminal resize events are sent to the exec session as a JSON encoded
+// aci.TerminalSize text message. ACI sessions are attached to a terminal, so
+// stdin EOF is sent as EOT and the session is closed by ACI once the command
+// exits. ACI does not report the exit code of the command.
+func (p *ACIProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
+	// Cleanup on exit
+	if out != nil {
+		defer out.Close()
+	}
+	if errstream != nil {
+		defer errstream.Close()
+	}
+
+	if len(cmd) == 0 {
+		return strongerrors.InvalidArgument(errors.New("no command specified"))
+	}
+
+	cg, t, err := p.getContainerGroup(context.TODO(), name)
+	if err != nil {
+		return err
+	}
+
+	// Set default terminal size
+	terminalSize := remotecommand.TerminalSize{
+		Height: defaultExecTerminalHeight,
+		Width:  defaultExecTerminalWidth,
+	}
+
+	if resize != nil {
+		// Use the initial terminal size if the client sends one.
+		select {
+		case size, ok := <-resize:
+			if ok {
+				terminalSize = size
+			}
+		case <-time.After(execInitialResizeTimeout):
+		}
+	}
+
+	ts := aci.TerminalSizeRequest{Height: int(terminalSize.Height), Width: int(terminalSize.Width)}
+	xcrsp, err := p.aciClient.LaunchExec(t.ResourceGroup, cg.Name, container, wsexec.CommandString(cmd), ts)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	c, resp, err := websocket.DefaultDialer.Dial(xcrsp.WebSocketURI, nil)
+	if err != nil {
+		if resp != nil {
+			return errors.Wrapf(err, "error connecting to exec session for container %s: %s", container, resp.Status)
+		}
+		return errors.Wrapf(err, "error connecting to exec session for container %s", container)
+	}
+	defer c.Close()
+
+	session := wsexec.NewSession(c)
+
+	// Websocket password needs to be sent before WS terminal is active
+	if err := session.WriteMessage(websocket.TextMessage, []byte(xcrsp.Password)); err != nil {
+		return errors.Wrapf(err, "error authenticating exec session for container %s", container)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	if in != nil {
+		go session.CopyStdin(in, wsexec.EOT)
+	}
+	if resize != nil {
+		go forwardResize(session, resize, done)
+	}
+
+	var w io.Writer = ioutil.Discard
+	if out != nil {
+		w = out
+	}
+	return session.CopyOutput(w)
+}
+
+// forwardResize sends the terminal size changes to the exec session until the
+// resize channel is closed or the session is done.
+func forwardResize(s *wsexec.Session, resize <-chan remotecommand.TerminalSize, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case size, ok := <-resize:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(aci.TerminalSize{Rows: int(size.Height), Cols: int(size.Width)})
+			if err != nil {
+				return
+			}
+			if err := s.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package azure
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/wsexec"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const fakeExecPassword = "exec-password"
+
+// execStandIn is a websocket server standing in for an ACI exec session.
+// It echoes stdin and records resize messages. On EOT it writes some trailing
+// output and closes the session, like a terminal running cat would.
+type execStandIn struct {
+	t        *testing.T
+	password chan string
+	resizes  chan string
+}
+
+func newExecStandIn(t *testing.T) *execStandIn {
+	return &execStandIn{
+		t:        t,
+		password: make(chan string, 1),
+		resizes:  make(chan string, 10),
+	}
+}
+
+func (s *execStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	_, password, err := conn.ReadMessage()
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	s.password <- string(password)
+
+	for {
+		mt, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		switch {
+		case mt == websocket.TextMessage:
+			s.resizes <- string(data)
+		case bytes.Equal(data, wsexec.EOT):
+			conn.WriteMessage(websocket.BinaryMessage, []byte(" bye"))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		default:
+			if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// managementTransport sends the requests made to the public Azure management
+// endpoint to the ACI mock. Unlike the other calls of the ACI client,
+// LaunchExec doesn't use the resource manager endpoint of the authentication.
+type managementTransport struct {
+	base   http.RoundTripper
+	target *url.URL
+}
+
+func (t *managementTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "management.azure.com" && t.target != nil {
+		u := *req.URL
+		u.Scheme = t.target.Scheme
+		u.Host = t.target.Host
+		req = req.WithContext(req.Context())
+		req.URL = &u
+		req.Host = u.Host
+	}
+	return t.base.RoundTrip(req)
+}
+
+// prepareExecMocks prepares the mocks with the requests of the ACI client to
+// the public management endpoint sent to the ACI mock.
+func prepareExecMocks(t *testing.T) (*ACIMock, *ACIProvider) {
+	transport := &managementTransport{base: http.DefaultTransport}
+	http.DefaultTransport = transport
+	defer func() { http.DefaultTransport = transport.base }()
+
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	target, err := url.Parse(aciServerMocker.GetServerURL())
+	assert.NilError(t, err)
+	transport.target = target
+
+	return aciServerMocker, provider
+}
+
+func TestExecInContainer(t *testing.T) {
+	aciServerMocker, provider := prepareExecMocks(t)
+
+	standIn := newExecStandIn(t)
+	wsServer := httptest.NewServer(standIn)
+	defer wsServer.Close()
+
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{Name: containerGroup}
+	}
+	aciServerMocker.OnLaunchExec = func(subscription, resourceGroup, containerGroup, containerName string, xc *aci.ExecRequest) (int, interface{}) {
+		assert.Check(t, is.Equal(containerGroup, "ns-pod"))
+		assert.Check(t, is.Equal(containerName, "nginx"))
+		assert.Check(t, is.Equal(xc.Command, "sh -c 'cat; echo bye'"))
+		assert.Check(t, is.Equal(xc.TerminalSize, aci.TerminalSize{Rows: 30, Cols: 100}))
+
+		return http.StatusOK, aci.ExecResponse{
+			WebSocketURI: "ws" + strings.TrimPrefix(wsServer.URL, "http"),
+			Password:     fakeExecPassword,
+		}
+	}
+
+	resize := make(chan remotecommand.TerminalSize, 2)
+	resize <- remotecommand.TerminalSize{Height: 30, Width: 100}
+	resize <- remotecommand.TerminalSize{Height: 40, Width: 120}
+
+	// Only send stdin once the resize event was received, so the session is
+	// not ended before then.
+	stdin, stdinWriter := io.Pipe()
+	go func() {
+		select {
+		case r := <-standIn.resizes:
+			assert.Check(t, is.Equal(r, `{"rows":40,"cols":120}`))
+		case <-time.After(10 * time.Second):
+			t.Error("timed out waiting for resize")
+		}
+		stdinWriter.Write([]byte("hello"))
+		stdinWriter.Close()
+	}()
+
+	var out bytes.Buffer
+	cmd := []string{"sh", "-c", "cat; echo bye"}
+	err := provider.ExecInContainer("ns-pod", "", "nginx", cmd, stdin, nopWriteCloser{&out}, nil, true, resize, 0)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(<-standIn.password, fakeExecPassword))
+	// The output written after stdin EOF is not lost.
+	assert.Check(t, is.Equal(out.String(), "hello bye"))
+}
+
+func TestExecInContainerDialError(t *testing.T) {
+	aciServerMocker, provider := prepareExecMocks(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{Name: containerGroup}
+	}
+	aciServerMocker.OnLaunchExec = func(subscription, resourceGroup, containerGroup, containerName string, xc *aci.ExecRequest) (int, interface{}) {
+		return http.StatusOK, aci.ExecResponse{
+			WebSocketURI: "ws" + strings.TrimPrefix(server.URL, "http"),
+			Password:     fakeExecPassword,
+		}
+	}
+
+	var out bytes.Buffer
+	err := provider.ExecInContainer("ns-pod", "", "nginx", []string{"ls"}, nil, nopWriteCloser{&out}, nil, false, nil, 0)
+	assert.ErrorContains(t, err, "error connecting to exec session for container nginx: 403 Forbidden")
+}
@@ -88,8 +88,13 @@ func (p *ACIProvider) GetStatsSummary(ctx context.Context) (summary *stats.Summa
 			logger.Debug("Acquired semaphore")
 
 			cgName := containerGroupName(pod)
+			t, err := p.containerGroupTarget(ctx, cgName)
+			if err != nil {
+				span.SetStatus(ocstatus.FromError(err))
+				return errors.Wrapf(err, "error looking up container group %s", cgName)
+			}
 			// cpu/mem and net stats are split because net stats do not support container level detail
-			systemStats, err := p.aciClient.GetContainerGroupMetrics(ctx, p.resourceGroup, cgName, aci.MetricsRequest{
+			systemStats, err := p.aciClient.GetContainerGroupMetrics(ctx, t.ResourceGroup, cgName, aci.MetricsRequest{
 				Dimension:    "containerName eq '*'",
 				Start:        start,
 				End:          end,
@@ -102,7 +107,7 @@ func (p *ACIProvider) GetStatsSummary(ctx context.Context) (summary *stats.Summa
 			}
 			logger.Debug("Got system stats")
 
-			netStats, err := p.aciClient.GetContainerGroupMetrics(ctx, p.resourceGroup, cgName, aci.MetricsRequest{
+			netStats, err := p.aciClient.GetContainerGroupMetrics(ctx, t.ResourceGroup, cgName, aci.MetricsRequest{
 				Start:        start,
 				End:          end,
 				Aggregations: []aci.AggregationType{aci.AggregationTypeAverage},
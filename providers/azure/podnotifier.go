@@ -0,0 +1,185 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/trace"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// podNotifierSyncInterval is the interval between two sweeps of the
+	// container groups when ARM is not throttling requests.
+	podNotifierSyncInterval = 5 * time.Second
+	// podNotifierMaxInterval caps the interval between two sweeps when ARM is
+	// throttling requests.
+	podNotifierMaxInterval = 5 * time.Minute
+)
+
+// NotifyPods instructs the provider to call the passed in function when the
+// status of a pod changes.
+//
+// Rather than getting each container group, the container groups of each
+// resource group are listed once per interval and the pods are notified when
+// the state of their container group, containers or events changed, or when
+// the container group is gone.
+// When ARM throttles the requests, the interval is backed off exponentially.
+func (p *ACIProvider) NotifyPods(ctx context.Context, notifier func(*v1.Pod)) {
+	w := newPodWatcher(p, notifier)
+	go w.run(ctx)
+}
+
+// podWatcher tracks the state of the container groups of the node between
+// sweeps.
+type podWatcher struct {
+	p        *ACIProvider
+	notifier func(*v1.Pod)
+	// pods is keyed by resource group and container group name.
+	pods map[string]*watchedPod
+}
+
+type watchedPod struct {
+	pod   *v1.Pod
+	state containerGroupState
+}
+
+// containerGroupState is the part of a container group which affects the
+// status of the pod.
+type containerGroupState struct {
+	provisioningState string
+	instanceView      aci.ContainerGroupPropertiesInstanceView
+	containers        []aci.ContainerPropertiesInstanceView
+}
+
+func newContainerGroupState(cg *aci.ContainerGroup) containerGroupState {
+	s := containerGroupState{
+		provisioningState: cg.ProvisioningState,
+		instanceView:      cg.InstanceView,
+		containers:        make([]aci.ContainerPropertiesInstanceView, 0, len(cg.Containers)),
+	}
+	for _, c := range cg.Containers {
+		s.containers = append(s.containers, c.InstanceView)
+	}
+	return s
+}
+
+func newPodWatcher(p *ACIProvider, notifier func(*v1.Pod)) *podWatcher {
+	return &podWatcher{
+		p:        p,
+		notifier: notifier,
+		pods:     make(map[string]*watchedPod),
+	}
+}
+
+func (w *podWatcher) run(ctx context.Context) {
+	interval := podNotifierSyncInterval
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		err := w.sync(ctx)
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("Error syncing container groups")
+		}
+
+		interval = nextPodNotifierInterval(interval, err)
+		t.Reset(interval)
+	}
+}
+
+// sync lists the container groups of each resource group and notifies the pods
+// whose container group changed since the previous sync.
+func (w *podWatcher) sync(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "aci.podWatcher.sync")
+	defer span.End()
+	ctx = addAzureAttributes(ctx, span, w.p)
+
+	seen := make(map[string]bool, len(w.pods))
+	for _, resourceGroup := range w.p.resourceGroups() {
+		cgs, err := w.p.aciClient.ListContainerGroups(ctx, resourceGroup)
+		if err != nil {
+			// Without a complete sweep, missing container groups can't be
+			// told apart from deleted ones.
+			return err
+		}
+
+		for i := range cgs.Value {
+			cg := &cgs.Value[i]
+			if cg.Tags["NodeName"] != w.p.nodeName || len(cg.Containers) == 0 {
+				continue
+			}
+
+			key := resourceGroup + "/" + cg.Name
+			seen[key] = true
+
+			state := newContainerGroupState(cg)
+			if prev, ok := w.pods[key]; ok && reflect.DeepEqual(prev.state, state) {
+				continue
+			}
+
+			pod, err := containerGroupToPod(cg)
+			if err != nil {
+				log.G(ctx).WithFields(log.Fields{
+					"name": cg.Name,
+					"id":   cg.ID,
+				}).WithError(err).Error("error converting container group to pod")
+				continue
+			}
+
+			t := target{ResourceGroup: resourceGroup, Region: cg.Location}
+			w.p.setContainerGroupTarget(cg.Name, t)
+			setTargetAnnotations(pod, t)
+
+			w.pods[key] = &watchedPod{pod: pod, state: state}
+			w.notifier(pod)
+		}
+	}
+
+	for key, wp := range w.pods {
+		if !seen[key] {
+			delete(w.pods, key)
+			w.notifier(wp.pod)
+		}
+	}
+
+	return nil
+}
+
+// nextPodNotifierInterval returns the interval until the next sweep.
+// It is doubled, or set to the delay requested by ARM, when the last sweep was
+// throttled, and halved back to the sync interval otherwise.
+func nextPodNotifierInterval(interval time.Duration, err error) time.Duration {
+	e, ok := err.(*api.Error)
+	if !ok || e.StatusCode != http.StatusTooManyRequests {
+		interval /= 2
+		if interval < podNotifierSyncInterval {
+			interval = podNotifierSyncInterval
+		}
+		return interval
+	}
+
+	interval *= 2
+	if retryAfter, err := strconv.Atoi(e.Header.Get("Retry-After")); err == nil {
+		if d := time.Duration(retryAfter) * time.Second; d > interval {
+			interval = d
+		}
+	}
+	if interval > podNotifierMaxInterval {
+		interval = podNotifierMaxInterval
+	}
+	return interval
+}
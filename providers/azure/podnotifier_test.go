@@ -0,0 +1,122 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestPodWatcherSync(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	newContainerGroup := func(name, state string) aci.ContainerGroup {
+		return aci.ContainerGroup{
+			Name: "ns-" + name,
+			Tags: map[string]string{"NodeName": fakeNodeName, "Namespace": "ns", "PodName": name},
+			ContainerGroupProperties: aci.ContainerGroupProperties{
+				ProvisioningState: "Succeeded",
+				InstanceView:      aci.ContainerGroupPropertiesInstanceView{State: state},
+				Containers: []aci.Container{{
+					Name: "nginx",
+					ContainerProperties: aci.ContainerProperties{
+						Image: "nginx",
+						Resources: aci.ResourceRequirements{
+							Requests: &aci.ComputeResources{CPU: 1, MemoryInGB: 1.5},
+						},
+						InstanceView: aci.ContainerPropertiesInstanceView{
+							CurrentState: aci.ContainerState{State: state},
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	cgs := []aci.ContainerGroup{
+		newContainerGroup("web", "Running"),
+		newContainerGroup("db", "Running"),
+	}
+	other := newContainerGroup("other", "Running")
+	other.Tags["NodeName"] = "other-node"
+
+	var lists int
+	aciServerMocker.OnGetContainerGroups = func(subscription, resourceGroup string) (int, interface{}) {
+		lists++
+		assert.Check(t, is.Equal(fakeResourceGroup, resourceGroup))
+		return http.StatusOK, aci.ContainerGroupListResult{Value: append(cgs, other)}
+	}
+
+	var notified []string
+	w := newPodWatcher(provider, func(pod *v1.Pod) {
+		notified = append(notified, pod.Namespace+"/"+pod.Name)
+	})
+	ctx := context.Background()
+
+	// All the pods of the node are notified on the first sync.
+	assert.NilError(t, w.sync(ctx))
+	assert.Check(t, is.Len(notified, 2))
+
+	// Nothing changed.
+	notified = nil
+	assert.NilError(t, w.sync(ctx))
+	assert.Check(t, is.Len(notified, 0))
+
+	// Container state and event changes are notified.
+	cgs[0].Containers[0].InstanceView.Events = []aci.Event{{Name: "Pulled", Count: 1}}
+	cgs[1] = newContainerGroup("db", "Terminated")
+	notified = nil
+	assert.NilError(t, w.sync(ctx))
+	assert.Check(t, is.DeepEqual(notified, []string{"ns/web", "ns/db"}))
+
+	// Deleted container groups are notified.
+	cgs = cgs[:1]
+	notified = nil
+	assert.NilError(t, w.sync(ctx))
+	assert.Check(t, is.DeepEqual(notified, []string{"ns/db"}))
+
+	// Nothing is notified when the sweep fails.
+	aciServerMocker.OnGetContainerGroups = func(subscription, resourceGroup string) (int, interface{}) {
+		return http.StatusTooManyRequests, nil
+	}
+	notified = nil
+	err = w.sync(ctx)
+	assert.Check(t, err != nil)
+	assert.Check(t, is.Len(notified, 0))
+	assert.Check(t, is.Equal(nextPodNotifierInterval(podNotifierSyncInterval, err), 2*podNotifierSyncInterval))
+
+	assert.Check(t, is.Equal(lists, 4))
+}
+
+func TestNextPodNotifierInterval(t *testing.T) {
+	throttled := &api.Error{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	retryAfter := &api.Error{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"60"}}}
+	failed := &api.Error{StatusCode: http.StatusInternalServerError}
+
+	cases := []struct {
+		name     string
+		interval time.Duration
+		err      error
+		expected time.Duration
+	}{
+		{"success", podNotifierSyncInterval, nil, podNotifierSyncInterval},
+		{"recovering", 40 * time.Second, nil, 20 * time.Second},
+		{"failed", 40 * time.Second, failed, 20 * time.Second},
+		{"throttled", podNotifierSyncInterval, throttled, 10 * time.Second},
+		{"retry after", podNotifierSyncInterval, retryAfter, time.Minute},
+		{"capped", 4 * time.Minute, throttled, podNotifierMaxInterval},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Check(t, is.Equal(nextPodNotifierInterval(c.interval, c.err), c.expected))
+		})
+	}
+}
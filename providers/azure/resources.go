@@ -0,0 +1,112 @@
+package azure
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// containerGroupMaxResources is the maximum amount of resources a container
+// group may request in a region.
+type containerGroupMaxResources struct {
+	CPU        float64
+	MemoryInGB float64
+}
+
+// defaultContainerGroupMaxResources is used for the regions without an entry
+// in the RegionLimits table of the provider config.
+var defaultContainerGroupMaxResources = containerGroupMaxResources{
+	CPU:        4,
+	MemoryInGB: 16,
+}
+
+type regionLimitsConfig struct {
+	CPU    string
+	Memory string
+}
+
+// parseRegionLimits converts the RegionLimits table of the provider config,
+// keyed by region, into the maximum resources of a container group.
+// Resources which are not set use the default.
+func parseRegionLimits(config map[string]regionLimitsConfig) (map[string]containerGroupMaxResources, error) {
+	limits := make(map[string]containerGroupMaxResources, len(config))
+	for region, c := range config {
+		max := defaultContainerGroupMaxResources
+
+		if c.CPU != "" {
+			q, err := resource.ParseQuantity(c.CPU)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing CPU limit for region %s: %v", region, err)
+			}
+			max.CPU = float64(q.MilliValue()) / 1000.00
+		}
+
+		if c.Memory != "" {
+			q, err := resource.ParseQuantity(c.Memory)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing memory limit for region %s: %v", region, err)
+			}
+			max.MemoryInGB = float64(q.Value()) / 1000000000.00
+		}
+
+		limits[normalizeRegion(region)] = max
+	}
+	return limits, nil
+}
+
+func normalizeRegion(region string) string {
+	return strings.Replace(strings.ToLower(region), " ", "", -1)
+}
+
+// maxContainerGroupResources returns the maximum amount of resources a
+// container group may request in the region.
+func (p *ACIProvider) maxContainerGroupResources(region string) containerGroupMaxResources {
+	if max, ok := p.regionLimits[normalizeRegion(region)]; ok {
+		return max
+	}
+	return defaultContainerGroupMaxResources
+}
+
+// validateContainerGroupResources checks that the total requests of the
+// containers, and the limits of each container, fit in a container group in
+// the region.
+func (p *ACIProvider) validateContainerGroupResources(containers []aci.Container, region string) error {
+	max := p.maxContainerGroupResources(region)
+
+	var cpu, memory float64
+	for _, c := range containers {
+		if r := c.Resources.Requests; r != nil {
+			cpu += r.CPU
+			memory += r.MemoryInGB
+		}
+	}
+
+	// Requests are multiples of 10m CPU and 0.1GB of memory.
+	cpu = math.Round(cpu*100) / 100
+	memory = math.Round(memory*10) / 10
+
+	if exceeds(cpu, max.CPU) || exceeds(memory, max.MemoryInGB) {
+		return strongerrors.InvalidArgument(fmt.Errorf(
+			"pod requests %g CPU and %gGB of memory, but container groups in region %s can request at most %g CPU and %gGB of memory",
+			cpu, memory, region, max.CPU, max.MemoryInGB))
+	}
+
+	for _, c := range containers {
+		if l := c.Resources.Limits; l != nil && (exceeds(l.CPU, max.CPU) || exceeds(l.MemoryInGB, max.MemoryInGB)) {
+			return strongerrors.InvalidArgument(fmt.Errorf(
+				"container %s is limited to %g CPU and %gGB of memory, but container groups in region %s can use at most %g CPU and %gGB of memory",
+				c.Name, l.CPU, l.MemoryInGB, region, max.CPU, max.MemoryInGB))
+		}
+	}
+	return nil
+}
+
+// exceeds compares resource amounts, ignoring the rounding errors of summing
+// floating point values.
+func exceeds(v, max float64) bool {
+	return v-max > 1e-9
+}
@@ -0,0 +1,143 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseRegionLimits(t *testing.T) {
+	limits, err := parseRegionLimits(map[string]regionLimitsConfig{
+		"East US":    {CPU: "2", Memory: "3500M"},
+		"westus2":    {CPU: "1500m"},
+		"westeurope": {},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(limits, map[string]containerGroupMaxResources{
+		"eastus":     {CPU: 2, MemoryInGB: 3.5},
+		"westus2":    {CPU: 1.5, MemoryInGB: defaultContainerGroupMaxResources.MemoryInGB},
+		"westeurope": defaultContainerGroupMaxResources,
+	}))
+
+	_, err = parseRegionLimits(map[string]regionLimitsConfig{"eastus": {Memory: "lots"}})
+	assert.ErrorContains(t, err, "error parsing memory limit for region eastus")
+}
+
+func TestValidateContainerGroupResources(t *testing.T) {
+	p := &ACIProvider{
+		region:       "eastus",
+		regionLimits: map[string]containerGroupMaxResources{"eastus": {CPU: 2, MemoryInGB: 4}},
+	}
+
+	container := func(name string, cpu, memory float64, limits *aci.ComputeResources) aci.Container {
+		return aci.Container{
+			Name: name,
+			ContainerProperties: aci.ContainerProperties{
+				Resources: aci.ResourceRequirements{
+					Requests: &aci.ComputeResources{CPU: cpu, MemoryInGB: memory},
+					Limits:   limits,
+				},
+			},
+		}
+	}
+
+	assert.NilError(t, p.validateContainerGroupResources([]aci.Container{
+		container("a", 1.1, 1.5, nil),
+		container("b", 0.9, 2.5, &aci.ComputeResources{CPU: 2, MemoryInGB: 4}),
+	}, "eastus"))
+
+	err := p.validateContainerGroupResources([]aci.Container{
+		container("a", 1.5, 1.5, nil),
+		container("b", 1, 1, nil),
+	}, "eastus")
+	assert.Check(t, strongerrors.IsInvalidArgument(err))
+	assert.Check(t, is.Error(err, "pod requests 2.5 CPU and 2.5GB of memory, but container groups in region eastus can request at most 2 CPU and 4GB of memory"))
+
+	err = p.validateContainerGroupResources([]aci.Container{
+		container("a", 1, 1, &aci.ComputeResources{CPU: 1, MemoryInGB: 8}),
+	}, "eastus")
+	assert.Check(t, strongerrors.IsInvalidArgument(err))
+	assert.Check(t, is.ErrorContains(err, "container a is limited to 1 CPU and 8GB of memory"))
+
+	// Regions without limits in the config use the default.
+	assert.NilError(t, p.validateContainerGroupResources([]aci.Container{
+		container("a", 4, 16, nil),
+	}, "westus"))
+}
+
+func TestCreatePodExceedingRegionLimits(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		t.Error("Container group should not be created")
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "ns",
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "nginx",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							"cpu":    resource.MustParse("8"),
+							"memory": resource.MustParse("1G"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err = provider.CreatePod(context.Background(), pod)
+	assert.Check(t, strongerrors.IsInvalidArgument(err))
+	assert.Check(t, strings.HasPrefix(err.Error(), "pod requests 8 CPU and 1GB of memory"), err.Error())
+}
+
+func TestCreatePodWithResourceLimitOnly(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		resources := cg.ContainerGroupProperties.Containers[0].Resources
+		assert.Check(t, is.DeepEqual(resources.Requests, &aci.ComputeResources{CPU: 0.5, MemoryInGB: 0.5}))
+		assert.Check(t, is.DeepEqual(resources.Limits, &aci.ComputeResources{CPU: 0.5, MemoryInGB: 0.5}))
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "ns",
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "nginx",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							"cpu":    resource.MustParse("500m"),
+							"memory": resource.MustParse("500M"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	assert.NilError(t, provider.CreatePod(context.Background(), pod))
+}
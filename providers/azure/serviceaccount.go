@@ -0,0 +1,190 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// minTokenExpirationSeconds is the minimum lifetime of the projected service
+// account tokens, one year.
+// ACI can't update the volumes of a running container group, and re-creating
+// it restarts its containers, so tokens are not refreshed. They are requested
+// with a long lifetime instead of the expirationSeconds of the pod, which is
+// only used when it is longer.
+const minTokenExpirationSeconds = int64(365 * 24 * 60 * 60)
+
+// filteredServiceAccountVolumes returns the names of the volumes mounted at the
+// service account secret mount path which filterServiceAccountSecretVolume
+// removes from the container group of the pod.
+func filteredServiceAccountVolumes(osType string, pod *v1.Pod) map[string]bool {
+	automount := pod.Spec.AutomountServiceAccountToken == nil || *pod.Spec.AutomountServiceAccountToken
+	if !strings.EqualFold(osType, "Windows") && automount {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, c := range pod.Spec.Containers {
+		for _, m := range c.VolumeMounts {
+			if strings.EqualFold(serviceAccountSecretMountPath, m.MountPath) {
+				names[m.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// getProjectedVolumePaths returns the files of a projected volume, base64
+// encoded and keyed by path, as expected by an ACI secret volume.
+func (p *ACIProvider) getProjectedVolumePaths(pod *v1.Pod, projected *v1.ProjectedVolumeSource) (map[string]string, error) {
+	paths := make(map[string]string)
+	for _, source := range projected.Sources {
+		switch {
+		case source.ServiceAccountToken != nil:
+			tr, err := p.requestServiceAccountToken(pod, source.ServiceAccountToken)
+			if err != nil {
+				return nil, err
+			}
+			paths[source.ServiceAccountToken.Path] = base64.StdEncoding.EncodeToString([]byte(tr.Status.Token))
+
+		case source.Secret != nil:
+			secret, err := p.resourceManager.GetSecret(source.Secret.Name, pod.Namespace)
+			if err != nil {
+				if k8serr.IsNotFound(err) && source.Secret.Optional != nil && *source.Secret.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("error getting secret %s: %v", source.Secret.Name, err)
+			}
+			if err := projectKeys(paths, secret.Data, source.Secret.Items, source.Secret.Optional); err != nil {
+				return nil, fmt.Errorf("error projecting secret %s: %v", source.Secret.Name, err)
+			}
+
+		case source.ConfigMap != nil:
+			configMap, err := p.resourceManager.GetConfigMap(source.ConfigMap.Name, pod.Namespace)
+			if err != nil {
+				if k8serr.IsNotFound(err) && source.ConfigMap.Optional != nil && *source.ConfigMap.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("error getting config map %s: %v", source.ConfigMap.Name, err)
+			}
+			data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+			for k, v := range configMap.Data {
+				data[k] = []byte(v)
+			}
+			for k, v := range configMap.BinaryData {
+				data[k] = v
+			}
+			if err := projectKeys(paths, data, source.ConfigMap.Items, source.ConfigMap.Optional); err != nil {
+				return nil, fmt.Errorf("error projecting config map %s: %v", source.ConfigMap.Name, err)
+			}
+
+		case source.DownwardAPI != nil:
+			for _, item := range source.DownwardAPI.Items {
+				if item.FieldRef == nil {
+					return nil, fmt.Errorf("downward API item %s: only field references are supported", item.Path)
+				}
+				value, err := podFieldValue(pod, item.FieldRef.FieldPath)
+				if err != nil {
+					return nil, fmt.Errorf("downward API item %s: %v", item.Path, err)
+				}
+				paths[item.Path] = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// requestServiceAccountToken requests a token for the service account of the
+// pod, bound to the pod, using the TokenRequest API.
+// The token is valid for at least minTokenExpirationSeconds, unless the API
+// server caps the lifetime of tokens, see --service-account-max-token-expiration.
+func (p *ACIProvider) requestServiceAccountToken(pod *v1.Pod, projection *v1.ServiceAccountTokenProjection) (*authenticationv1.TokenRequest, error) {
+	expirationSeconds := minTokenExpirationSeconds
+	if projection.ExpirationSeconds != nil && *projection.ExpirationSeconds > expirationSeconds {
+		expirationSeconds = *projection.ExpirationSeconds
+	}
+
+	var audiences []string
+	if projection.Audience != "" {
+		audiences = []string{projection.Audience}
+	}
+
+	serviceAccountName := pod.Spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = "default"
+	}
+
+	tr, err := p.resourceManager.GetServiceAccountToken(serviceAccountName, pod.Namespace, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         audiences,
+			ExpirationSeconds: &expirationSeconds,
+			BoundObjectRef: &authenticationv1.BoundObjectReference{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error requesting a token for service account %s: %v", serviceAccountName, err)
+	}
+
+	if expiry := tr.Status.ExpirationTimestamp.Time; time.Until(expiry) < time.Duration(expirationSeconds)*time.Second/2 {
+		log.G(context.TODO()).WithFields(log.Fields{
+			"namespace":      pod.Namespace,
+			"name":           pod.Name,
+			"serviceAccount": serviceAccountName,
+			"expiry":         expiry,
+		}).Warn("The service account token expires before the requested lifetime and won't be refreshed")
+	}
+	return tr, nil
+}
+
+// projectKeys adds the data selected by items, or all of it when items is
+// empty, to paths.
+func projectKeys(paths map[string]string, data map[string][]byte, items []v1.KeyToPath, optional *bool) error {
+	if len(items) == 0 {
+		for k, v := range data {
+			paths[k] = base64.StdEncoding.EncodeToString(v)
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		v, ok := data[item.Key]
+		if !ok {
+			if optional != nil && *optional {
+				continue
+			}
+			return fmt.Errorf("key %s does not exist", item.Key)
+		}
+		paths[item.Path] = base64.StdEncoding.EncodeToString(v)
+	}
+	return nil
+}
+
+// podFieldValue returns the value of the pod fields which can be projected
+// before the container group is created.
+func podFieldValue(pod *v1.Pod, fieldPath string) (string, error) {
+	switch fieldPath {
+	case "metadata.name":
+		return pod.Name, nil
+	case "metadata.namespace":
+		return pod.Namespace, nil
+	case "metadata.uid":
+		return string(pod.UID), nil
+	case "spec.nodeName":
+		return pod.Spec.NodeName, nil
+	case "spec.serviceAccountName":
+		return pod.Spec.ServiceAccountName, nil
+	}
+	return "", fmt.Errorf("field %s is not supported", fieldPath)
+}
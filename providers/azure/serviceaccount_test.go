@@ -0,0 +1,190 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeTokenIssuer answers TokenRequests with a numbered token valid for
+// lifetime.
+type fakeTokenIssuer struct {
+	mu       sync.Mutex
+	lifetime time.Duration
+	requests []*authenticationv1.TokenRequest
+}
+
+func (f *fakeTokenIssuer) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func (f *fakeTokenIssuer) reactor(action k8stesting.Action) (bool, runtime.Object, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+	f.requests = append(f.requests, tr)
+	tr.Status = authenticationv1.TokenRequestStatus{
+		Token:               "token-" + strconv.Itoa(len(f.requests)),
+		ExpirationTimestamp: metav1.NewTime(time.Now().Add(f.lifetime)),
+	}
+	return true, tr, nil
+}
+
+func setTokenResourceManager(t *testing.T, p *ACIProvider, issuer *fakeTokenIssuer, objects ...*v1.ConfigMap) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", issuer.reactor)
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, o := range objects {
+		assert.NilError(t, indexer.Add(o))
+	}
+
+	rm, err := manager.NewResourceManager(nil, nil, corev1listers.NewConfigMapLister(indexer), nil, manager.WithServiceAccountTokens(client.CoreV1()))
+	assert.NilError(t, err)
+	p.resourceManager = rm
+}
+
+func newServiceAccountTokenPod(expirationSeconds int64) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "ns",
+			UID:       "uid",
+		},
+		Spec: v1.PodSpec{
+			ServiceAccountName: "builder",
+			Containers: []v1.Container{{
+				Name: "nginx",
+				VolumeMounts: []v1.VolumeMount{{
+					Name:      "kube-api-access",
+					MountPath: serviceAccountSecretMountPath,
+				}},
+			}},
+			Volumes: []v1.Volume{{
+				Name: "kube-api-access",
+				VolumeSource: v1.VolumeSource{
+					Projected: &v1.ProjectedVolumeSource{
+						Sources: []v1.VolumeProjection{
+							{ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+								Audience:          "vault",
+								ExpirationSeconds: &expirationSeconds,
+								Path:              "token",
+							}},
+							{ConfigMap: &v1.ConfigMapProjection{
+								LocalObjectReference: v1.LocalObjectReference{Name: "kube-root-ca.crt"},
+								Items:                []v1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+							}},
+							{DownwardAPI: &v1.DownwardAPIProjection{
+								Items: []v1.DownwardAPIVolumeFile{{
+									Path:     "namespace",
+									FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+								}},
+							}},
+						},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func decodeSecretVolume(t *testing.T, volume aci.Volume) map[string]string {
+	files := make(map[string]string, len(volume.Secret))
+	for k, v := range volume.Secret {
+		b, err := base64.StdEncoding.DecodeString(v)
+		assert.NilError(t, err)
+		files[k] = string(b)
+	}
+	return files
+}
+
+func TestCreatePodProjectedServiceAccountToken(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	issuer := &fakeTokenIssuer{lifetime: time.Hour}
+	setTokenResourceManager(t, provider, issuer, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "ns"},
+		Data:       map[string]string{"ca.crt": "ca"},
+	})
+
+	var created *aci.ContainerGroup
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		created = cg
+		return http.StatusOK, cg
+	}
+
+	pod := newServiceAccountTokenPod(7200)
+	assert.NilError(t, provider.CreatePod(context.Background(), pod))
+
+	assert.Assert(t, created != nil)
+	assert.Assert(t, is.Len(created.Volumes, 1))
+	assert.Check(t, is.DeepEqual(decodeSecretVolume(t, created.Volumes[0]), map[string]string{
+		"token":     "token-1",
+		"ca.crt":    "ca",
+		"namespace": "ns",
+	}))
+	assert.Check(t, is.Len(created.Containers[0].VolumeMounts, 1))
+
+	assert.Assert(t, is.Equal(issuer.count(), 1))
+	spec := issuer.requests[0].Spec
+	assert.Check(t, is.DeepEqual(spec.Audiences, []string{"vault"}))
+	// Tokens are not refreshed, so they are requested with a long lifetime.
+	assert.Check(t, is.Equal(*spec.ExpirationSeconds, minTokenExpirationSeconds))
+	assert.Check(t, is.DeepEqual(spec.BoundObjectRef, &authenticationv1.BoundObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       "web",
+		UID:        "uid",
+	}))
+
+	// Longer lifetimes set by the pod are kept.
+	pod = newServiceAccountTokenPod(2 * minTokenExpirationSeconds)
+	assert.NilError(t, provider.CreatePod(context.Background(), pod))
+	assert.Assert(t, is.Equal(issuer.count(), 2))
+	assert.Check(t, is.Equal(*issuer.requests[1].Spec.ExpirationSeconds, 2*minTokenExpirationSeconds))
+}
+
+func TestCreatePodAutomountServiceAccountTokenDisabled(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	issuer := &fakeTokenIssuer{lifetime: time.Hour}
+	setTokenResourceManager(t, provider, issuer)
+
+	var created *aci.ContainerGroup
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		created = cg
+		return http.StatusOK, cg
+	}
+
+	pod := newServiceAccountTokenPod(3600)
+	automount := false
+	pod.Spec.AutomountServiceAccountToken = &automount
+	assert.NilError(t, provider.CreatePod(context.Background(), pod))
+
+	assert.Assert(t, created != nil)
+	assert.Check(t, is.Len(created.Volumes, 0))
+	assert.Check(t, is.Len(created.Containers[0].VolumeMounts, 0))
+	assert.Check(t, is.Equal(issuer.count(), 0))
+}
@@ -0,0 +1,266 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// The resource group and region annotations restrict the targets the
+	// container group of a pod can be created in. They are set on the pods
+	// returned by the provider to the target of their container group.
+	aciResourceGroupAnnotation = "virtual-kubelet.io/aci-resource-group"
+	aciRegionAnnotation        = "virtual-kubelet.io/aci-region"
+)
+
+// regionLabels are the node labels which pods can select the region of their
+// container group with, through their node selector or required node affinity.
+var regionLabels = []string{
+	"topology.kubernetes.io/region",
+	"failure-domain.beta.kubernetes.io/region",
+}
+
+// capacityErrorCodes are the ARM error codes returned when a region can't
+// host a container group, in which case the next target is tried.
+var capacityErrorCodes = map[string]bool{
+	"ServiceUnavailable":           true,
+	"ContainerGroupQuotaReached":   true,
+	"SkuNotAvailable":              true,
+	"ResourceRequestsNotAvailable": true,
+}
+
+// target is a resource group and region container groups can be created in.
+// Targets with a higher priority are tried first.
+type target struct {
+	ResourceGroup string
+	Region        string
+	Priority      int
+}
+
+func (t target) String() string {
+	return t.ResourceGroup + "/" + t.Region
+}
+
+// sortTargets returns the targets ordered by decreasing priority, targets
+// with the same priority keep the order of the config.
+func sortTargets(targets []target) []target {
+	sorted := append([]target(nil), targets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	return sorted
+}
+
+// newTargets returns the targets of the provider config, or the resource
+// group and region of the config when no target is set.
+func newTargets(c *providerConfig) []target {
+	if len(c.Targets) == 0 {
+		return []target{{ResourceGroup: c.ResourceGroup, Region: c.Region}}
+	}
+	return sortTargets(c.Targets)
+}
+
+// resourceGroups returns the resource groups of the targets, once each.
+func (p *ACIProvider) resourceGroups() []string {
+	seen := make(map[string]bool, len(p.targets))
+	resourceGroups := make([]string, 0, len(p.targets))
+	for _, t := range p.targets {
+		key := strings.ToLower(t.ResourceGroup)
+		if !seen[key] {
+			seen[key] = true
+			resourceGroups = append(resourceGroups, t.ResourceGroup)
+		}
+	}
+	return resourceGroups
+}
+
+// candidateTargets returns the targets the container group of the pod can be
+// created in, by decreasing priority.
+func (p *ACIProvider) candidateTargets(pod *v1.Pod) ([]target, error) {
+	resourceGroup := pod.Annotations[aciResourceGroupAnnotation]
+	region := pod.Annotations[aciRegionAnnotation]
+
+	targets := make([]target, 0, len(p.targets))
+	for _, t := range p.targets {
+		if resourceGroup != "" && !strings.EqualFold(resourceGroup, t.ResourceGroup) {
+			continue
+		}
+		if region != "" && normalizeRegion(region) != normalizeRegion(t.Region) {
+			continue
+		}
+		if !regionAllowed(pod, t.Region) {
+			continue
+		}
+		targets = append(targets, t)
+	}
+
+	if len(targets) == 0 {
+		return nil, strongerrors.InvalidArgument(fmt.Errorf("none of the ACI targets %v matches the annotations, node selector and node affinity of the pod", p.targets))
+	}
+	return targets, nil
+}
+
+// regionAllowed returns whether the node selector and the required node
+// affinity of the pod allow the region. Only the region labels are evaluated.
+func regionAllowed(pod *v1.Pod, region string) bool {
+	region = normalizeRegion(region)
+
+	for _, label := range regionLabels {
+		if v, ok := pod.Spec.NodeSelector[label]; ok && normalizeRegion(v) != region {
+			return false
+		}
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	// The terms are ORed, and the expressions of a term are ANDed.
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return true
+	}
+	for _, term := range terms {
+		if termAllowsRegion(term, region) {
+			return true
+		}
+	}
+	return false
+}
+
+func termAllowsRegion(term v1.NodeSelectorTerm, region string) bool {
+	for _, expr := range term.MatchExpressions {
+		if !isRegionLabel(expr.Key) {
+			continue
+		}
+
+		var in bool
+		for _, v := range expr.Values {
+			if normalizeRegion(v) == region {
+				in = true
+				break
+			}
+		}
+
+		switch expr.Operator {
+		case v1.NodeSelectorOpIn:
+			if !in {
+				return false
+			}
+		case v1.NodeSelectorOpNotIn:
+			if in {
+				return false
+			}
+		case v1.NodeSelectorOpExists:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isRegionLabel(key string) bool {
+	for _, label := range regionLabels {
+		if key == label {
+			return true
+		}
+	}
+	return false
+}
+
+// isCapacityError returns whether the container group could not be created
+// because the target lacks capacity.
+func isCapacityError(err error) bool {
+	e, ok := err.(*api.Error)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusServiceUnavailable || capacityErrorCodes[e.Code]
+}
+
+// setTargetAnnotations records the target of the container group of the pod.
+func setTargetAnnotations(pod *v1.Pod, t target) {
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[aciResourceGroupAnnotation] = t.ResourceGroup
+	pod.Annotations[aciRegionAnnotation] = t.Region
+}
+
+func (p *ACIProvider) setContainerGroupTarget(name string, t target) {
+	p.containerGroupTargetsMu.Lock()
+	defer p.containerGroupTargetsMu.Unlock()
+
+	if p.containerGroupTargets == nil {
+		p.containerGroupTargets = make(map[string]target)
+	}
+	p.containerGroupTargets[name] = t
+}
+
+func (p *ACIProvider) forgetContainerGroupTarget(name string) {
+	p.containerGroupTargetsMu.Lock()
+	defer p.containerGroupTargetsMu.Unlock()
+	delete(p.containerGroupTargets, name)
+}
+
+func (p *ACIProvider) cachedContainerGroupTarget(name string) (target, bool) {
+	p.containerGroupTargetsMu.Lock()
+	defer p.containerGroupTargetsMu.Unlock()
+	t, ok := p.containerGroupTargets[name]
+	return t, ok
+}
+
+// containerGroupTarget returns the target of the named container group,
+// looking it up in the resource groups of the targets when it isn't known.
+func (p *ACIProvider) containerGroupTarget(ctx context.Context, name string) (target, error) {
+	if t, ok := p.cachedContainerGroupTarget(name); ok {
+		return t, nil
+	}
+	_, t, err := p.getContainerGroup(ctx, name)
+	return t, err
+}
+
+// getContainerGroup returns the named container group and its target.
+// The container group is looked up in the resource groups of the targets when
+// its target isn't known, e.g. after a restart.
+// A strongerrors.NotFound error is returned if there is no such container
+// group.
+func (p *ACIProvider) getContainerGroup(ctx context.Context, name string) (*aci.ContainerGroup, target, error) {
+	if t, ok := p.cachedContainerGroupTarget(name); ok {
+		cg, _, err := p.aciClient.GetContainerGroup(ctx, t.ResourceGroup, name)
+		if err != nil {
+			err = wrapError(err)
+			if strongerrors.IsNotFound(err) {
+				p.forgetContainerGroupTarget(name)
+			}
+			return nil, target{}, err
+		}
+		return cg, t, nil
+	}
+
+	for _, resourceGroup := range p.resourceGroups() {
+		cg, _, err := p.aciClient.GetContainerGroup(ctx, resourceGroup, name)
+		if err != nil {
+			err = wrapError(err)
+			if strongerrors.IsNotFound(err) {
+				continue
+			}
+			return nil, target{}, err
+		}
+
+		t := target{ResourceGroup: resourceGroup, Region: cg.Location}
+		p.setContainerGroupTarget(name, t)
+		return cg, t, nil
+	}
+
+	return nil, target{}, strongerrors.NotFound(fmt.Errorf("container group %s not found in resource groups %v", name, p.resourceGroups()))
+}
@@ -0,0 +1,218 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCandidateTargets(t *testing.T) {
+	p := &ACIProvider{
+		targets: sortTargets([]target{
+			{ResourceGroup: "rg-west", Region: "westus", Priority: 1},
+			{ResourceGroup: "rg-east", Region: "eastus", Priority: 10},
+			{ResourceGroup: "rg-west2", Region: "westus2", Priority: 1},
+		}),
+	}
+
+	regionAffinity := func(op v1.NodeSelectorOperator, values ...string) *v1.Affinity {
+		return &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: "type", Operator: v1.NodeSelectorOpIn, Values: []string{"virtual-kubelet"}},
+						{Key: "topology.kubernetes.io/region", Operator: op, Values: values},
+					},
+				}},
+			},
+		}}
+	}
+
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		selector    map[string]string
+		affinity    *v1.Affinity
+		expected    []string
+	}{
+		{
+			name:     "all",
+			expected: []string{"rg-east/eastus", "rg-west/westus", "rg-west2/westus2"},
+		},
+		{
+			name:        "region annotation",
+			annotations: map[string]string{aciRegionAnnotation: "West US"},
+			expected:    []string{"rg-west/westus"},
+		},
+		{
+			name:        "resource group annotation",
+			annotations: map[string]string{aciResourceGroupAnnotation: "rg-west2"},
+			expected:    []string{"rg-west2/westus2"},
+		},
+		{
+			name:     "node selector",
+			selector: map[string]string{"failure-domain.beta.kubernetes.io/region": "eastus"},
+			expected: []string{"rg-east/eastus"},
+		},
+		{
+			name:     "affinity in",
+			affinity: regionAffinity(v1.NodeSelectorOpIn, "westus2", "westus"),
+			expected: []string{"rg-west/westus", "rg-west2/westus2"},
+		},
+		{
+			name:     "affinity not in",
+			affinity: regionAffinity(v1.NodeSelectorOpNotIn, "eastus"),
+			expected: []string{"rg-west/westus", "rg-west2/westus2"},
+		},
+		{
+			name:        "conflicting constraints",
+			annotations: map[string]string{aciRegionAnnotation: "eastus"},
+			affinity:    regionAffinity(v1.NodeSelectorOpIn, "westus"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: c.annotations},
+				Spec:       v1.PodSpec{NodeSelector: c.selector, Affinity: c.affinity},
+			}
+
+			targets, err := p.candidateTargets(pod)
+			if c.expected == nil {
+				assert.Check(t, strongerrors.IsInvalidArgument(err))
+				return
+			}
+			assert.NilError(t, err)
+
+			var names []string
+			for _, target := range targets {
+				names = append(names, target.String())
+			}
+			assert.Check(t, is.DeepEqual(names, c.expected))
+		})
+	}
+}
+
+func TestCreatePodFallsBackOnCapacityError(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	provider.targets = sortTargets([]target{
+		{ResourceGroup: "rg-east", Region: "eastus", Priority: 2},
+		{ResourceGroup: "rg-west", Region: "westus", Priority: 1},
+	})
+
+	var attempts []string
+	var created *aci.ContainerGroup
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		attempts = append(attempts, resourceGroup+"/"+cg.Location)
+		if resourceGroup == "rg-east" {
+			return http.StatusConflict, map[string]interface{}{
+				"error": map[string]string{
+					"code":    "ServiceUnavailable",
+					"message": "The requested resource is not available in the location 'eastus' at this moment.",
+				},
+			}
+		}
+
+		created = cg
+		cg.Containers[0].Resources.Requests = &aci.ComputeResources{CPU: 1, MemoryInGB: 1.5}
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+		Spec: v1.PodSpec{
+			NodeName:   fakeNodeName,
+			Containers: []v1.Container{{Name: "nginx"}},
+		},
+	}
+	assert.NilError(t, provider.CreatePod(context.Background(), pod))
+	assert.Check(t, is.DeepEqual(attempts, []string{"rg-east/eastus", "rg-west/westus"}))
+
+	// The target of the container group is recorded in the annotations of
+	// the pod, and used to delete it.
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		assert.Check(t, is.Equal(resourceGroup, "rg-west"))
+		return http.StatusOK, created
+	}
+	p, err := provider.GetPod(context.Background(), "ns", "web")
+	assert.NilError(t, err)
+	assert.Assert(t, p != nil)
+	assert.Check(t, is.Equal(p.Annotations[aciResourceGroupAnnotation], "rg-west"))
+	assert.Check(t, is.Equal(p.Annotations[aciRegionAnnotation], "westus"))
+
+	var deleted string
+	aciServerMocker.OnDelete = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		deleted = resourceGroup + "/" + containerGroup
+		return http.StatusOK, nil
+	}
+	assert.NilError(t, provider.DeletePod(context.Background(), p))
+	assert.Check(t, is.Equal(deleted, "rg-west/ns-web"))
+}
+
+func TestCreatePodStopsOnOtherErrors(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	provider.targets = sortTargets([]target{
+		{ResourceGroup: "rg-east", Region: "eastus", Priority: 2},
+		{ResourceGroup: "rg-west", Region: "westus", Priority: 1},
+	})
+
+	var attempts int
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		attempts++
+		return http.StatusBadRequest, map[string]interface{}{
+			"error": map[string]string{"code": "InvalidImage", "message": "invalid image"},
+		}
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "nginx"}}},
+	}
+	assert.Check(t, provider.CreatePod(context.Background(), pod) != nil)
+	assert.Check(t, is.Equal(attempts, 1))
+}
+
+func TestGetContainerGroupLooksUpTargets(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	assert.NilError(t, err)
+
+	provider.targets = sortTargets([]target{
+		{ResourceGroup: "rg-east", Region: "eastus", Priority: 2},
+		{ResourceGroup: "rg-west", Region: "westus", Priority: 1},
+	})
+
+	var lookups []string
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		lookups = append(lookups, resourceGroup)
+		if resourceGroup == "rg-west" && containerGroup == "ns-web" {
+			return http.StatusOK, aci.ContainerGroup{Name: containerGroup, Location: "westus"}
+		}
+		return http.StatusNotFound, nil
+	}
+
+	_, tgt, err := provider.getContainerGroup(context.Background(), "ns-web")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(tgt.String(), "rg-west/westus"))
+	assert.Check(t, is.DeepEqual(lookups, []string{"rg-east", "rg-west"}))
+
+	// The target is remembered.
+	lookups = nil
+	_, _, err = provider.getContainerGroup(context.Background(), "ns-web")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(lookups, []string{"rg-west"}))
+
+	_, _, err = provider.getContainerGroup(context.Background(), "ns-missing")
+	assert.Check(t, strongerrors.IsNotFound(err))
+}
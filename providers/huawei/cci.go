@@ -9,9 +9,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cpuguy83/strongerrors"
@@ -21,7 +22,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/remotecommand"
 )
 
 const (
@@ -49,6 +49,7 @@ type CCIProvider struct {
 	operatingSystem    string
 	client             *Client
 	resourceManager    *manager.ResourceManager
+	resources          resourceSyncer
 	cpu                string
 	memory             string
 	pods               string
@@ -126,32 +127,97 @@ func (p *CCIProvider) createProject() error {
 			Name: p.project,
 		},
 	}
-	var bodyReader io.Reader
-	body, err := json.Marshal(project)
+	resp, err := p.doRequest(context.Background(), "POST", uri, project)
 	if err != nil {
+		// The project is kept when the provider stops.
+		if strongerrors.IsConflict(err) {
+			return nil
+		}
 		return err
 	}
-	if body != nil {
+	return resp.Body.Close()
+}
+
+func (p *CCIProvider) signRequest(r *http.Request) error {
+	r.Header.Add("content-type", "application/json; charset=utf-8")
+	if err := p.client.Signer.Sign(r); err != nil {
+		return fmt.Errorf("Sign the request failed: %v", err)
+	}
+	return nil
+}
+
+// doRequest signs and sends a request to CCI with obj, if not nil, as its JSON
+// body. CCI error responses are returned as errors by errorFromResponse,
+// otherwise the caller must close the body of the response.
+func (p *CCIProvider) doRequest(ctx context.Context, method, uri string, obj interface{}) (*http.Response, error) {
+	var bodyReader io.Reader
+	if obj != nil {
+		body, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
 		bodyReader = bytes.NewReader(body)
 	}
 
-	r, err := http.NewRequest("POST", uri, bodyReader)
+	r, err := http.NewRequest(method, uri, bodyReader)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("Create %s request failed: %v", method, err)
 	}
+	r = r.WithContext(ctx)
+
 	if err = p.signRequest(r); err != nil {
-		return fmt.Errorf("Sign the request failed: %v", err)
+		return nil, err
+	}
+	resp, err := p.client.HTTPClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := errorFromResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
 	}
-	_, err = p.client.HTTPClient.Do(r)
-	return err
+	return resp, nil
 }
 
-func (p *CCIProvider) signRequest(r *http.Request) error {
-	r.Header.Add("content-type", "application/json; charset=utf-8")
-	if err := p.client.Signer.Sign(r); err != nil {
-		return fmt.Errorf("Sign the request failed: %v", err)
+// decodeResponse decodes the JSON body of a response into obj and closes it.
+func decodeResponse(resp *http.Response, obj interface{}) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(obj)
+}
+
+// cciName returns the name in the CCI project, which holds the objects of all
+// namespaces, of a pod, secret or config map of a namespace. The namespace is
+// prefixed by its length, so that the names of objects of different
+// namespaces never collide.
+func cciName(namespace, name string) string {
+	return strconv.Itoa(len(namespace)) + "-" + namespace + "-" + name
+}
+
+// legacyCCIName returns the name of the pods created in the CCI project before
+// their names were prefixed by the length of their namespace.
+func legacyCCIName(namespace, name string) string {
+	return namespace + "-" + name
+}
+
+// originalName returns the name of an object of a namespace from its name in
+// the CCI project.
+func originalName(namespace, name string) string {
+	if prefix := cciName(namespace, ""); strings.HasPrefix(name, prefix) {
+		return strings.TrimPrefix(name, prefix)
 	}
-	return nil
+	return strings.TrimPrefix(name, legacyCCIName(namespace, ""))
+}
+
+// doPodRequest sends a request to a pod of the CCI project, or to the path of
+// one of its subresources. Pods created with their legacy name are found too.
+func (p *CCIProvider) doPodRequest(ctx context.Context, method, namespace, name, subresource string, obj interface{}) (*http.Response, error) {
+	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods/"
+	resp, err := p.doRequest(ctx, method, uri+cciName(namespace, name)+subresource, obj)
+	if strongerrors.IsNotFound(err) {
+		resp, err = p.doRequest(ctx, method, uri+legacyCCIName(namespace, name)+subresource, obj)
+	}
+	return resp, err
 }
 
 func (p *CCIProvider) setPodAnnotations(pod *v1.Pod) {
@@ -161,8 +227,9 @@ func (p *CCIProvider) setPodAnnotations(pod *v1.Pod) {
 	metav1.SetMetaDataAnnotation(&pod.ObjectMeta, podAnnotationUIDkey, string(pod.UID))
 	metav1.SetMetaDataAnnotation(&pod.ObjectMeta, podAnnotationNodeName, pod.Spec.NodeName)
 	metav1.SetMetaDataAnnotation(&pod.ObjectMeta, podAnnotationCreationTimestamp, pod.CreationTimestamp.String())
+	renamePodResources(pod, func(name string) string { return cciName(pod.Namespace, name) })
+	pod.Name = cciName(pod.Namespace, pod.Name)
 	pod.Namespace = p.project
-	pod.Name = pod.Namespace + "-" + pod.Name
 	pod.UID = ""
 	pod.Spec.NodeName = ""
 	pod.CreationTimestamp = metav1.Time{}
@@ -174,6 +241,7 @@ func (p *CCIProvider) deletePodAnnotations(pod *v1.Pod) error {
 	pod.UID = types.UID(pod.Annotations[podAnnotationUIDkey])
 	pod.ClusterName = pod.Annotations[podAnnotationClusterNameKey]
 	pod.Spec.NodeName = pod.Annotations[podAnnotationNodeName]
+	renamePodResources(pod, func(name string) string { return originalName(pod.Namespace, name) })
 	if pod.Annotations[podAnnotationCreationTimestamp] != "" {
 		t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", pod.Annotations[podAnnotationCreationTimestamp])
 		if err != nil {
@@ -194,29 +262,18 @@ func (p *CCIProvider) deletePodAnnotations(pod *v1.Pod) error {
 
 // CreatePod takes a Kubernetes Pod and deploys it within the huawei CCI provider.
 func (p *CCIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
-	// Create the createPod request url
-	p.setPodAnnotations(pod)
-	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods"
-	// build the request
-	var bodyReader io.Reader
-	body, err := json.Marshal(pod)
-	if err != nil {
+	if err := p.syncPodResources(ctx, pod); err != nil {
 		return err
 	}
-	if body != nil {
-		bodyReader = bytes.NewReader(body)
-	}
 
-	r, err := http.NewRequest("POST", uri, bodyReader)
+	// Create the createPod request url
+	p.setPodAnnotations(pod)
+	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods"
+	resp, err := p.doRequest(ctx, "POST", uri, pod)
 	if err != nil {
 		return err
 	}
-
-	if err = p.signRequest(r); err != nil {
-		return fmt.Errorf("Sign the request failed: %v", err)
-	}
-	_, err = p.client.HTTPClient.Do(r)
-	return err
+	return resp.Body.Close()
 }
 
 // UpdatePod takes a Kubernetes Pod and updates it within the huawei CCI provider.
@@ -226,24 +283,14 @@ func (p *CCIProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
 
 // DeletePod takes a Kubernetes Pod and deletes it from the huawei CCI provider.
 func (p *CCIProvider) DeletePod(ctx context.Context, pod *v1.Pod) error {
-	// Create the deletePod request url
-	podName := pod.Namespace + "-" + pod.Name
-	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods/" + podName
-	// build the request
-	r, err := http.NewRequest("DELETE", uri, nil)
-	if err != nil {
-		return err
-	}
-
-	if err = p.signRequest(r); err != nil {
-		return fmt.Errorf("Sign the request failed: %v", err)
-	}
-	resp, err := p.client.HTTPClient.Do(r)
+	resp, err := p.doPodRequest(ctx, "DELETE", pod.Namespace, pod.Name, "", nil)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
-	return errorFromResponse(resp)
+	p.deletePodResources(ctx, pod)
+	return nil
 }
 
 func errorFromResponse(resp *http.Response) error {
@@ -255,8 +302,18 @@ func errorFromResponse(resp *http.Response) error {
 	err := fmt.Errorf("error during http request, status=%d: %q", resp.StatusCode, string(body))
 
 	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return strongerrors.InvalidArgument(err)
+	case http.StatusUnauthorized:
+		return strongerrors.Unauthenticated(err)
+	case http.StatusForbidden:
+		return strongerrors.Unauthorized(err)
 	case http.StatusNotFound:
 		return strongerrors.NotFound(err)
+	case http.StatusConflict:
+		return strongerrors.Conflict(err)
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return strongerrors.Unavailable(err)
 	default:
 		return err
 	}
@@ -264,31 +321,13 @@ func errorFromResponse(resp *http.Response) error {
 
 // GetPod retrieves a pod by name from the huawei CCI provider.
 func (p *CCIProvider) GetPod(ctx context.Context, namespace, name string) (*v1.Pod, error) {
-	// Create the getPod request url
-	podName := namespace + "-" + name
-	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods/" + podName
-	r, err := http.NewRequest("GET", uri, nil)
-	if err != nil {
-		return nil, fmt.Errorf("Create get POD request failed: %v", err)
-	}
-
-	if err = p.signRequest(r); err != nil {
-		return nil, fmt.Errorf("Sign the request failed: %v", err)
-	}
-
-	resp, err := p.client.HTTPClient.Do(r)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, err := p.doPodRequest(ctx, "GET", namespace, name, "", nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var pod v1.Pod
-	if err = json.Unmarshal(body, &pod); err != nil {
+	if err = decodeResponse(resp, &pod); err != nil {
 		return nil, err
 	}
 	if err := p.deletePodAnnotations(&pod); err != nil {
@@ -297,25 +336,12 @@ func (p *CCIProvider) GetPod(ctx context.Context, namespace, name string) (*v1.P
 	return &pod, nil
 }
 
-// GetContainerLogs retrieves the logs of a container by name from the huawei CCI provider.
-func (p *CCIProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
-	return "", nil
-}
-
 // Get full pod name as defined in the provider context
 // TODO: Implementation
 func (p *CCIProvider) GetPodFullName(namespace string, pod string) string {
 	return ""
 }
 
-// ExecInContainer executes a command in a container in the pod, copying data
-// between in/out/err and the container's stdin/stdout/stderr.
-// TODO: Implementation
-func (p *CCIProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
-	log.Printf("receive ExecInContainer %q\n", container)
-	return nil
-}
-
 // GetPodStatus retrieves the status of a pod by name from the huawei CCI provider.
 func (p *CCIProvider) GetPodStatus(ctx context.Context, namespace, name string) (*v1.PodStatus, error) {
 	pod, err := p.GetPod(ctx, namespace, name)
@@ -333,36 +359,36 @@ func (p *CCIProvider) GetPodStatus(ctx context.Context, namespace, name string)
 // GetPods retrieves a list of all pods running on the huawei CCI provider.
 func (p *CCIProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	// Create the getPod request url
-	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods"
-	r, err := http.NewRequest("GET", uri, nil)
+	pods, err := p.listPods(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("Create get POD request failed: %v", err)
+		return nil, err
 	}
 
-	if err = p.signRequest(r); err != nil {
-		return nil, fmt.Errorf("Sign the request failed: %v", err)
-	}
-	resp, err := p.client.HTTPClient.Do(r)
-	if err != nil {
-		return nil, err
+	result := make([]*v1.Pod, 0, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if err := p.deletePodAnnotations(pod); err != nil {
+			return nil, err
+		}
+		result = append(result, pod)
 	}
+	return result, nil
+}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+// listPods lists the pods of the CCI project, as they are in CCI.
+func (p *CCIProvider) listPods(ctx context.Context) (*v1.PodList, error) {
+	// Create the getPod request url
+	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods"
+	resp, err := p.doRequest(ctx, "GET", uri, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var pods []*v1.Pod
-	if err = json.Unmarshal(body, &pods); err != nil {
+	var pods v1.PodList
+	if err = decodeResponse(resp, &pods); err != nil {
 		return nil, err
 	}
-	for _, pod := range pods {
-		if err := p.deletePodAnnotations(pod); err != nil {
-			return nil, err
-		}
-	}
-	return pods, nil
+	return &pods, nil
 }
 
 // Capacity returns a resource list with the capacity constraints of the huawei CCI provider.
@@ -3,11 +3,20 @@ package huawei
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"time"
 
 	"github.com/gorilla/mux"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/remotecommand"
+	kubeletremotecommand "k8s.io/kubernetes/pkg/kubelet/server/remotecommand"
 )
 
 // CCIMock implements a CCI service mock server.
@@ -17,6 +26,15 @@ type CCIMock struct {
 	OnCreatePod     func(*v1.Pod) (int, interface{})
 	OnGetPods       func() (int, interface{})
 	OnGetPod        func(string, string) (int, interface{})
+	OnDeletePod     func(string, string) (int, interface{})
+	// OnWatchPods returns the events of a pods watch from a resource
+	// version, the watch is closed after they are sent.
+	OnWatchPods func(string) []metav1.WatchEvent
+	OnGetLogs   func(string, string, url.Values) (int, string)
+	OnExec      func(name, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool) error
+	// OnResource handles the requests to the secrets and config maps of
+	// the project, name is empty when creating or listing them.
+	OnResource func(method, kind, name string, body []byte) (int, interface{})
 }
 
 // fakeSigner signature HWS meta
@@ -36,6 +54,11 @@ const (
 	cciProjectRoute = "/api/v1/namespaces"
 	cciPodsRoute    = cciProjectRoute + "/{namespaceID}/pods"
 	cciPodRoute     = cciPodsRoute + "/{podID}"
+	cciPodLogRoute  = cciPodRoute + "/log"
+	cciPodExecRoute = cciPodRoute + "/exec"
+
+	cciResourcesRoute = cciProjectRoute + "/{namespaceID}/{kind:secrets|configmaps}"
+	cciResourceRoute  = cciResourcesRoute + "/{name}"
 )
 
 // NewCCIMock creates a CCI service mock server.
@@ -61,7 +84,7 @@ func (mock *CCIMock) start() {
 				panic(err)
 			}
 
-			if mock.OnCreatePod != nil {
+			if mock.OnCreateProject != nil {
 				statusCode, response := mock.OnCreateProject(&ns)
 				w.WriteHeader(statusCode)
 				b := new(bytes.Buffer)
@@ -72,7 +95,7 @@ func (mock *CCIMock) start() {
 			}
 
 			w.WriteHeader(http.StatusNotImplemented)
-		}).Methods("PUT")
+		}).Methods("POST")
 
 	router.HandleFunc(
 		cciPodsRoute,
@@ -93,7 +116,7 @@ func (mock *CCIMock) start() {
 			}
 
 			w.WriteHeader(http.StatusNotImplemented)
-		}).Methods("PUT")
+		}).Methods("POST")
 
 	router.HandleFunc(
 		cciPodRoute,
@@ -117,6 +140,18 @@ func (mock *CCIMock) start() {
 	router.HandleFunc(
 		cciPodsRoute,
 		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("watch") == "true" {
+				if mock.OnWatchPods == nil {
+					w.WriteHeader(http.StatusNotImplemented)
+					return
+				}
+				enc := json.NewEncoder(w)
+				for _, event := range mock.OnWatchPods(r.URL.Query().Get("resourceVersion")) {
+					enc.Encode(event)
+				}
+				return
+			}
+
 			if mock.OnGetPods != nil {
 				statusCode, response := mock.OnGetPods()
 				w.WriteHeader(statusCode)
@@ -130,9 +165,99 @@ func (mock *CCIMock) start() {
 			w.WriteHeader(http.StatusNotImplemented)
 		}).Methods("GET")
 
+	router.HandleFunc(
+		cciPodRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			namespace, _ := mux.Vars(r)["namespaceID"]
+			podname, _ := mux.Vars(r)["podID"]
+
+			if mock.OnDeletePod != nil {
+				statusCode, response := mock.OnDeletePod(namespace, podname)
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				json.NewEncoder(b).Encode(response)
+				w.Write(b.Bytes())
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("DELETE")
+
+	router.HandleFunc(
+		cciPodLogRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			namespace, _ := mux.Vars(r)["namespaceID"]
+			podname, _ := mux.Vars(r)["podID"]
+
+			if mock.OnGetLogs != nil {
+				statusCode, logs := mock.OnGetLogs(namespace, podname, r.URL.Query())
+				w.WriteHeader(statusCode)
+				io.WriteString(w, logs)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("GET")
+
+	router.HandleFunc(
+		cciPodExecRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			podname, _ := mux.Vars(r)["podID"]
+
+			if mock.OnExec != nil {
+				q := r.URL.Query()
+				opts := &kubeletremotecommand.Options{
+					Stdin:  q.Get("stdin") == "true",
+					Stdout: q.Get("stdout") == "true",
+					Stderr: q.Get("stderr") == "true",
+					TTY:    q.Get("tty") == "true",
+				}
+				kubeletremotecommand.ServeExec(w, r, execMock{mock}, podname, "", q.Get("container"), q["command"], opts,
+					30*time.Second, 30*time.Second, r.Header[httpstream.HeaderProtocolVersion])
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("POST")
+
+	resourceHandler := func(w http.ResponseWriter, r *http.Request) {
+		kind, _ := mux.Vars(r)["kind"]
+		name, _ := mux.Vars(r)["name"]
+
+		if mock.OnResource != nil {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				panic(err)
+			}
+			statusCode, response := mock.OnResource(r.Method, kind, name, body)
+			w.WriteHeader(statusCode)
+			b := new(bytes.Buffer)
+			json.NewEncoder(b).Encode(response)
+			w.Write(b.Bytes())
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+	router.HandleFunc(cciResourcesRoute, resourceHandler).Methods("POST", "GET")
+	router.HandleFunc(cciResourceRoute, resourceHandler).Methods("PUT", "DELETE")
+
 	mock.server = httptest.NewServer(router)
 }
 
+// execMock runs the commands of exec requests with OnExec.
+type execMock struct {
+	mock *CCIMock
+}
+
+func (e execMock) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
+	return e.mock.OnExec(name, container, cmd, in, out, errstream, tty)
+}
+
 // GetServerURL returns the mock server URL.
 func (mock *CCIMock) GetServerURL() string {
 	if mock.server != nil {
@@ -3,9 +3,14 @@ package huawei
 import (
 	"context"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cpuguy83/strongerrors"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
 	v1 "k8s.io/api/core/v1"
@@ -172,7 +177,7 @@ func TestGetPods(t *testing.T) {
 				},
 			},
 		}
-		return http.StatusOK, []v1.Pod{pod}
+		return http.StatusOK, &v1.PodList{Items: []v1.Pod{pod}}
 	}
 	pods, err := provider.GetPods(context.Background())
 	if err != nil {
@@ -190,8 +195,21 @@ func TestGetPods(t *testing.T) {
 	assert.Check(t, is.Equal(pod.Spec.NodeName, "podnodename"), "Pod node name is not expected")
 }
 
+func TestCCIName(t *testing.T) {
+	// Joining namespaces and names with a dash is ambiguous, the length of
+	// the namespace isn't.
+	assert.Check(t, cciName("a-b", "c") != cciName("a", "b-c"))
+	assert.Check(t, is.Equal(cciName("ns", "pod"), "2-ns-pod"))
+
+	assert.Check(t, is.Equal(originalName("a", cciName("a", "b-c")), "b-c"))
+	assert.Check(t, is.Equal(originalName("ns", "ns-pod"), "pod"))
+}
+
 func prepareMocks() (*CCIMock, *CCIProvider, error) {
 	cciServerMocker := NewCCIMock()
+	cciServerMocker.OnCreateProject = func(ns *v1.Namespace) (int, interface{}) {
+		return http.StatusCreated, ns
+	}
 
 	os.Setenv("CCI_APP_KEP", fakeAppKey)
 	os.Setenv("CCI_APP_SECRET", fakeAppSecret)
@@ -212,3 +230,71 @@ func prepareMocks() (*CCIMock, *CCIProvider, error) {
 
 	return cciServerMocker, provider, nil
 }
+
+// TestCreatePodError tests that CCI errors are returned.
+func TestCreatePodError(t *testing.T) {
+	cciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	cciServerMocker.OnCreatePod = func(pod *v1.Pod) (int, interface{}) {
+		return http.StatusBadRequest, &metav1.Status{Message: "invalid pod"}
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	err = provider.CreatePod(context.Background(), pod)
+	assert.Check(t, strongerrors.IsInvalidArgument(err), "an invalid argument error is expected, got %v", err)
+
+	cciServerMocker.OnGetPod = func(namespace, name string) (int, interface{}) {
+		return http.StatusNotFound, &metav1.Status{Message: "not found"}
+	}
+	_, err = provider.GetPod(context.Background(), "ns", "pod")
+	assert.Check(t, strongerrors.IsNotFound(err), "a not found error is expected, got %v", err)
+
+	// An existing project is reused.
+	cciServerMocker.OnCreateProject = func(ns *v1.Namespace) (int, interface{}) {
+		return http.StatusConflict, &metav1.Status{Message: "already exists"}
+	}
+	assert.NilError(t, provider.createProject())
+}
+
+// TestStreamContainerLogs tests reading logs from the log subresource.
+func TestStreamContainerLogs(t *testing.T) {
+	cciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	cciServerMocker.OnGetLogs = func(namespace, name string, q url.Values) (int, string) {
+		assert.Check(t, is.Equal(namespace, fakeProject))
+		assert.Check(t, is.Equal(name, "2-ns-pod"))
+		assert.Check(t, is.Equal(q.Get("container"), "nginx"))
+		assert.Check(t, is.Equal(q.Get("tailLines"), "2"))
+		assert.Check(t, is.Equal(q.Get("follow"), "true"))
+		assert.Check(t, is.Equal(q.Get("sinceTime"), "2019-01-01T00:00:00Z"))
+		return http.StatusOK, "two\nthree\n"
+	}
+
+	var logs strings.Builder
+	opts := providers.ContainerLogOpts{Tail: 2, Follow: true, SinceTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	assert.NilError(t, provider.StreamContainerLogs(context.Background(), "ns", "pod", "nginx", opts, &logs))
+	assert.Check(t, is.Equal(logs.String(), "two\nthree\n"))
+
+	// Pods created before their names were prefixed by the length of their
+	// namespace are found by their legacy name.
+	cciServerMocker.OnGetLogs = func(namespace, name string, q url.Values) (int, string) {
+		if name != "ns-pod" {
+			return http.StatusNotFound, "not found"
+		}
+		return http.StatusOK, "legacy\n"
+	}
+	legacyLogs, err := provider.GetContainerLogs(context.Background(), "ns", "pod", "nginx", 10)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(legacyLogs, "legacy\n"))
+
+	cciServerMocker.OnGetLogs = func(namespace, name string, q url.Values) (int, string) {
+		return http.StatusNotFound, "not found"
+	}
+	_, err = provider.GetContainerLogs(context.Background(), "ns", "pod", "nginx", 10)
+	assert.Check(t, strongerrors.IsNotFound(err), "a not found error is expected, got %v", err)
+}
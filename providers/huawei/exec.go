@@ -0,0 +1,138 @@
+package huawei
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ExecInContainer executes a command in a container in the pod, copying data
+// between in/out/err and the container's stdin/stdout/stderr.
+//
+// name is the pod's namespace and name joined by a dash, the pod is looked up
+// by its annotations in the CCI project. The command is run through the exec
+// subresource of the pod, whose SPDY connection is opened with a signed
+// request.
+// A non-zero exit code of the command is returned as an exec.ExitError.
+func (p *CCIProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
+	// Cleanup on exit
+	if out != nil {
+		defer out.Close()
+	}
+	if errstream != nil {
+		defer errstream.Close()
+	}
+
+	if len(cmd) == 0 {
+		return strongerrors.InvalidArgument(errors.New("no command specified"))
+	}
+
+	podName, err := p.execPodName(context.TODO(), name)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods/" + podName + "/exec")
+	if err != nil {
+		return err
+	}
+	q := url.Values{}
+	q.Set("container", container)
+	q["command"] = cmd
+	q.Set("stdin", strconv.FormatBool(in != nil))
+	q.Set("stdout", strconv.FormatBool(out != nil))
+	q.Set("stderr", strconv.FormatBool(errstream != nil && !tty))
+	q.Set("tty", strconv.FormatBool(tty))
+	u.RawQuery = q.Encode()
+
+	upgrader := spdy.NewRoundTripper(&tls.Config{InsecureSkipVerify: true}, true, false)
+	transport := &signingRoundTripper{p: p, rt: upgrader}
+	exec, err := remotecommand.NewSPDYExecutorForTransports(transport, upgrader, "POST", u)
+	if err != nil {
+		return errors.Wrapf(err, "error creating exec session for container %s", container)
+	}
+
+	opts := remotecommand.StreamOptions{Tty: tty}
+	// Unset streams are left as nil interfaces, which the executor ignores.
+	if in != nil {
+		opts.Stdin = in
+	}
+	if out != nil {
+		opts.Stdout = out
+	}
+	if errstream != nil && !tty {
+		opts.Stderr = errstream
+	}
+	if resize != nil {
+		opts.TerminalSizeQueue = resizeQueue(resize)
+	}
+	return exec.Stream(opts)
+}
+
+// execPodName returns the name in the CCI project of the pod whose namespace
+// and name joined by a dash are name. Joined names are ambiguous, when several
+// pods match none is picked.
+func (p *CCIProvider) execPodName(ctx context.Context, name string) (string, error) {
+	pods, err := p.listPods(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for _, pod := range pods.Items {
+		if pod.Annotations[podAnnotationNamespaceKey]+"-"+pod.Annotations[podAnnotationPodNameKey] == name {
+			found = append(found, pod.Name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", strongerrors.NotFound(errors.Errorf("pod %s not found", name))
+	case 1:
+		return found[0], nil
+	default:
+		return "", strongerrors.InvalidArgument(errors.Errorf("pod name %s is ambiguous, it matches the pods %v", name, found))
+	}
+}
+
+// signingRoundTripper signs the requests sent by rt for CCI.
+type signingRoundTripper struct {
+	p  *CCIProvider
+	rt http.RoundTripper
+}
+
+func (s *signingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round trippers must not modify the request.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+
+	if err := s.p.signRequest(r); err != nil {
+		return nil, err
+	}
+	return s.rt.RoundTrip(r)
+}
+
+// resizeQueue adapts a channel of terminal sizes to a
+// remotecommand.TerminalSizeQueue.
+type resizeQueue <-chan remotecommand.TerminalSize
+
+func (q resizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q
+	if !ok {
+		return nil
+	}
+	return &size
+}
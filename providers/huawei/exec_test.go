@@ -0,0 +1,79 @@
+package huawei
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cpuguy83/strongerrors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilexec "k8s.io/utils/exec"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestExecInContainer(t *testing.T) {
+	cciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	cciServerMocker.OnGetPods = func() (int, interface{}) {
+		return http.StatusOK, &v1.PodList{Items: []v1.Pod{
+			newWatchedPod("pod", fakeNodeName, "1"),
+			{ObjectMeta: metav1.ObjectMeta{
+				Name: cciName("ns", "pod"),
+				Annotations: map[string]string{
+					podAnnotationNamespaceKey: "ns",
+					podAnnotationPodNameKey:   "pod",
+				},
+			}},
+		}}
+	}
+	cciServerMocker.OnExec = func(name, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool) error {
+		assert.Check(t, is.Equal(name, "2-ns-pod"))
+		assert.Check(t, is.Equal(container, "nginx"))
+		assert.Check(t, is.DeepEqual(cmd, []string{"sh", "-c", "cat; exit 3"}))
+		assert.Check(t, !tty)
+
+		b, err := ioutil.ReadAll(in)
+		assert.Check(t, err)
+		io.WriteString(out, "out: "+string(b))
+		io.WriteString(errstream, "err")
+		return utilexec.CodeExitError{Err: io.EOF, Code: 3}
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = provider.ExecInContainer("ns-pod", "", "nginx", []string{"sh", "-c", "cat; exit 3"},
+		strings.NewReader("in"), nopWriteCloser{&stdout}, nopWriteCloser{&stderr}, false, nil, 0)
+
+	// The exit code of the command is returned.
+	exitErr, ok := err.(utilexec.ExitError)
+	assert.Assert(t, ok, "an exit error is expected, got %v", err)
+	assert.Check(t, is.Equal(exitErr.ExitStatus(), 3))
+	assert.Check(t, is.Equal(stdout.String(), "out: in"))
+	assert.Check(t, is.Equal(stderr.String(), "err"))
+
+	// Pods whose joined namespace and name are the same can't be told apart.
+	cciServerMocker.OnGetPods = func() (int, interface{}) {
+		pod := func(namespace, name string) v1.Pod {
+			return v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        cciName(namespace, name),
+				Annotations: map[string]string{podAnnotationNamespaceKey: namespace, podAnnotationPodNameKey: name},
+			}}
+		}
+		return http.StatusOK, &v1.PodList{Items: []v1.Pod{pod("a-b", "c"), pod("a", "b-c")}}
+	}
+	err = provider.ExecInContainer("a-b-c", "", "nginx", []string{"true"}, nil, nil, nil, false, nil, 0)
+	assert.Check(t, strongerrors.IsInvalidArgument(err), "an invalid argument error is expected, got %v", err)
+}
@@ -0,0 +1,53 @@
+package huawei
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+)
+
+// GetContainerLogs retrieves the logs of a container by name from the huawei CCI provider.
+func (p *CCIProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
+	var logs strings.Builder
+	if err := p.StreamContainerLogs(ctx, namespace, podName, containerName, providers.ContainerLogOpts{Tail: tail}, &logs); err != nil {
+		return "", err
+	}
+	return logs.String(), nil
+}
+
+// StreamContainerLogs copies the logs of a container by name from the huawei
+// CCI provider to w, as they are read from the log subresource of the pod.
+func (p *CCIProvider) StreamContainerLogs(ctx context.Context, namespace, podName, containerName string, opts providers.ContainerLogOpts, w io.Writer) error {
+	q := url.Values{}
+	q.Set("container", containerName)
+	if opts.Tail > 0 {
+		q.Set("tailLines", strconv.Itoa(opts.Tail))
+	}
+	if opts.Follow {
+		q.Set("follow", "true")
+	}
+	if opts.Timestamps {
+		q.Set("timestamps", "true")
+	}
+	if !opts.SinceTime.IsZero() {
+		q.Set("sinceTime", opts.SinceTime.UTC().Format(time.RFC3339))
+	}
+	resp, err := p.doPodRequest(ctx, "GET", namespace, podName, "/log?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package huawei
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// watchRetryInterval is the delay before the pods of the CCI project are
+// watched again after the watch failed. Watches closed by CCI are reopened
+// right away.
+var watchRetryInterval = 5 * time.Second
+
+// errWatchExpired is returned when the resource version a watch was opened at
+// is too old, and the pods have to be listed again.
+var errWatchExpired = errors.New("watch resource version expired")
+
+// NotifyPods instructs the provider to call the passed in function when the
+// status of a pod changes.
+//
+// The pods of the CCI project are listed, then watched from the resource
+// version of the list. When the watch is closed it is reopened right away from
+// the last resource version seen, and the pods are listed again if CCI no
+// longer has it. Errors are retried after watchRetryInterval.
+func (p *CCIProvider) NotifyPods(ctx context.Context, notifier func(*v1.Pod)) {
+	go p.watchPods(ctx, notifier, watchRetryInterval)
+}
+
+func (p *CCIProvider) watchPods(ctx context.Context, notifier func(*v1.Pod), retryInterval time.Duration) {
+	var resourceVersion string
+	for {
+		var err error
+		if resourceVersion == "" {
+			resourceVersion, err = p.syncPods(ctx, notifier)
+		}
+		if err == nil {
+			resourceVersion, err = p.watchPodsFrom(ctx, resourceVersion, notifier)
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err == nil:
+			continue
+		case err == errWatchExpired:
+			resourceVersion = ""
+			continue
+		}
+
+		log.G(ctx).WithError(err).Warn("Error watching CCI pods")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// syncPods notifies all the pods of the node and returns the resource version
+// to watch them from.
+func (p *CCIProvider) syncPods(ctx context.Context, notifier func(*v1.Pod)) (string, error) {
+	pods, err := p.listPods(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "error listing pods")
+	}
+
+	for i := range pods.Items {
+		p.notifyPod(ctx, &pods.Items[i], notifier)
+	}
+	return pods.ResourceVersion, nil
+}
+
+// watchPodsFrom watches the pods of the CCI project from a resource version
+// and notifies the pods of the node until the watch is closed. It returns the
+// last resource version seen.
+func (p *CCIProvider) watchPodsFrom(ctx context.Context, resourceVersion string, notifier func(*v1.Pod)) (string, error) {
+	q := url.Values{}
+	q.Set("watch", "true")
+	q.Set("resourceVersion", resourceVersion)
+	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/pods?" + q.Encode()
+
+	resp, err := p.doRequest(ctx, "GET", uri, nil)
+	if err != nil {
+		return resourceVersion, errors.Wrap(err, "error watching pods")
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event metav1.WatchEvent
+		if err := dec.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return resourceVersion, ctx.Err()
+			}
+			if err == io.EOF {
+				log.G(ctx).Debug("CCI pod watch closed")
+				return resourceVersion, nil
+			}
+			return resourceVersion, errors.Wrap(err, "error reading pod watch")
+		}
+
+		switch watch.EventType(event.Type) {
+		case watch.Added, watch.Modified, watch.Deleted:
+			var pod v1.Pod
+			if err := json.Unmarshal(event.Object.Raw, &pod); err != nil {
+				return resourceVersion, errors.Wrap(err, "error decoding watched pod")
+			}
+			resourceVersion = pod.ResourceVersion
+			p.notifyPod(ctx, &pod, notifier)
+		case watch.Error:
+			var status metav1.Status
+			if err := json.Unmarshal(event.Object.Raw, &status); err != nil {
+				return resourceVersion, errors.Wrap(err, "error decoding watch error")
+			}
+			if status.Code == http.StatusGone {
+				return resourceVersion, errWatchExpired
+			}
+			return resourceVersion, errors.Errorf("watch error: %s", status.Message)
+		}
+	}
+}
+
+// notifyPod notifies a pod in CCI if it was created by this node.
+func (p *CCIProvider) notifyPod(ctx context.Context, pod *v1.Pod, notifier func(*v1.Pod)) {
+	if pod.Annotations[podAnnotationNodeName] != p.nodeName {
+		return
+	}
+	if err := p.deletePodAnnotations(pod); err != nil {
+		log.G(ctx).WithField("name", pod.Name).WithError(err).Error("error converting CCI pod")
+		return
+	}
+	notifier(pod)
+}
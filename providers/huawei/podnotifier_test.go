@@ -0,0 +1,128 @@
+package huawei
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newWatchedPod(name, nodeName, resourceVersion string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fakeProject + "-" + name,
+			Namespace:       fakeProject,
+			ResourceVersion: resourceVersion,
+			Annotations: map[string]string{
+				podAnnotationPodNameKey:   name,
+				podAnnotationNamespaceKey: "default",
+				podAnnotationNodeName:     nodeName,
+			},
+		},
+	}
+}
+
+func newWatchEvent(t *testing.T, eventType watch.EventType, obj interface{}) metav1.WatchEvent {
+	raw, err := json.Marshal(obj)
+	assert.NilError(t, err)
+	return metav1.WatchEvent{Type: string(eventType), Object: runtime.RawExtension{Raw: raw}}
+}
+
+func TestNotifyPods(t *testing.T) {
+	// Watches closed by CCI are reopened right away, only errors wait for
+	// the retry interval.
+	defer func(d time.Duration) { watchRetryInterval = d }(watchRetryInterval)
+	watchRetryInterval = time.Hour
+
+	cciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	lists := make(chan struct{}, 10)
+	cciServerMocker.OnGetPods = func() (int, interface{}) {
+		lists <- struct{}{}
+		list := &v1.PodList{Items: []v1.Pod{
+			newWatchedPod("listed", fakeNodeName, "1"),
+			newWatchedPod("other-node", "other", "1"),
+		}}
+		list.ResourceVersion = "1"
+		return http.StatusOK, list
+	}
+
+	watches := make(chan string, 10)
+	watched := make(map[string]int)
+	cciServerMocker.OnWatchPods = func(resourceVersion string) []metav1.WatchEvent {
+		watches <- resourceVersion
+		watched[resourceVersion]++
+		switch {
+		case resourceVersion == "1" && watched[resourceVersion] == 1:
+			// The watch is closed after two events.
+			return []metav1.WatchEvent{
+				newWatchEvent(t, watch.Added, newWatchedPod("added", fakeNodeName, "2")),
+				newWatchEvent(t, watch.Deleted, newWatchedPod("added", fakeNodeName, "3")),
+			}
+		case resourceVersion == "3":
+			// The resource version is too old, the pods are listed again.
+			return []metav1.WatchEvent{
+				newWatchEvent(t, watch.Error, &metav1.Status{Code: http.StatusGone, Message: "too old"}),
+			}
+		default:
+			return []metav1.WatchEvent{
+				newWatchEvent(t, watch.Error, &metav1.Status{Code: http.StatusInternalServerError, Message: "internal error"}),
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notified := make(chan *v1.Pod, 10)
+	provider.NotifyPods(ctx, func(pod *v1.Pod) { notified <- pod })
+
+	expectNotified := func(name string) {
+		t.Helper()
+		select {
+		case pod := <-notified:
+			assert.Check(t, is.Equal(pod.Namespace, "default"))
+			assert.Check(t, is.Equal(pod.Name, name))
+		case <-time.After(10 * time.Second):
+			t.Fatalf("pod %s was not notified", name)
+		}
+	}
+	expectWatched := func(resourceVersion string) {
+		t.Helper()
+		select {
+		case rv := <-watches:
+			assert.Check(t, is.Equal(rv, resourceVersion))
+		case <-time.After(10 * time.Second):
+			t.Fatalf("pods were not watched from %s", resourceVersion)
+		}
+	}
+
+	<-lists
+	expectNotified("listed")
+	expectWatched("1")
+	expectNotified("added")
+	expectNotified("added")
+	expectWatched("3")
+
+	<-lists
+	expectNotified("listed")
+	expectWatched("1")
+
+	// The watch failed, it is not reopened before the retry interval.
+	select {
+	case rv := <-watches:
+		t.Fatalf("pods were watched again from %s right after an error", rv)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,384 @@
+package huawei
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Paths of the secrets and config maps of the CCI project.
+	resourceKindSecret    = "secrets"
+	resourceKindConfigMap = "configmaps"
+
+	resourceAnnotationNameKey    = "virtual-kubelet-name"
+	resourceAnnotationVersionKey = "virtual-kubelet-resourceversion"
+)
+
+// resourceSyncInterval is the interval between two updates of the secrets and
+// config maps referenced by pods in the CCI project.
+var resourceSyncInterval = time.Minute
+
+// resourceSyncer tracks the secrets and config maps replicated in the CCI
+// project.
+type resourceSyncer struct {
+	once sync.Once
+
+	mu sync.Mutex
+	// versions holds the resource version of the replicated objects, keyed
+	// by kind, namespace and name.
+	versions map[string]string
+}
+
+func (s *resourceSyncer) version(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.versions[key]
+}
+
+func (s *resourceSyncer) setVersion(key, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == "" {
+		delete(s.versions, key)
+		return
+	}
+	if s.versions == nil {
+		s.versions = make(map[string]string)
+	}
+	s.versions[key] = version
+}
+
+// podResource is a reference of a pod to a secret or config map.
+type podResource struct {
+	kind     string
+	name     *string
+	optional bool
+}
+
+// podResources returns the references of a pod to secrets and config maps, in
+// its image pull secrets, volumes and container environments.
+func podResources(pod *v1.Pod) []podResource {
+	var refs []podResource
+	add := func(kind string, name *string, optional *bool) {
+		refs = append(refs, podResource{kind: kind, name: name, optional: optional != nil && *optional})
+	}
+
+	for i := range pod.Spec.ImagePullSecrets {
+		add(resourceKindSecret, &pod.Spec.ImagePullSecrets[i].Name, nil)
+	}
+
+	for i := range pod.Spec.Volumes {
+		v := &pod.Spec.Volumes[i].VolumeSource
+		if v.Secret != nil {
+			add(resourceKindSecret, &v.Secret.SecretName, v.Secret.Optional)
+		}
+		if v.ConfigMap != nil {
+			add(resourceKindConfigMap, &v.ConfigMap.Name, v.ConfigMap.Optional)
+		}
+		if v.Projected != nil {
+			for j := range v.Projected.Sources {
+				source := &v.Projected.Sources[j]
+				if source.Secret != nil {
+					add(resourceKindSecret, &source.Secret.Name, source.Secret.Optional)
+				}
+				if source.ConfigMap != nil {
+					add(resourceKindConfigMap, &source.ConfigMap.Name, source.ConfigMap.Optional)
+				}
+			}
+		}
+	}
+
+	containers := make([]*v1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	for i := range pod.Spec.InitContainers {
+		containers = append(containers, &pod.Spec.InitContainers[i])
+	}
+	for i := range pod.Spec.Containers {
+		containers = append(containers, &pod.Spec.Containers[i])
+	}
+	for _, c := range containers {
+		for i := range c.EnvFrom {
+			envFrom := &c.EnvFrom[i]
+			if envFrom.SecretRef != nil {
+				add(resourceKindSecret, &envFrom.SecretRef.Name, envFrom.SecretRef.Optional)
+			}
+			if envFrom.ConfigMapRef != nil {
+				add(resourceKindConfigMap, &envFrom.ConfigMapRef.Name, envFrom.ConfigMapRef.Optional)
+			}
+		}
+		for i := range c.Env {
+			valueFrom := c.Env[i].ValueFrom
+			if valueFrom == nil {
+				continue
+			}
+			if valueFrom.SecretKeyRef != nil {
+				add(resourceKindSecret, &valueFrom.SecretKeyRef.Name, valueFrom.SecretKeyRef.Optional)
+			}
+			if valueFrom.ConfigMapKeyRef != nil {
+				add(resourceKindConfigMap, &valueFrom.ConfigMapKeyRef.Name, valueFrom.ConfigMapKeyRef.Optional)
+			}
+		}
+	}
+
+	return refs
+}
+
+// renamePodResources renames the secrets and config maps referenced by a pod.
+func renamePodResources(pod *v1.Pod, rename func(string) string) {
+	for _, ref := range podResources(pod) {
+		*ref.name = rename(*ref.name)
+	}
+}
+
+// ResolvesEnvironmentReferences implements providers.PodEnvironmentResolver.
+// The secrets and config maps referenced by the environment of the containers
+// are replicated into the CCI project like the other referenced objects, and
+// CCI resolves the variables set from them.
+func (p *CCIProvider) ResolvesEnvironmentReferences() bool {
+	return true
+}
+
+// syncPodResources replicates the secrets and config maps referenced by a pod
+// into the CCI project.
+// Missing optional objects are skipped, other missing objects fail the pod
+// creation until they are created.
+func (p *CCIProvider) syncPodResources(ctx context.Context, pod *v1.Pod) error {
+	refs := podResources(pod)
+	if len(refs) == 0 {
+		return nil
+	}
+	if p.resourceManager == nil {
+		return errors.New("the resource manager is required to sync the secrets and config maps of pods")
+	}
+
+	for _, ref := range refs {
+		err := p.syncResource(ctx, ref.kind, pod.Namespace, *ref.name)
+		if err != nil && !(ref.optional && strongerrors.IsNotFound(err)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncResource replicates a secret or config map into the CCI project, unless
+// its current version was already replicated.
+func (p *CCIProvider) syncResource(ctx context.Context, kind, namespace, name string) error {
+	var (
+		obj     interface{}
+		version string
+	)
+	switch kind {
+	case resourceKindSecret:
+		secret, err := p.resourceManager.GetSecret(name, namespace)
+		if err != nil {
+			return wrapResourceError(err, "secret", namespace, name)
+		}
+		obj, version = p.cciSecret(secret), secret.ResourceVersion
+	case resourceKindConfigMap:
+		configMap, err := p.resourceManager.GetConfigMap(name, namespace)
+		if err != nil {
+			return wrapResourceError(err, "config map", namespace, name)
+		}
+		obj, version = p.cciConfigMap(configMap), configMap.ResourceVersion
+	}
+
+	key := resourceKey(kind, namespace, name)
+	if version != "" && p.resources.version(key) == version {
+		return nil
+	}
+
+	uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/" + kind
+	resp, err := p.doRequest(ctx, "POST", uri, obj)
+	if strongerrors.IsConflict(err) {
+		resp, err = p.doRequest(ctx, "PUT", uri+"/"+cciName(namespace, name), obj)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error syncing %s %s/%s", kind, namespace, name)
+	}
+	resp.Body.Close()
+
+	p.resources.setVersion(key, version)
+	return nil
+}
+
+func resourceKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+func wrapResourceError(err error, kind, namespace, name string) error {
+	wrapped := errors.Wrapf(err, "error getting %s %s/%s", kind, namespace, name)
+	if apierrors.IsNotFound(err) {
+		return strongerrors.NotFound(wrapped)
+	}
+	return wrapped
+}
+
+// deletePodResources deletes the secrets and config maps referenced by a
+// deleted pod from the CCI project, unless another pod of the node references
+// them.
+func (p *CCIProvider) deletePodResources(ctx context.Context, pod *v1.Pod) {
+	refs := podResources(pod)
+	if len(refs) == 0 || p.resourceManager == nil {
+		return
+	}
+
+	inUse := make(map[string]bool)
+	for _, other := range p.resourceManager.GetPods() {
+		if other.Namespace != pod.Namespace || other.Name == pod.Name {
+			continue
+		}
+		for _, ref := range podResources(other) {
+			inUse[ref.kind+"/"+*ref.name] = true
+		}
+	}
+
+	for _, ref := range refs {
+		if inUse[ref.kind+"/"+*ref.name] {
+			continue
+		}
+		inUse[ref.kind+"/"+*ref.name] = true
+
+		uri := p.apiEndpoint + "/api/v1/namespaces/" + p.project + "/" + ref.kind + "/" + cciName(pod.Namespace, *ref.name)
+		resp, err := p.doRequest(ctx, "DELETE", uri, nil)
+		if err != nil && !strongerrors.IsNotFound(err) {
+			log.G(ctx).WithField("name", *ref.name).WithError(err).Warnf("Error deleting %s from CCI", ref.kind)
+			continue
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		p.resources.setVersion(resourceKey(ref.kind, pod.Namespace, *ref.name), "")
+	}
+}
+
+// ReconcilePods implements providers.PodReconciler.
+// The versions of the objects replicated by a previous run of the provider are
+// read back from the CCI project, and the replicas start being kept updated.
+func (p *CCIProvider) ReconcilePods(ctx context.Context) error {
+	if p.resourceManager == nil {
+		return nil
+	}
+
+	var err error
+	for _, kind := range []string{resourceKindSecret, resourceKindConfigMap} {
+		if lerr := p.loadResourceVersions(ctx, kind); lerr != nil {
+			err = lerr
+		}
+	}
+
+	p.resources.once.Do(func() {
+		go p.runResourceSync(ctx)
+	})
+	return err
+}
+
+// loadResourceVersions records the versions of the objects of a kind
+// replicated in the CCI project for this node.
+func (p *CCIProvider) loadResourceVersions(ctx context.Context, kind string) error {
+	resp, err := p.doRequest(ctx, "GET", p.apiEndpoint+"/api/v1/namespaces/"+p.project+"/"+kind, nil)
+	if err != nil {
+		return errors.Wrapf(err, "error listing %s", kind)
+	}
+
+	var list struct {
+		Items []struct {
+			metav1.ObjectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := decodeResponse(resp, &list); err != nil {
+		return errors.Wrapf(err, "error decoding %s", kind)
+	}
+
+	for _, item := range list.Items {
+		a := item.Annotations
+		if a[podAnnotationNodeName] != p.nodeName || a[resourceAnnotationNameKey] == "" {
+			continue
+		}
+		version := a[resourceAnnotationVersionKey]
+		if version == "" {
+			// The replica is updated by the next sync.
+			version = "unknown"
+		}
+		p.resources.setVersion(resourceKey(kind, a[podAnnotationNamespaceKey], a[resourceAnnotationNameKey]), version)
+	}
+	return nil
+}
+
+// runResourceSync periodically updates the secrets and config maps replicated
+// in the CCI project for the pods of the node.
+func (p *CCIProvider) runResourceSync(ctx context.Context) {
+	t := time.NewTicker(resourceSyncInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		for _, pod := range p.resourceManager.GetPods() {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			for _, ref := range podResources(pod) {
+				// Only the objects replicated for running pods are
+				// updated, those of deleted pods are not recreated.
+				if p.resources.version(resourceKey(ref.kind, pod.Namespace, *ref.name)) == "" {
+					continue
+				}
+				err := p.syncResource(ctx, ref.kind, pod.Namespace, *ref.name)
+				if err != nil && !strongerrors.IsNotFound(err) {
+					log.G(ctx).WithField("name", *ref.name).WithError(err).Warnf("Error syncing %s to CCI", ref.kind)
+				}
+			}
+		}
+	}
+}
+
+// cciObjectMeta returns the metadata of the replica in the CCI project of an
+// object of a namespace.
+func (p *CCIProvider) cciObjectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      cciName(meta.Namespace, meta.Name),
+		Namespace: p.project,
+		Labels:    meta.Labels,
+		Annotations: map[string]string{
+			podAnnotationNamespaceKey:    meta.Namespace,
+			resourceAnnotationNameKey:    meta.Name,
+			resourceAnnotationVersionKey: meta.ResourceVersion,
+			podAnnotationNodeName:        p.nodeName,
+		},
+	}
+}
+
+func (p *CCIProvider) cciSecret(secret *v1.Secret) *v1.Secret {
+	s := &v1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: p.cciObjectMeta(secret.ObjectMeta),
+		Data:       secret.Data,
+		Type:       secret.Type,
+	}
+	// Service account tokens are only valid for accounts of the project,
+	// their copies are plain secrets.
+	if s.Type == v1.SecretTypeServiceAccountToken {
+		s.Type = v1.SecretTypeOpaque
+	}
+	return s
+}
+
+func (p *CCIProvider) cciConfigMap(configMap *v1.ConfigMap) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: p.cciObjectMeta(configMap.ObjectMeta),
+		Data:       configMap.Data,
+		BinaryData: configMap.BinaryData,
+	}
+}
@@ -0,0 +1,215 @@
+package huawei
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceMock is a store of the secrets and config maps of the CCI project.
+type resourceMock struct {
+	mu       sync.Mutex
+	objects  map[string]map[string]interface{}
+	requests []string
+}
+
+func (m *resourceMock) handle(method, kind, name string, body []byte) (int, interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var obj map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return http.StatusBadRequest, nil
+		}
+	}
+	if method == "GET" && name == "" {
+		var items []interface{}
+		for key, obj := range m.objects {
+			if strings.HasPrefix(key, kind+"/") {
+				items = append(items, obj)
+			}
+		}
+		return http.StatusOK, map[string]interface{}{"items": items}
+	}
+	if method == "POST" {
+		name = obj["metadata"].(map[string]interface{})["name"].(string)
+	}
+	key := kind + "/" + name
+	m.requests = append(m.requests, method+" "+key)
+
+	_, exists := m.objects[key]
+	switch {
+	case method == "POST" && exists:
+		return http.StatusConflict, nil
+	case method != "POST" && !exists:
+		return http.StatusNotFound, nil
+	case method == "DELETE":
+		delete(m.objects, key)
+		return http.StatusOK, nil
+	default:
+		m.objects[key] = obj
+		return http.StatusOK, obj
+	}
+}
+
+func (m *resourceMock) get(key string) map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.objects[key]
+}
+
+func (m *resourceMock) takeRequests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	requests := m.requests
+	m.requests = nil
+	return requests
+}
+
+func newResourcesTestPod(name string, secret string) *v1.Pod {
+	optional := true
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{Name: "db", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: secret}}},
+				{Name: "missing", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "missing", Optional: &optional}}},
+			},
+			Containers: []v1.Container{{
+				Name: "nginx",
+				EnvFrom: []v1.EnvFromSource{{
+					ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "cfg"}},
+				}},
+				Env: []v1.EnvVar{{
+					Name: "CONFIG",
+					ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "cfg"},
+						Key:                  "config",
+					}},
+				}},
+			}},
+		},
+	}
+}
+
+func newIndexer() cache.Indexer {
+	return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+func TestPodResources(t *testing.T) {
+	defer func(d time.Duration) { resourceSyncInterval = d }(resourceSyncInterval)
+	resourceSyncInterval = time.Millisecond
+
+	cciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	resources := &resourceMock{objects: make(map[string]map[string]interface{})}
+	cciServerMocker.OnResource = resources.handle
+
+	var created *v1.Pod
+	cciServerMocker.OnCreatePod = func(pod *v1.Pod) (int, interface{}) {
+		created = pod
+		return http.StatusCreated, pod
+	}
+	cciServerMocker.OnDeletePod = func(namespace, name string) (int, interface{}) {
+		return http.StatusOK, &metav1.Status{}
+	}
+
+	pods, secrets, configMaps := newIndexer(), newIndexer(), newIndexer()
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns", ResourceVersion: "1"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+		Type:       v1.SecretTypeServiceAccountToken,
+	}
+	assert.NilError(t, secrets.Add(secret))
+	assert.NilError(t, configMaps.Add(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "ns", ResourceVersion: "1"},
+		Data:       map[string]string{"config": "value"},
+	}))
+	// Another pod of the node uses the config map.
+	assert.NilError(t, pods.Add(newResourcesTestPod("other", "other")))
+	assert.NilError(t, pods.Add(newResourcesTestPod("pod", "db")))
+	provider.resourceManager, err = manager.NewResourceManager(corev1listers.NewPodLister(pods), corev1listers.NewSecretLister(secrets), corev1listers.NewConfigMapLister(configMaps), nil)
+	assert.NilError(t, err)
+
+	// The environment references are passed to CreatePod as is, and
+	// resolved by CCI.
+	var resolver providers.PodEnvironmentResolver = provider
+	assert.Check(t, resolver.ResolvesEnvironmentReferences())
+
+	// The referenced objects are replicated and the references renamed.
+	assert.NilError(t, provider.CreatePod(context.Background(), newResourcesTestPod("pod", "db")))
+	assert.Check(t, is.DeepEqual(resources.takeRequests(), []string{"POST secrets/2-ns-db", "POST configmaps/2-ns-cfg"}))
+	assert.Check(t, is.Equal(resources.get("secrets/2-ns-db")["type"], string(v1.SecretTypeOpaque)))
+	assert.Check(t, is.Equal(resources.get("configmaps/2-ns-cfg")["data"].(map[string]interface{})["config"], "value"))
+
+	assert.Assert(t, created != nil)
+	assert.Check(t, is.Equal(created.Spec.Volumes[0].Secret.SecretName, "2-ns-db"))
+	assert.Check(t, is.Equal(created.Spec.Volumes[1].Secret.SecretName, "2-ns-missing"))
+	assert.Check(t, is.Equal(created.Spec.Containers[0].Env[0].ValueFrom.ConfigMapKeyRef.Name, "2-ns-cfg"))
+	assert.Check(t, is.Equal(created.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Name, "2-ns-cfg"))
+
+	// Pods read from CCI reference the original names.
+	assert.NilError(t, provider.deletePodAnnotations(created))
+	assert.Check(t, is.Equal(created.Spec.Volumes[0].Secret.SecretName, "db"))
+	assert.Check(t, is.Equal(created.Spec.Containers[0].Env[0].ValueFrom.ConfigMapKeyRef.Name, "cfg"))
+	assert.Check(t, is.Equal(created.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Name, "cfg"))
+
+	// Updated objects are synced once the provider started, including the
+	// objects replicated by a previous run.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider.resources = resourceSyncer{}
+	assert.NilError(t, provider.ReconcilePods(ctx))
+	assert.Check(t, is.Equal(provider.resources.version(resourceKey(resourceKindSecret, "ns", "db")), "1"))
+	assert.Check(t, is.Equal(provider.resources.version(resourceKey(resourceKindConfigMap, "ns", "cfg")), "1"))
+
+	secret = secret.DeepCopy()
+	secret.ResourceVersion = "2"
+	secret.Data["password"] = []byte("updated")
+	assert.NilError(t, secrets.Update(secret))
+	deadline := time.Now().Add(10 * time.Second)
+	for resources.get("secrets/2-ns-db")["data"].(map[string]interface{})["password"] != "dXBkYXRlZA==" {
+		if time.Now().After(deadline) {
+			t.Fatal("the updated secret was not synced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Objects referenced by no other pod are deleted with the pod.
+	resources.takeRequests()
+	assert.NilError(t, provider.DeletePod(context.Background(), newResourcesTestPod("pod", "db")))
+	assert.Check(t, is.DeepEqual(resources.takeRequests(), []string{"DELETE secrets/2-ns-db"}))
+	assert.Check(t, is.Nil(resources.get("secrets/2-ns-db")))
+	assert.Check(t, resources.get("configmaps/2-ns-cfg") != nil, "the config map used by another pod should be kept")
+}
+
+func TestPodResourcesMissing(t *testing.T) {
+	_, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	provider.resourceManager, err = manager.NewResourceManager(corev1listers.NewPodLister(newIndexer()), corev1listers.NewSecretLister(newIndexer()), corev1listers.NewConfigMapLister(newIndexer()), nil)
+	assert.NilError(t, err)
+
+	// The pod can't be created until the objects it requires exist.
+	err = provider.CreatePod(context.Background(), newResourcesTestPod("pod", "db"))
+	assert.Check(t, is.ErrorContains(err, "error getting secret ns/db"))
+}
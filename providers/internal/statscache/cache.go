@@ -0,0 +1,54 @@
+// Package statscache caches the stats summaries of providers whose metrics
+// take several calls to the API of their container service.
+package statscache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+// DefaultMaxAge is how long a stats summary is served from a cache before the
+// metrics are requested again, when the cache sets no MaxAge.
+const DefaultMaxAge = time.Minute
+
+// Cache holds the last stats summary of a provider, so that frequent scrapes
+// don't add calls to the API of its service.
+// The zero value is ready to use. Its methods may be called concurrently.
+type Cache struct {
+	// MaxAge is how long a summary is served from the cache, DefaultMaxAge
+	// if it is not positive.
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	summary *stats.Summary
+	time    time.Time
+}
+
+// Get returns the cached summary if it is younger than the maximum age of the
+// cache, and otherwise the summary returned by fetch, which is cached unless
+// fetch fails.
+// Calls are serialized, so concurrent scrapes of an expired cache fetch the
+// summary once.
+func (c *Cache) Get(ctx context.Context, fetch func(context.Context) (*stats.Summary, error)) (*stats.Summary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maxAge := c.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if c.summary != nil && time.Since(c.time) < maxAge {
+		return c.summary, nil
+	}
+
+	summary, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.summary = summary
+	c.time = time.Now()
+	return summary, nil
+}
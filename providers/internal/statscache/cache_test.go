@@ -0,0 +1,44 @@
+package statscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+func TestCache(t *testing.T) {
+	fetches := 0
+	fetch := func(context.Context) (*stats.Summary, error) {
+		fetches++
+		return &stats.Summary{}, nil
+	}
+
+	var c Cache
+	summary, err := c.Get(context.Background(), fetch)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(fetches, 1))
+
+	// The summary is served from the cache.
+	cached, err := c.Get(context.Background(), fetch)
+	assert.NilError(t, err)
+	assert.Check(t, cached == summary)
+	assert.Check(t, is.Equal(fetches, 1))
+
+	// Errors are returned and not cached.
+	c.MaxAge = time.Nanosecond
+	_, err = c.Get(context.Background(), func(context.Context) (*stats.Summary, error) {
+		return nil, errors.New("unavailable")
+	})
+	assert.Check(t, is.Error(err, "unavailable"))
+
+	// The summary is fetched again once the cache expired.
+	cached, err = c.Get(context.Background(), fetch)
+	assert.NilError(t, err)
+	assert.Check(t, cached != summary)
+	assert.Check(t, is.Equal(fetches, 2))
+}
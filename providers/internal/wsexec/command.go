@@ -0,0 +1,40 @@
+package wsexec
+
+import "strings"
+
+// CommandString quotes a command and its arguments into the single command
+// string accepted by the exec APIs of container services.
+// Arguments containing characters other than letters, digits and a few safe
+// punctuation characters are single quoted using POSIX shell rules.
+func CommandString(cmd []string) string {
+	args := make([]string, 0, len(cmd))
+	for _, arg := range cmd {
+		args = append(args, quoteArg(arg))
+	}
+	return strings.Join(args, " ")
+}
+
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range arg {
+		if !isSafeArgRune(r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+func isSafeArgRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_./=:,+@%", r)
+}
@@ -0,0 +1,26 @@
+package wsexec
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCommandString(t *testing.T) {
+	cases := []struct {
+		cmd      []string
+		expected string
+	}{
+		{[]string{"/bin/sh"}, "/bin/sh"},
+		{[]string{"ls", "-la", "/var/log"}, "ls -la /var/log"},
+		{[]string{"sh", "-c", "echo hello; exit 3"}, "sh -c 'echo hello; exit 3'"},
+		{[]string{"sh", "-c", "echo $HOME && exit 1"}, "sh -c 'echo $HOME && exit 1'"},
+		{[]string{"echo", "it's"}, `echo 'it'\''s'`},
+		{[]string{"echo", ""}, "echo ''"},
+	}
+
+	for _, c := range cases {
+		assert.Check(t, is.Equal(CommandString(c.cmd), c.expected))
+	}
+}
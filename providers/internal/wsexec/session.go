@@ -0,0 +1,81 @@
+// Package wsexec streams the input and output of commands executed in
+// containers over websocket exec sessions, as provided by several container
+// services, and builds the command strings these services take.
+package wsexec
+
+import (
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+const stdinBufferSize = 32 * 1024
+
+// EOT is the end of transmission character. Sent through a terminal, it makes
+// the next read of the command return EOF.
+var EOT = []byte{4}
+
+// Session is a websocket connection to an exec session.
+// Its methods may be called concurrently.
+type Session struct {
+	conn *websocket.Conn
+	// mu serializes writes, gorilla/websocket connections support a single
+	// concurrent writer.
+	mu sync.Mutex
+}
+
+// NewSession returns a session using the passed in connection.
+func NewSession(conn *websocket.Conn) *Session {
+	return &Session{conn: conn}
+}
+
+// WriteMessage sends a message to the exec session.
+func (s *Session) WriteMessage(messageType int, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteMessage(messageType, data)
+}
+
+// CopyStdin sends the data read from in to the exec session as binary
+// messages, until in returns an error.
+//
+// Websockets can't be half-closed, closing the session would drop the output
+// the command writes after its input ends. On EOF, eof is sent instead if it
+// is not empty, e.g. EOT for sessions attached to a terminal, and the session
+// is left open until the server closes it.
+func (s *Session) CopyStdin(in io.Reader, eof []byte) {
+	buf := make([]byte, stdinBufferSize)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if werr := s.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err == io.EOF && len(eof) > 0 {
+				s.WriteMessage(websocket.BinaryMessage, eof)
+			}
+			return
+		}
+	}
+}
+
+// CopyOutput copies the messages received from the exec session to w until
+// the server closes the session. A normal closure returns nil.
+func (s *Session) CopyOutput(w io.Writer) error {
+	for {
+		_, r, err := s.conn.NextReader()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				return nil
+			}
+			return errors.Wrap(err, "error reading from exec session")
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			return errors.Wrap(err, "error writing exec output")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package wsexec
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// echoServer echoes the binary messages it receives until EOT, then writes
+// some trailing output and closes the session.
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if bytes.Equal(data, EOT) {
+				conn.WriteMessage(websocket.BinaryMessage, []byte(" done"))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			conn.WriteMessage(websocket.BinaryMessage, data)
+		}
+	}))
+}
+
+func TestSession(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	s := NewSession(conn)
+	go s.CopyStdin(strings.NewReader("hello"), EOT)
+
+	var out bytes.Buffer
+	assert.NilError(t, s.CopyOutput(&out))
+	assert.Check(t, is.Equal(out.String(), "hello done"))
+}
+
+func TestSessionAbnormalClosure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "boom"))
+		conn.Close()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	err = NewSession(conn).CopyOutput(&bytes.Buffer{})
+	assert.Check(t, is.ErrorContains(err, "error reading from exec session"))
+}
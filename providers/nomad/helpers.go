@@ -3,45 +3,93 @@ package nomad
 import (
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // createNomadTasks takes the containers in a kubernetes pod and creates
 // a list of Nomad tasks from them.
-func (p *Provider) createNomadTasks(pod *v1.Pod) []*nomad.Task {
+func (p *Provider) createNomadTasks(pod *v1.Pod) ([]*nomad.Task, error) {
+	driver := defaultNomadDriver
+	if d := pod.Annotations[nomadDriverAnnotation]; d != "" {
+		driver = d
+	}
+	if !nomadDrivers[driver] {
+		return nil, fmt.Errorf("unsupported nomad driver %q", driver)
+	}
+
+	volumes, err := p.createVolumes(pod)
+	if err != nil {
+		return nil, err
+	}
+
 	nomadTasks := make([]*nomad.Task, 0, len(pod.Spec.Containers))
 
 	for _, ctr := range pod.Spec.Containers {
 		portMap, networkResourcess := createPortMap(ctr.Ports)
-		image := ctr.Image
-		labels := pod.Labels
-		command := ctr.Command
-		args := ctr.Args
-		resources := createResources(ctr.Resources.Limits, networkResourcess)
+		resources := createResources(ctr.Resources, networkResourcess)
 		envVars := createEnvVars(ctr.Env)
+		mounts, templates, err := createVolumeMounts(ctr, driver, volumes)
+		if err != nil {
+			return nil, err
+		}
+		config, err := createTaskConfig(ctr, driver)
+		if err != nil {
+			return nil, err
+		}
+
+		switch driver {
+		case "docker", "podman":
+			config["image"] = ctr.Image
+			config["port_map"] = portMap
+			if len(mounts) > 0 {
+				config["volumes"] = mounts
+			}
+		}
+		if driver == "docker" {
+			config["labels"] = pod.Labels
+		}
+
 		task := nomad.Task{
-			Name:   ctr.Name,
-			Driver: "docker",
-			Config: map[string]interface{}{
-				"image":    image,
-				"port_map": portMap,
-				"labels":   labels,
-				// TODO: Add volumes support
-				"command": strings.Join(command, ""),
-				"args":    args,
-			},
+			Name:      ctr.Name,
+			Driver:    driver,
+			Config:    config,
 			Resources: resources,
 			Env:       envVars,
+			Templates: templates,
 		}
 		nomadTasks = append(nomadTasks, &task)
 	}
 
-	return nomadTasks
+	return nomadTasks, nil
+}
+
+// createTaskConfig returns the task config of the command of a container.
+// The first element of the command is the command of the task, the following
+// elements and the arguments of the container are its arguments.
+//
+// Containers without a command run the entrypoint of their image with their
+// arguments, which requires a driver running images.
+func createTaskConfig(ctr v1.Container, driver string) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+
+	var args []string
+	if len(ctr.Command) > 0 {
+		config["command"] = ctr.Command[0]
+		args = append(args, ctr.Command[1:]...)
+	} else if driver == "exec" || driver == "raw_exec" {
+		return nil, fmt.Errorf("container %s: a command is required by the %s driver", ctr.Name, driver)
+	}
+	args = append(args, ctr.Args...)
+
+	if len(args) > 0 {
+		config["args"] = args
+	}
+	return config, nil
 }
 
 func createPortMap(ports []v1.ContainerPort) ([]map[string]interface{}, []*nomad.NetworkResource) {
@@ -61,9 +109,21 @@ func createPortMap(ports []v1.ContainerPort) ([]map[string]interface{}, []*nomad
 	return portMap, append(networkResources, &nomad.NetworkResource{DynamicPorts: dynamicPorts})
 }
 
-func createResources(limits v1.ResourceList, networkResources []*nomad.NetworkResource) *nomad.Resources {
-	taskMemory := int(limits.Memory().Value())
-	taskCPU := int(limits.Cpu().Value())
+// createResources returns the resources reserved for a container, its
+// requests or, when not set, its limits. Nomad measures CPU in MHz, a core is
+// counted as 1000 MHz.
+func createResources(requirements v1.ResourceRequirements, networkResources []*nomad.NetworkResource) *nomad.Resources {
+	memory, ok := requirements.Requests[v1.ResourceMemory]
+	if !ok {
+		memory = requirements.Limits[v1.ResourceMemory]
+	}
+	cpu, ok := requirements.Requests[v1.ResourceCPU]
+	if !ok {
+		cpu = requirements.Limits[v1.ResourceCPU]
+	}
+
+	taskMemory := int(memory.Value() / (1024 * 1024))
+	taskCPU := int(cpu.MilliValue())
 
 	if taskMemory == 0 {
 		taskMemory = 128
@@ -109,14 +169,15 @@ func (p *Provider) createTaskGroups(name string, tasks []*nomad.Task) []*nomad.T
 	}
 }
 
-func (p *Provider) createJob(name string, datacenters []string, taskGroups []*nomad.TaskGroup) *nomad.Job {
-	jobName := fmt.Sprintf("%s-%s", jobNamePrefix, name)
+func (p *Provider) createJob(pod *v1.Pod, datacenters []string, taskGroups []*nomad.TaskGroup) *nomad.Job {
+	jobName := jobID(pod)
 
 	// Create a new nomad job
 	job := nomad.NewServiceJob(jobName, jobName, p.nomadRegion, 100)
 
 	job.Datacenters = datacenters
 	job.TaskGroups = taskGroups
+	p.setJobMeta(job, pod)
 
 	return job
 }
@@ -146,11 +207,12 @@ func (p *Provider) jobToPod(job *nomad.Job, allocs []*nomad.AllocationListStub)
 				}
 			}
 
+			command, args := taskCommand(task)
 			containers = append(containers, v1.Container{
 				Name:    task.Name,
 				Image:   fmt.Sprintf("%s", task.Config["image"]),
-				Command: strings.Split(fmt.Sprintf("%s", task.Config["command"]), ""),
-				Args:    strings.Split(fmt.Sprintf("%s", task.Config["args"]), " "),
+				Command: command,
+				Args:    args,
 				Ports:   containerPorts,
 			})
 
@@ -161,14 +223,28 @@ func (p *Provider) jobToPod(job *nomad.Job, allocs []*nomad.AllocationListStub)
 		}
 	}
 
+	// Jobs created by previous versions of the provider have no meta, their
+	// pod is the pod which adopted them.
+	name := job.Meta[jobMetaPodName]
+	namespace := job.Meta[jobMetaNamespace]
+	uid := types.UID(job.Meta[jobMetaPodUID])
+	if isLegacyJob(job) {
+		pod := p.legacyJobPod(job)
+		if pod == nil {
+			return nil, fmt.Errorf("nomad job %q was not adopted by a pod", *job.ID)
+		}
+		name, namespace, uid = pod.Name, pod.Namespace, pod.UID
+	}
+
 	pod := v1.Pod{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Pod",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:              *job.Name,
-			Namespace:         "default",
+			Name:              name,
+			Namespace:         namespace,
+			UID:               uid,
 			CreationTimestamp: metav1.NewTime(time.Unix(jobCreatedAt, 0)),
 		},
 		Spec: v1.PodSpec{
@@ -295,3 +371,21 @@ func convertTaskStateToContainerState(taskState string, startedAt time.Time, fin
 
 	return containerState, readyFlag
 }
+
+// taskCommand returns the command and arguments of a task, as found in its
+// config.
+func taskCommand(task *nomad.Task) ([]string, []string) {
+	var command, args []string
+	if c, ok := task.Config["command"].(string); ok && c != "" {
+		command = []string{c}
+	}
+	switch a := task.Config["args"].(type) {
+	case []string:
+		args = a
+	case []interface{}:
+		for _, arg := range a {
+			args = append(args, fmt.Sprintf("%v", arg))
+		}
+	}
+	return command, args
+}
@@ -0,0 +1,120 @@
+package nomad
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreateNomadTasks(t *testing.T) {
+	p := &Provider{}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "ns",
+		},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{Name: "data", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+				{Name: "host", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}}},
+			},
+			Containers: []v1.Container{
+				{
+					Name:    "app",
+					Image:   "busybox",
+					Command: []string{"sh", "-c"},
+					Args:    []string{"echo hello world"},
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse("250m"),
+							v1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+						Limits: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse("1"),
+							v1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "data", MountPath: "/data"},
+						{Name: "host", MountPath: "/logs", ReadOnly: true},
+					},
+				},
+			},
+		},
+	}
+
+	tasks, err := p.createNomadTasks(pod)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(tasks, 1))
+
+	task := tasks[0]
+	assert.Check(t, is.Equal(task.Driver, "docker"))
+	assert.Check(t, is.Equal(task.Config["image"], "busybox"))
+	assert.Check(t, is.Equal(task.Config["command"], "sh"))
+	assert.Check(t, is.DeepEqual(task.Config["args"], []string{"-c", "echo hello world"}))
+	assert.Check(t, is.DeepEqual(task.Config["volumes"], []string{"../alloc/data:/data", "/var/log:/logs:ro"}))
+	assert.Check(t, is.Equal(*task.Resources.CPU, 250))
+	assert.Check(t, is.Equal(*task.Resources.MemoryMB, 64))
+
+	command, args := taskCommand(task)
+	assert.Check(t, is.DeepEqual(command, []string{"sh"}))
+	assert.Check(t, is.DeepEqual(args, []string{"-c", "echo hello world"}))
+}
+
+func TestCreateNomadTasksDriver(t *testing.T) {
+	p := &Provider{}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "ns",
+			Annotations: map[string]string{nomadDriverAnnotation: "raw_exec"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "app", Command: []string{"/bin/sleep", "60"}},
+			},
+		},
+	}
+
+	tasks, err := p.createNomadTasks(pod)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(tasks, 1))
+	assert.Check(t, is.Equal(tasks[0].Driver, "raw_exec"))
+	assert.Check(t, is.Equal(tasks[0].Config["command"], "/bin/sleep"))
+	assert.Check(t, is.Nil(tasks[0].Config["image"]))
+
+	pod.Spec.Containers[0].Command = nil
+	_, err = p.createNomadTasks(pod)
+	assert.Check(t, is.ErrorContains(err, "a command is required"))
+
+	pod.Annotations[nomadDriverAnnotation] = "qemu"
+	_, err = p.createNomadTasks(pod)
+	assert.Check(t, is.ErrorContains(err, "unsupported nomad driver"))
+}
+
+func TestVolumeTemplates(t *testing.T) {
+	mode := int32(0400)
+	data := map[string][]byte{
+		"b.conf": []byte("b"),
+		"a.tmpl": []byte("{{ .Value }}"),
+	}
+
+	templates, err := volumeTemplates("secrets/config", data, nil, &mode, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(templates, 2))
+	assert.Check(t, is.Equal(*templates[0].DestPath, "secrets/config/a.tmpl"))
+	assert.Check(t, is.Equal(*templates[0].EmbeddedTmpl, "{{ .Value }}"))
+	assert.Check(t, is.Equal(*templates[0].LeftDelim, "{{{"))
+	assert.Check(t, is.Equal(*templates[0].RightDelim, "}}}"))
+	assert.Check(t, is.Equal(*templates[0].Perms, "400"))
+	assert.Check(t, is.Equal(*templates[1].DestPath, "secrets/config/b.conf"))
+
+	_, err = volumeTemplates("local/config", data, []v1.KeyToPath{{Key: "missing", Path: "missing"}}, nil, nil)
+	assert.Check(t, is.ErrorContains(err, "key missing not found"))
+}
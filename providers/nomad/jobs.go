@@ -0,0 +1,196 @@
+package nomad
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	nomad "github.com/hashicorp/nomad/api"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Meta keys of the jobs of pods, identifying the pod a job was created for.
+const (
+	jobMetaNamespace = "k8s_namespace"
+	jobMetaPodName   = "k8s_pod_name"
+	jobMetaPodUID    = "k8s_pod_uid"
+	jobMetaNodeName  = "k8s_node_name"
+)
+
+// jobID returns the ID of the job of a pod. It is unique across namespaces
+// and pods recreated with the same name.
+func jobID(pod *v1.Pod) string {
+	return fmt.Sprintf("%s-%s-%s-%s", jobNamePrefix, pod.Namespace, pod.Name, pod.UID)
+}
+
+// jobIDPrefix returns the prefix of the IDs of the jobs of the pods with the
+// given namespace and name. Other pods may share the prefix, when namespaces
+// or names contain dashes, so the meta of the jobs must be checked.
+func jobIDPrefix(namespace, name string) string {
+	return fmt.Sprintf("%s-%s-%s-", jobNamePrefix, namespace, name)
+}
+
+// legacyJobID returns the ID of the job of a pod created by previous versions
+// of the provider, which named jobs after the pod name only.
+func legacyJobID(name string) string {
+	return fmt.Sprintf("%s-%s", jobNamePrefix, name)
+}
+
+// isLegacyJob returns whether a job was created by previous versions of the
+// provider, without the meta identifying its pod.
+func isLegacyJob(job *nomad.Job) bool {
+	_, ok := job.Meta[jobMetaNamespace]
+	return !ok
+}
+
+// jobNamespace returns the Nomad namespace of the jobs of the pods of a
+// Kubernetes namespace.
+func (p *Provider) jobNamespace(namespace string) string {
+	if p.mapNamespaces {
+		return namespace
+	}
+	return p.nomadNamespace
+}
+
+// setJobMeta records the pod a job is created for in its meta and namespace.
+func (p *Provider) setJobMeta(job *nomad.Job, pod *v1.Pod) {
+	namespace := p.jobNamespace(pod.Namespace)
+	job.Namespace = &namespace
+	job.SetMeta(jobMetaNamespace, pod.Namespace)
+	job.SetMeta(jobMetaPodName, pod.Name)
+	job.SetMeta(jobMetaPodUID, string(pod.UID))
+	job.SetMeta(jobMetaNodeName, p.nodeName)
+}
+
+// findJob returns the job of a pod of the node. Legacy jobs named after the
+// pod name only are found too, in the Nomad namespace of the pod, once they
+// were adopted by ReconcilePods.
+func (p *Provider) findJob(namespace, name string) (*nomad.Job, error) {
+	q := &nomad.QueryOptions{Namespace: p.jobNamespace(namespace)}
+	stubs, _, err := p.nomadClient.Jobs().List(&nomad.QueryOptions{Namespace: q.Namespace, Prefix: jobIDPrefix(namespace, name)})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get job list from nomad: %s", err)
+	}
+
+	for _, stub := range stubs {
+		job, _, err := p.nomadClient.Jobs().Info(stub.ID, q)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't retrieve nomad job: %s", err)
+		}
+		if job.Meta[jobMetaNamespace] == namespace && job.Meta[jobMetaPodName] == name && job.Meta[jobMetaNodeName] == p.nodeName {
+			return job, nil
+		}
+	}
+
+	job, _, err := p.nomadClient.Jobs().Info(legacyJobID(name), q)
+	if err == nil && isLegacyJob(job) {
+		if pod := p.legacyJobPod(job); pod != nil && pod.Namespace == namespace && pod.Name == name {
+			return job, nil
+		}
+	}
+	if err != nil && !strings.Contains(err.Error(), "404") {
+		return nil, fmt.Errorf("couldn't retrieve nomad job: %s", err)
+	}
+
+	return nil, strongerrors.NotFound(fmt.Errorf("nomad job of pod %s/%s not found", namespace, name))
+}
+
+// listJobs returns the jobs of the pods of the node, in all the Nomad
+// namespaces used for pods.
+func (p *Provider) listJobs() ([]*nomad.Job, error) {
+	namespaces := []string{p.nomadNamespace}
+	if p.mapNamespaces {
+		list, _, err := p.nomadClient.Namespaces().List(nil)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get namespace list from nomad: %s", err)
+		}
+		namespaces = namespaces[:0]
+		for _, ns := range list {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	var jobs []*nomad.Job
+	for _, namespace := range namespaces {
+		q := &nomad.QueryOptions{Namespace: namespace}
+		stubs, _, err := p.nomadClient.Jobs().List(&nomad.QueryOptions{Namespace: namespace, Prefix: jobNamePrefix})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get job list from nomad: %s", err)
+		}
+
+		for _, stub := range stubs {
+			job, _, err := p.nomadClient.Jobs().Info(stub.ID, q)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't retrieve nomad job: %s", err)
+			}
+			if isLegacyJob(job) || job.Meta[jobMetaNodeName] == p.nodeName {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// legacyJobKey returns the key of a legacy job in the adopted legacy jobs.
+func (p *Provider) legacyJobKey(job *nomad.Job) string {
+	namespace := p.nomadNamespace
+	if job.Namespace != nil {
+		namespace = *job.Namespace
+	}
+	return namespace + "/" + *job.ID
+}
+
+// legacyJobPod returns the pod a legacy job was adopted for, or nil if it was
+// not adopted.
+func (p *Provider) legacyJobPod(job *nomad.Job) *v1.Pod {
+	p.legacyJobsMu.RLock()
+	defer p.legacyJobsMu.RUnlock()
+	return p.legacyJobs[p.legacyJobKey(job)]
+}
+
+// ReconcilePods implements providers.PodReconciler.
+// Jobs created by previous versions of the provider are named after the pod
+// name only and have no meta identifying their pod. They are adopted by the
+// pod of the node with their name whose Nomad namespace is the namespace of
+// the job, and are left running under their ID: renaming or updating the job
+// would restart its allocations.
+// Legacy jobs matching no pod or several pods are not adopted, they are
+// neither returned as pods nor deleted by the provider.
+func (p *Provider) ReconcilePods(ctx context.Context) error {
+	jobs, err := p.listJobs()
+	if err != nil {
+		return err
+	}
+
+	pods := make(map[string][]*v1.Pod)
+	for _, pod := range p.resourceManager.GetPods() {
+		if pod.Spec.NodeName != p.nodeName {
+			continue
+		}
+		key := p.jobNamespace(pod.Namespace) + "/" + legacyJobID(pod.Name)
+		pods[key] = append(pods[key], pod)
+	}
+
+	adopted := make(map[string]*v1.Pod)
+	for _, job := range jobs {
+		if !isLegacyJob(job) {
+			continue
+		}
+
+		key := p.legacyJobKey(job)
+		if len(pods[key]) != 1 {
+			log.Printf("couldn't adopt nomad job %q: found %d matching pods", key, len(pods[key]))
+			continue
+		}
+		pod := pods[key][0]
+		adopted[key] = pod
+		log.Printf("adopted nomad job %q for pod %s/%s", key, pod.Namespace, pod.Name)
+	}
+
+	p.legacyJobsMu.Lock()
+	p.legacyJobs = adopted
+	p.legacyJobsMu.Unlock()
+	return nil
+}
@@ -0,0 +1,152 @@
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	testutil "github.com/virtual-kubelet/virtual-kubelet/test/util"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fakeNomad serves the jobs of the Nomad job API, keyed by namespace and ID.
+type fakeNomad struct {
+	t    *testing.T
+	jobs map[string]*nomad.Job
+}
+
+func (f *fakeNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace := r.URL.Query().Get("namespace")
+	if r.Method != http.MethodGet {
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/namespaces":
+		namespaces := map[string]*nomad.Namespace{}
+		for _, job := range f.jobs {
+			namespaces[*job.Namespace] = &nomad.Namespace{Name: *job.Namespace}
+		}
+		list := []*nomad.Namespace{}
+		for _, ns := range namespaces {
+			list = append(list, ns)
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.URL.Path == "/v1/jobs":
+		stubs := []*nomad.JobListStub{}
+		for key, job := range f.jobs {
+			if strings.HasPrefix(key, namespace+"/"+r.URL.Query().Get("prefix")) {
+				stubs = append(stubs, &nomad.JobListStub{ID: *job.ID})
+			}
+		}
+		json.NewEncoder(w).Encode(stubs)
+	case strings.HasSuffix(r.URL.Path, "/allocations"):
+		json.NewEncoder(w).Encode([]*nomad.AllocationListStub{})
+	case strings.HasPrefix(r.URL.Path, "/v1/job/"):
+		job, ok := f.jobs[namespace+"/"+strings.TrimPrefix(r.URL.Path, "/v1/job/")]
+		if !ok {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(job)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeNomadProvider(t *testing.T, f *fakeNomad, pods ...*v1.Pod) (*Provider, func()) {
+	server := httptest.NewServer(f)
+	client, err := nomad.NewClient(&nomad.Config{Address: server.URL})
+	assert.NilError(t, err)
+
+	objects := make([]runtime.Object, 0, len(pods))
+	for _, pod := range pods {
+		objects = append(objects, pod)
+	}
+	return &Provider{
+		nomadClient:     client,
+		resourceManager: testutil.FakeResourceManager(objects...),
+		nodeName:        "vk",
+		nomadNamespace:  nomad.DefaultNamespace,
+	}, server.Close
+}
+
+func legacyJob(namespace, name string) *nomad.Job {
+	id := legacyJobID(name)
+	status := "running"
+	submitTime := int64(0)
+	return &nomad.Job{ID: &id, Name: &id, Namespace: &namespace, Status: &status, SubmitTime: &submitTime}
+}
+
+func nodePod(namespace, name, uid string) *v1.Pod {
+	pod := testutil.FakePodWithSingleContainer(namespace, name, "nginx")
+	pod.UID = types.UID(uid)
+	pod.Spec.NodeName = "vk"
+	return pod
+}
+
+func TestReconcilePodsAdoptsLegacyJobs(t *testing.T) {
+	f := &fakeNomad{t: t, jobs: map[string]*nomad.Job{
+		"default/" + legacyJobID("web"):    legacyJob("default", "web"),
+		"default/" + legacyJobID("orphan"): legacyJob("default", "orphan"),
+	}}
+	p, cleanup := newFakeNomadProvider(t, f, nodePod("default", "web", "uid-web"))
+	defer cleanup()
+
+	// Legacy jobs are not returned before they are adopted.
+	pods, err := p.GetPods(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(pods, 0))
+	pod, err := p.GetPod(context.Background(), "default", "web")
+	assert.NilError(t, err)
+	assert.Check(t, pod == nil)
+
+	assert.NilError(t, p.ReconcilePods(context.Background()))
+
+	// The job matching a pod is returned under its legacy ID as the pod.
+	// The job matching no pod is ignored.
+	pods, err = p.GetPods(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(pods, 1))
+	assert.Check(t, is.Equal(pods[0].Namespace, "default"))
+	assert.Check(t, is.Equal(pods[0].Name, "web"))
+	assert.Check(t, is.Equal(pods[0].UID, types.UID("uid-web")))
+
+	pod, err = p.GetPod(context.Background(), "default", "web")
+	assert.NilError(t, err)
+	assert.Assert(t, pod != nil)
+	assert.Check(t, is.Equal(pod.UID, types.UID("uid-web")))
+
+	pod, err = p.GetPod(context.Background(), "default", "orphan")
+	assert.NilError(t, err)
+	assert.Check(t, pod == nil)
+}
+
+func TestReconcilePodsLegacyJobNamespace(t *testing.T) {
+	f := &fakeNomad{t: t, jobs: map[string]*nomad.Job{
+		"default/" + legacyJobID("web"): legacyJob("default", "web"),
+	}}
+	// With mapped namespaces, the pod of namespace other has its jobs in the
+	// Nomad namespace other.
+	p, cleanup := newFakeNomadProvider(t, f, nodePod("other", "web", "uid-web"))
+	defer cleanup()
+	p.mapNamespaces = true
+
+	assert.NilError(t, p.ReconcilePods(context.Background()))
+	assert.Check(t, p.legacyJobPod(f.jobs["default/"+legacyJobID("web")]) == nil)
+
+	pod, err := p.GetPod(context.Background(), "other", "web")
+	assert.NilError(t, err)
+	assert.Check(t, pod == nil)
+}
@@ -6,9 +6,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cpuguy83/strongerrors"
 	"github.com/virtual-kubelet/virtual-kubelet/manager"
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
 
@@ -24,11 +27,21 @@ import (
 const (
 	jobNamePrefix              = "nomad-virtual-kubelet"
 	nomadDatacentersAnnotation = "nomad.hashicorp.com/datacenters"
+	nomadDriverAnnotation      = "nomad.hashicorp.com/driver"
+	defaultNomadDriver         = "docker"
 	defaultNomadAddress        = "127.0.0.1:4646"
 	defaultNomadDatacenter     = "dc1"
 	defaultNomadRegion         = "global"
 )
 
+// nomadDrivers are the task drivers supported for pods.
+var nomadDrivers = map[string]bool{
+	"docker":   true,
+	"exec":     true,
+	"raw_exec": true,
+	"podman":   true,
+}
+
 // Provider implements the virtual-kubelet provider interface and communicates with the Nomad API.
 type Provider struct {
 	nomadClient     *nomad.Client
@@ -37,9 +50,16 @@ type Provider struct {
 	operatingSystem string
 	nomadAddress    string
 	nomadRegion     string
+	nomadNamespace  string
+	mapNamespaces   bool
 	cpu             string
 	memory          string
 	pods            string
+
+	// legacyJobs are the jobs created by previous versions of the provider
+	// adopted by the pods of the node, keyed by Nomad namespace and job ID.
+	legacyJobs   map[string]*v1.Pod
+	legacyJobsMu sync.RWMutex
 }
 
 // NewProvider creates a new Provider
@@ -50,6 +70,15 @@ func NewProvider(rm *manager.ResourceManager, nodeName, operatingSystem string)
 	p.operatingSystem = operatingSystem
 	p.nomadAddress = os.Getenv("NOMAD_ADDR")
 	p.nomadRegion = os.Getenv("NOMAD_REGION")
+	p.nomadNamespace = os.Getenv("NOMAD_NAMESPACE")
+
+	if mapNamespaces := os.Getenv("NOMAD_MAP_NAMESPACES"); mapNamespaces != "" {
+		m, err := strconv.ParseBool(mapNamespaces)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for NOMAD_MAP_NAMESPACES: %s", err)
+		}
+		p.mapNamespaces = m
+	}
 
 	if p.nomadAddress == "" {
 		p.nomadAddress = defaultNomadAddress
@@ -59,6 +88,10 @@ func NewProvider(rm *manager.ResourceManager, nodeName, operatingSystem string)
 		p.nomadRegion = defaultNomadRegion
 	}
 
+	if p.nomadNamespace == "" {
+		p.nomadNamespace = nomad.DefaultNamespace
+	}
+
 	c := nomad.DefaultConfig()
 	log.Printf("nomad client address: %s", p.nomadAddress)
 	nomadClient, err := nomad.NewClient(c.ClientConfig(p.nomadRegion, p.nomadAddress, false))
@@ -91,12 +124,15 @@ func (p *Provider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	}
 
 	// Create a list of nomad tasks
-	nomadTasks := p.createNomadTasks(pod)
+	nomadTasks, err := p.createNomadTasks(pod)
+	if err != nil {
+		return fmt.Errorf("couldn't create nomad tasks: %s", err)
+	}
 	taskGroups := p.createTaskGroups(pod.Name, nomadTasks)
-	job := p.createJob(pod.Name, datacenters, taskGroups)
+	job := p.createJob(pod, datacenters, taskGroups)
 
 	// Register nomad job
-	_, _, err := p.nomadClient.Jobs().Register(job, nil)
+	_, _, err = p.nomadClient.Jobs().Register(job, &nomad.WriteOptions{Namespace: *job.Namespace})
 	if err != nil {
 		return fmt.Errorf("couldn't start nomad job: %q", err)
 	}
@@ -112,13 +148,18 @@ func (p *Provider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
 
 // DeletePod accepts a Pod definition and deletes a Nomad job.
 func (p *Provider) DeletePod(ctx context.Context, pod *v1.Pod) (err error) {
+	job, err := p.findJob(pod.Namespace, pod.Name)
+	if err != nil {
+		return err
+	}
+
 	// Deregister job
-	response, _, err := p.nomadClient.Jobs().Deregister(pod.Name, true, nil)
+	response, _, err := p.nomadClient.Jobs().Deregister(*job.ID, true, &nomad.WriteOptions{Namespace: *job.Namespace})
 	if err != nil {
 		return fmt.Errorf("couldn't stop or deregister nomad job: %s: %s", response, err)
 	}
 
-	log.Printf("deregistered nomad job %q response %q\n", pod.Name, response)
+	log.Printf("deregistered nomad job %q response %q\n", *job.ID, response)
 
 	return nil
 }
@@ -126,22 +167,28 @@ func (p *Provider) DeletePod(ctx context.Context, pod *v1.Pod) (err error) {
 // GetPod returns the pod running in the Nomad cluster. returns nil
 // if pod is not found.
 func (p *Provider) GetPod(ctx context.Context, namespace, name string) (pod *v1.Pod, err error) {
-	jobID := fmt.Sprintf("%s-%s", jobNamePrefix, name)
-
 	// Get nomad job
-	job, _, err := p.nomadClient.Jobs().Info(jobID, nil)
+	job, err := p.findJob(namespace, name)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't retrieve nomad job: %s", err)
+		if strongerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
+	return p.jobPod(job)
+}
+
+// jobPod returns the pod of a nomad job.
+func (p *Provider) jobPod(job *nomad.Job) (*v1.Pod, error) {
 	// Get nomad job allocations to get individual task statuses
-	jobAllocs, _, err := p.nomadClient.Jobs().Allocations(jobID, false, nil)
+	jobAllocs, _, err := p.nomadClient.Jobs().Allocations(*job.ID, false, &nomad.QueryOptions{Namespace: *job.Namespace})
 	if err != nil {
 		return nil, fmt.Errorf("couldn't retrieve nomad job allocations: %s", err)
 	}
 
 	// Change a nomad job into a kubernetes pod
-	pod, err = p.jobToPod(job, jobAllocs)
+	pod, err := p.jobToPod(job, jobAllocs)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't convert a nomad job into a pod: %s", err)
 	}
@@ -156,7 +203,7 @@ func (p *Provider) GetContainerLogs(ctx context.Context, namespace, podName, con
 
 // GetPodFullName as defined in the provider context
 func (p *Provider) GetPodFullName(ctx context.Context, namespace string, pod string) string {
-	return fmt.Sprintf("%s-%s", jobNamePrefix, pod)
+	return fmt.Sprintf("%s-%s-%s", jobNamePrefix, namespace, pod)
 }
 
 // ExecInContainer executes a command in a container in the pod, copying data
@@ -174,35 +221,31 @@ func (p *Provider) GetPodStatus(ctx context.Context, namespace, name string) (*v
 	if err != nil {
 		return nil, err
 	}
+	if pod == nil {
+		return nil, nil
+	}
 	return &pod.Status, nil
 }
 
 // GetPods returns a list of all pods known to be running in Nomad nodes.
+// Jobs created by previous versions of the provider are only returned once
+// they were adopted by a pod, see ReconcilePods.
 func (p *Provider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	log.Printf("GetPods\n")
-	jobsList, _, err := p.nomadClient.Jobs().PrefixList(jobNamePrefix)
+	jobs, err := p.listJobs()
 	if err != nil {
-		return nil, fmt.Errorf("couldn't get job list from nomad: %s", err)
+		return nil, err
 	}
 
 	var pods = []*v1.Pod{}
-	for _, job := range jobsList {
-		// Get nomad job
-		j, _, err := p.nomadClient.Jobs().Info(job.ID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't retrieve nomad job: %s", err)
-		}
-
-		// Get nomad job allocations to get individual task statuses
-		jobAllocs, _, err := p.nomadClient.Jobs().Allocations(job.ID, false, nil)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't retrieve nomad job allocations: %s", err)
+	for _, job := range jobs {
+		if isLegacyJob(job) && p.legacyJobPod(job) == nil {
+			continue
 		}
 
-		// Change a nomad job into a kubernetes pod
-		pod, err := p.jobToPod(j, jobAllocs)
+		pod, err := p.jobPod(job)
 		if err != nil {
-			return nil, fmt.Errorf("couldn't convert a nomad job into a pod: %s", err)
+			return nil, err
 		}
 
 		pods = append(pods, pod)
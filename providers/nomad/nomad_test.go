@@ -2,10 +2,10 @@ package nomad
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"testing"
 
+	"github.com/cpuguy83/strongerrors"
 	"github.com/google/uuid"
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/testutil"
@@ -13,6 +13,7 @@ import (
 	is "gotest.tools/assert/cmp"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -35,11 +36,13 @@ func TestCreateGetDeletePod(t *testing.T) {
 
 	podName := "pod-" + uuid.New().String()
 	podNamespace := "ns-" + uuid.New().String()
+	podUID := types.UID(uuid.New().String())
 
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
 			Namespace: podNamespace,
+			UID:       podUID,
 		},
 		Spec: v1.PodSpec{
 			Containers: []v1.Container{
@@ -81,7 +84,9 @@ func TestCreateGetDeletePod(t *testing.T) {
 	assert.Check(t, pod != nil, "pod cannot be nil")
 	assert.Check(t, pod.Spec.Containers != nil, "containers cannot be nil")
 	assert.Check(t, is.Nil(pod.Annotations), "pod annotations should be nil")
-	assert.Check(t, is.Equal(pod.Name, fmt.Sprintf("%s-%s", jobNamePrefix, podName)), "pod name should be equal")
+	assert.Check(t, is.Equal(pod.Name, podName), "pod name should be equal")
+	assert.Check(t, is.Equal(pod.Namespace, podNamespace), "pod namespace should be equal")
+	assert.Check(t, is.Equal(pod.UID, podUID), "pod UID should be equal")
 
 	// Get pods
 	pods, err := provider.GetPods(context.Background())
@@ -97,6 +102,15 @@ func TestCreateGetDeletePod(t *testing.T) {
 	if err != nil {
 		t.Fatal("failed to delete pod", err)
 	}
+
+	// Get deleted pod
+	pod, err = provider.GetPod(context.Background(), podNamespace, podName)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(pod), "deleted pod should not be found")
+
+	// Delete deleted pod
+	err = provider.DeletePod(context.Background(), &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: podNamespace}})
+	assert.Check(t, strongerrors.IsNotFound(err), "deleting a deleted pod should return a not found error")
 }
 
 func makeClient(t *testing.T, cb testutil.ServerConfigCallback) (*nomad.Client, *testutil.TestServer) {
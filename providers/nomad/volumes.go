@@ -0,0 +1,213 @@
+package nomad
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// defaultVolumeFileMode is the mode of the files of secret and config map
+// volumes without a default mode.
+const defaultVolumeFileMode = 0644
+
+// podVolume is a volume of a pod as seen by the tasks of its job.
+type podVolume struct {
+	// source is the host path of the volume, relative to the task directory
+	// for the volumes stored in the allocation.
+	source string
+	// templates render the files of secret and config map volumes.
+	templates []*nomad.Template
+}
+
+// volumeDrivers are the task drivers supporting volume mounts.
+var volumeDrivers = map[string]bool{
+	"docker": true,
+	"podman": true,
+}
+
+// createVolumes returns the volumes of a pod, by name.
+//
+// emptyDir volumes are directories of the shared allocation directory, so
+// that all the tasks of the job see the same files. Secret and config map
+// volumes are rendered by templates into the directory of each task mounting
+// them, and hostPath volumes are mounted from the Nomad client.
+func (p *Provider) createVolumes(pod *v1.Pod) (map[string]*podVolume, error) {
+	volumes := make(map[string]*podVolume, len(pod.Spec.Volumes))
+	for _, v := range pod.Spec.Volumes {
+		volume := &podVolume{}
+
+		switch {
+		case v.EmptyDir != nil:
+			volume.source = path.Join("..", "alloc", v.Name)
+		case v.HostPath != nil:
+			volume.source = v.HostPath.Path
+		case v.Secret != nil:
+			secret, err := p.getSecret(v.Secret.SecretName, pod.Namespace, v.Secret.Optional)
+			if err != nil {
+				return nil, err
+			}
+			var data map[string][]byte
+			if secret != nil {
+				data = secret.Data
+			}
+			volume.source = path.Join("secrets", v.Name)
+			volume.templates, err = volumeTemplates(volume.source, data, v.Secret.Items, v.Secret.DefaultMode, v.Secret.Optional)
+			if err != nil {
+				return nil, fmt.Errorf("volume %s: %s", v.Name, err)
+			}
+		case v.ConfigMap != nil:
+			configMap, err := p.getConfigMap(v.ConfigMap.Name, pod.Namespace, v.ConfigMap.Optional)
+			if err != nil {
+				return nil, err
+			}
+			var data map[string][]byte
+			if configMap != nil {
+				data = make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+				for k, v := range configMap.Data {
+					data[k] = []byte(v)
+				}
+				for k, v := range configMap.BinaryData {
+					data[k] = v
+				}
+			}
+			volume.source = path.Join("local", v.Name)
+			volume.templates, err = volumeTemplates(volume.source, data, v.ConfigMap.Items, v.ConfigMap.DefaultMode, v.ConfigMap.Optional)
+			if err != nil {
+				return nil, fmt.Errorf("volume %s: %s", v.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("volume %s: only emptyDir, hostPath, secret and config map volumes are supported", v.Name)
+		}
+
+		volumes[v.Name] = volume
+	}
+	return volumes, nil
+}
+
+// createVolumeMounts returns the driver volumes and the templates of the
+// volumes mounted by a container.
+func createVolumeMounts(ctr v1.Container, driver string, volumes map[string]*podVolume) ([]string, []*nomad.Template, error) {
+	if len(ctr.VolumeMounts) == 0 {
+		return nil, nil, nil
+	}
+	if !volumeDrivers[driver] {
+		return nil, nil, fmt.Errorf("container %s: volume mounts are not supported by the %s driver", ctr.Name, driver)
+	}
+
+	var (
+		mounts    []string
+		templates []*nomad.Template
+		rendered  = map[string]bool{}
+	)
+	for _, m := range ctr.VolumeMounts {
+		volume, ok := volumes[m.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("container %s: volume %s not found", ctr.Name, m.Name)
+		}
+
+		source := volume.source
+		if m.SubPath != "" {
+			source = path.Join(source, m.SubPath)
+		}
+		mount := source + ":" + m.MountPath
+		if m.ReadOnly {
+			mount += ":ro"
+		}
+		mounts = append(mounts, mount)
+
+		if !rendered[m.Name] {
+			templates = append(templates, volume.templates...)
+			rendered[m.Name] = true
+		}
+	}
+	return mounts, templates, nil
+}
+
+// volumeTemplates returns the templates rendering the files of a secret or
+// config map volume into dir, all keys of data or only the given items.
+func volumeTemplates(dir string, data map[string][]byte, items []v1.KeyToPath, defaultMode *int32, optional *bool) ([]*nomad.Template, error) {
+	mode := int32(defaultVolumeFileMode)
+	if defaultMode != nil {
+		mode = *defaultMode
+	}
+
+	if len(items) == 0 {
+		for k := range data {
+			items = append(items, v1.KeyToPath{Key: k, Path: k})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	}
+
+	templates := make([]*nomad.Template, 0, len(items))
+	for _, item := range items {
+		content, ok := data[item.Key]
+		if !ok {
+			if optional != nil && *optional {
+				continue
+			}
+			return nil, fmt.Errorf("key %s not found", item.Key)
+		}
+
+		itemMode := mode
+		if item.Mode != nil {
+			itemMode = *item.Mode
+		}
+		templates = append(templates, newFileTemplate(path.Join(dir, item.Path), string(content), itemMode))
+	}
+	return templates, nil
+}
+
+// newFileTemplate returns a template rendering content as is into destPath.
+// Its delimiters don't appear in content, so that content is never
+// interpreted as a template.
+func newFileTemplate(destPath, content string, mode int32) *nomad.Template {
+	leftDelim, rightDelim := "{{", "}}"
+	for strings.Contains(content, leftDelim) {
+		leftDelim += "{"
+	}
+	for strings.Contains(content, rightDelim) {
+		rightDelim += "}"
+	}
+	perms := fmt.Sprintf("%o", mode)
+
+	return &nomad.Template{
+		DestPath:     &destPath,
+		EmbeddedTmpl: &content,
+		LeftDelim:    &leftDelim,
+		RightDelim:   &rightDelim,
+		Perms:        &perms,
+	}
+}
+
+func (p *Provider) getSecret(name, namespace string, optional *bool) (*v1.Secret, error) {
+	if p.resourceManager == nil {
+		return nil, fmt.Errorf("couldn't get secret %s/%s: no resource manager", namespace, name)
+	}
+	secret, err := p.resourceManager.GetSecret(name, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) && optional != nil && *optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't get secret %s/%s: %s", namespace, name, err)
+	}
+	return secret, nil
+}
+
+func (p *Provider) getConfigMap(name, namespace string, optional *bool) (*v1.ConfigMap, error) {
+	if p.resourceManager == nil {
+		return nil, fmt.Errorf("couldn't get config map %s/%s: no resource manager", namespace, name)
+	}
+	configMap, err := p.resourceManager.GetConfigMap(name, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) && optional != nil && *optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't get config map %s/%s: %s", namespace, name, err)
+	}
+	return configMap, nil
+}
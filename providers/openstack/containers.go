@@ -0,0 +1,164 @@
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/container/v1/capsules"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/wsexec"
+)
+
+// The calls to the Zun container API below are not provided by gophercloud.
+
+func containerURL(client *gophercloud.ServiceClient, id string, action string) string {
+	return client.ServiceURL("containers", id, action)
+}
+
+// containerLogsOpts are the options of containerLogs.
+type containerLogsOpts struct {
+	// Tail is the number of lines to return from the end of the logs, all
+	// lines are returned if it is not positive.
+	Tail       int
+	Timestamps bool
+}
+
+// containerLogs returns the stdout and stderr logs of a container.
+func containerLogs(client *gophercloud.ServiceClient, id string, opts containerLogsOpts) (string, error) {
+	q := url.Values{}
+	q.Set("stdout", "true")
+	q.Set("stderr", "true")
+	q.Set("timestamps", strconv.FormatBool(opts.Timestamps))
+	if opts.Tail > 0 {
+		q.Set("tail", strconv.Itoa(opts.Tail))
+	} else {
+		q.Set("tail", "all")
+	}
+
+	resp, err := client.Get(containerURL(client, id, "logs")+"?"+q.Encode(), nil, &gophercloud.RequestOpts{
+		OkCodes: []int{200},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	// Zun returns the logs as a JSON string.
+	var logs string
+	if err := json.Unmarshal(b, &logs); err != nil {
+		return string(b), nil
+	}
+	return logs, nil
+}
+
+// containerExecuteOpts are the options of containerExecute.
+type containerExecuteOpts struct {
+	Command []string
+	// Interactive starts the command attached to a websocket instead of
+	// running it to completion.
+	Interactive bool
+}
+
+// containerExecuteResult is the result of containerExecute. Output and
+// ExitCode are set for commands run to completion, ExecID and URL for
+// interactive ones.
+type containerExecuteResult struct {
+	Output   string `json:"output"`
+	ExitCode int    `json:"exit_code"`
+	ExecID   string `json:"exec_id"`
+	URL      string `json:"url"`
+}
+
+// containerExecute executes a command in a container.
+func containerExecute(client *gophercloud.ServiceClient, id string, opts containerExecuteOpts) (*containerExecuteResult, error) {
+	q := url.Values{}
+	q.Set("command", wsexec.CommandString(opts.Command))
+	q.Set("run", "true")
+	q.Set("interactive", strconv.FormatBool(opts.Interactive))
+
+	var result containerExecuteResult
+	_, err := client.Post(containerURL(client, id, "execute")+"?"+q.Encode(), nil, &result, &gophercloud.RequestOpts{
+		OkCodes: []int{200},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// containerExecuteResize resizes the terminal of an interactive command.
+func containerExecuteResize(client *gophercloud.ServiceClient, id, execID string, height, width uint16) error {
+	q := url.Values{}
+	q.Set("exec_id", execID)
+	q.Set("h", strconv.Itoa(int(height)))
+	q.Set("w", strconv.Itoa(int(width)))
+
+	_, err := client.Post(containerURL(client, id, "execute_resize")+"?"+q.Encode(), nil, nil, &gophercloud.RequestOpts{
+		OkCodes: []int{200, 202},
+	})
+	return err
+}
+
+// containerStats are the resource usage statistics of a container, in the
+// format of `docker stats`.
+type containerStats struct {
+	CPUPercent    float64 `json:"CPU %"`
+	MemoryUsageMB float64 `json:"MEM USAGE(MiB)"`
+	MemoryLimitMB float64 `json:"MEM LIMIT(MiB)"`
+	// BlockIO and NetIO are the numbers of bytes read and written, or
+	// received and sent, separated by a slash.
+	BlockIO string `json:"BLOCK I/O(B)"`
+	NetIO   string `json:"NET I/O(B)"`
+}
+
+// getContainerStats returns the resource usage statistics of a container.
+func getContainerStats(client *gophercloud.ServiceClient, id string) (*containerStats, error) {
+	var stats containerStats
+	_, err := client.Get(containerURL(client, id, "stats"), &stats, &gophercloud.RequestOpts{
+		OkCodes: []int{200},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// parseIOPair parses a pair of byte counts separated by a slash.
+func parseIOPair(s string) (uint64, uint64, bool) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	in, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	out, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return in, out, true
+}
+
+// capsuleContainer returns the container of a capsule with the given name.
+// Capsules whose containers were not named after the containers of the pod
+// have their only container returned.
+func capsuleContainer(capsule *capsules.CapsuleV132, name string) (*capsules.Container, error) {
+	for i := range capsule.Containers {
+		if capsule.Containers[i].Name == name {
+			return &capsule.Containers[i], nil
+		}
+	}
+	if len(capsule.Containers) == 1 {
+		return &capsule.Containers[0], nil
+	}
+	return nil, fmt.Errorf("container %s not found in capsule %s", name, capsule.MetaName)
+}
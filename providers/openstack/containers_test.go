@@ -0,0 +1,71 @@
+package openstack
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// newTestClient returns a Zun client sending its requests to the handler.
+func newTestClient(t *testing.T, handler http.Handler) (*gophercloud.ServiceClient, func()) {
+	server := httptest.NewServer(handler)
+	client := &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       server.URL + "/",
+	}
+	return client, server.Close
+}
+
+func TestContainerLogs(t *testing.T) {
+	var queries []string
+	client, cleanup := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, is.Equal(r.Method, http.MethodGet))
+		assert.Check(t, is.Equal(r.URL.Path, "/containers/container-1/logs"))
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("tail") == "all" {
+			// Plain text logs are returned as is.
+			fmt.Fprint(w, "line 1\nline 2\n")
+			return
+		}
+		fmt.Fprint(w, `"line 2\n"`)
+	}))
+	defer cleanup()
+
+	logs, err := containerLogs(client, "container-1", containerLogsOpts{Tail: 1})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(logs, "line 2\n"))
+
+	logs, err = containerLogs(client, "container-1", containerLogsOpts{})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(logs, "line 1\nline 2\n"))
+
+	assert.Check(t, is.DeepEqual(queries, []string{
+		"stderr=true&stdout=true&tail=1&timestamps=false",
+		"stderr=true&stdout=true&tail=all&timestamps=false",
+	}))
+}
+
+func TestParseIOPair(t *testing.T) {
+	for _, c := range []struct {
+		s       string
+		in, out uint64
+		ok      bool
+	}{
+		{s: "1024/2048", in: 1024, out: 2048, ok: true},
+		{s: " 0 / 7 ", in: 0, out: 7, ok: true},
+		{s: "1024"},
+		{s: "1/2/3"},
+		{s: "1kB/2kB"},
+		{s: ""},
+	} {
+		in, out, ok := parseIOPair(c.s)
+		assert.Check(t, is.Equal(ok, c.ok), c.s)
+		assert.Check(t, is.Equal(in, c.in), c.s)
+		assert.Check(t, is.Equal(out, c.out), c.s)
+	}
+}
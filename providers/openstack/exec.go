@@ -0,0 +1,121 @@
+package openstack
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/container/v1/capsules"
+	"github.com/gorilla/websocket"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/wsexec"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/utils/exec"
+)
+
+// ExecInContainer executes a command in a container in the pod, copying data
+// between in/out/err and the container's stdin/stdout/stderr.
+//
+// Commands without stdin or tty are run to completion by Zun, which returns
+// their output and exit code; a non-zero exit code is returned as an
+// exec.ExitError.
+// Other commands are attached to the websocket returned by Zun, which carries
+// their input and output. Terminal resize events are sent to Zun. On stdin
+// EOF, EOT is sent to commands attached to a terminal, and the session is
+// left open until Zun closes it once the command has exited.
+func (p *ZunProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errstream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
+	// Cleanup on exit
+	if out != nil {
+		defer out.Close()
+	}
+	if errstream != nil {
+		defer errstream.Close()
+	}
+
+	if len(cmd) == 0 {
+		return fmt.Errorf("no command specified")
+	}
+
+	capsule, err := capsules.Get(p.ZunClient, name).ExtractV132()
+	if err != nil {
+		return err
+	}
+	c, err := capsuleContainer(capsule, container)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = ioutil.Discard
+	if out != nil {
+		w = out
+	}
+
+	interactive := in != nil || tty
+	result, err := containerExecute(p.ZunClient, c.UUID, containerExecuteOpts{Command: cmd, Interactive: interactive})
+	if err != nil {
+		return fmt.Errorf("error executing command in container %s: %v", container, err)
+	}
+
+	if !interactive {
+		if _, err := io.WriteString(w, result.Output); err != nil {
+			return err
+		}
+		if result.ExitCode != 0 {
+			return utilexec.CodeExitError{
+				Err:  fmt.Errorf("command %q exited with code %d", strings.Join(cmd, " "), result.ExitCode),
+				Code: result.ExitCode,
+			}
+		}
+		return nil
+	}
+
+	conn, resp, err := websocket.DefaultDialer.Dial(result.URL, nil)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("error connecting to exec session for container %s: %s: %v", container, resp.Status, err)
+		}
+		return fmt.Errorf("error connecting to exec session for container %s: %v", container, err)
+	}
+	defer conn.Close()
+
+	session := wsexec.NewSession(conn)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	if in != nil {
+		// Only a terminal turns EOT into EOF for the command, the session
+		// can't be half-closed.
+		var eof []byte
+		if tty {
+			eof = wsexec.EOT
+		}
+		go session.CopyStdin(in, eof)
+	}
+	if resize != nil {
+		go p.forwardResize(c.UUID, result.ExecID, resize, done)
+	}
+
+	return session.CopyOutput(w)
+}
+
+// forwardResize sends the terminal size changes to Zun until the resize
+// channel is closed or the session is done.
+func (p *ZunProvider) forwardResize(id, execID string, resize <-chan remotecommand.TerminalSize, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case size, ok := <-resize:
+			if !ok {
+				return
+			}
+			if err := containerExecuteResize(p.ZunClient, id, execID, size.Height, size.Width); err != nil {
+				log.Printf("error resizing the terminal of exec %s: %v", execID, err)
+			}
+		}
+	}
+}
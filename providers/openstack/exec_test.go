@@ -0,0 +1,62 @@
+package openstack
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	utilexec "k8s.io/utils/exec"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (*bufferCloser) Close() error {
+	return nil
+}
+
+func TestExecInContainerRunToCompletion(t *testing.T) {
+	var commands []string
+	p := &ZunProvider{}
+	var cleanup func()
+	p.ZunClient, cleanup = newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/capsules/ns-pod":
+			fmt.Fprint(w, `{"uuid": "capsule-1", "name": "ns-pod", "containers": [{"uuid": "container-1", "name": "app"}]}`)
+		case "/containers/container-1/execute":
+			assert.Check(t, is.Equal(r.Method, http.MethodPost))
+			assert.Check(t, is.Equal(r.URL.Query().Get("run"), "true"))
+			assert.Check(t, is.Equal(r.URL.Query().Get("interactive"), "false"))
+			command := r.URL.Query().Get("command")
+			commands = append(commands, command)
+			if command == "false" {
+				fmt.Fprint(w, `{"output": "failed\n", "exit_code": 1}`)
+				return
+			}
+			fmt.Fprint(w, `{"output": "hello world\n", "exit_code": 0}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer cleanup()
+
+	var out bufferCloser
+	err := p.ExecInContainer("ns-pod", "", "app", []string{"echo", "hello world"}, nil, &out, nil, false, nil, 0)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(out.String(), "hello world\n"))
+
+	// Non-zero exit codes are returned with the output.
+	out.Reset()
+	err = p.ExecInContainer("ns-pod", "", "app", []string{"false"}, nil, &out, nil, false, nil, 0)
+	exitErr, ok := err.(utilexec.CodeExitError)
+	assert.Assert(t, ok, "an exit error is expected, got %v", err)
+	assert.Check(t, is.Equal(exitErr.ExitStatus(), 1))
+	assert.Check(t, is.Equal(out.String(), "failed\n"))
+
+	assert.Check(t, is.DeepEqual(commands, []string{"echo 'hello world'", "false"}))
+}
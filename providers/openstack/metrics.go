@@ -0,0 +1,111 @@
+package openstack
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/container/v1/capsules"
+	"github.com/gophercloud/gophercloud/pagination"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+// GetStatsSummary returns the stats summary for pods running on Zun, from
+// the statistics of the running containers of their capsules.
+//
+// Zun returns the statistics of a single container per call, the summary is
+// cached so that frequent scrapes don't add calls to the Zun API.
+func (p *ZunProvider) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
+	return p.metrics.Get(ctx, p.statsSummary)
+}
+
+func (p *ZunProvider) statsSummary(ctx context.Context) (*stats.Summary, error) {
+	s := &stats.Summary{
+		Node: stats.NodeStats{NodeName: p.nodeName},
+	}
+
+	err := capsules.List(p.ZunClient, nil).EachPage(func(page pagination.Page) (bool, error) {
+		capsuleList, err := capsules.ExtractCapsulesV132(page)
+		if err != nil {
+			return false, err
+		}
+
+		for i := range capsuleList {
+			capsule := &capsuleList[i]
+			if capsule.MetaLabels["NodeName"] != p.nodeName || capsule.Status != "Running" {
+				continue
+			}
+			s.Pods = append(s.Pods, p.capsuleStats(capsule))
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// capsuleStats returns the stats of a capsule, the sum of those of its
+// running containers. Containers whose stats can't be read are skipped.
+func (p *ZunProvider) capsuleStats(capsule *capsules.CapsuleV132) stats.PodStats {
+	now := metav1.NewTime(time.Now())
+	var (
+		podNanoCores, podMemory uint64
+		rxBytes, txBytes        uint64
+	)
+
+	stat := stats.PodStats{
+		PodRef: stats.PodReference{
+			Name:      capsule.MetaLabels["PodName"],
+			Namespace: capsule.MetaLabels["Namespace"],
+			UID:       capsule.UUID,
+		},
+		StartTime: metav1.NewTime(capsule.CreatedAt),
+	}
+
+	for _, c := range capsule.Containers {
+		if c.Status != "Running" {
+			continue
+		}
+		cs, err := getContainerStats(p.ZunClient, c.UUID)
+		if err != nil {
+			log.Printf("error getting the stats of container %s: %v", c.UUID, err)
+			continue
+		}
+
+		// Zun reports the CPU usage as a percentage of one core.
+		nanoCores := uint64(cs.CPUPercent / 100 * 1e9)
+		memory := uint64(cs.MemoryUsageMB * 1024 * 1024)
+		podNanoCores += nanoCores
+		podMemory += memory
+		if rx, tx, ok := parseIOPair(cs.NetIO); ok {
+			rxBytes += rx
+			txBytes += tx
+		}
+
+		stat.Containers = append(stat.Containers, stats.ContainerStats{
+			Name:      c.Name,
+			StartTime: metav1.NewTime(c.StartedAt),
+			CPU:       &stats.CPUStats{Time: now, UsageNanoCores: uint64Ptr(nanoCores)},
+			Memory:    &stats.MemoryStats{Time: now, UsageBytes: uint64Ptr(memory), WorkingSetBytes: uint64Ptr(memory)},
+		})
+	}
+
+	stat.CPU = &stats.CPUStats{Time: now, UsageNanoCores: uint64Ptr(podNanoCores)}
+	stat.Memory = &stats.MemoryStats{Time: now, UsageBytes: uint64Ptr(podMemory), WorkingSetBytes: uint64Ptr(podMemory)}
+	stat.Network = &stats.NetworkStats{
+		Time: now,
+		InterfaceStats: stats.InterfaceStats{
+			Name:    "eth0",
+			RxBytes: uint64Ptr(rxBytes),
+			TxBytes: uint64Ptr(txBytes),
+		},
+	}
+	return stat
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
@@ -0,0 +1,59 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestGetStatsSummaryCache(t *testing.T) {
+	statsRequests := 0
+	p := &ZunProvider{nodeName: "vk"}
+	var cleanup func()
+	p.ZunClient, cleanup = newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/capsules":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"capsules": [
+				{"uuid": "capsule-1", "status": "Running", "labels": {"NodeName": "vk", "PodName": "pod", "Namespace": "ns"},
+				 "containers": [{"uuid": "container-1", "name": "app", "status": "Running"}]},
+				{"uuid": "capsule-2", "status": "Running", "labels": {"NodeName": "other"}}
+			]}`)
+		case "/containers/container-1/stats":
+			statsRequests++
+			fmt.Fprint(w, `{"CPU %": 50, "MEM USAGE(MiB)": 2, "NET I/O(B)": "100/200"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer cleanup()
+
+	summary, err := p.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(summary.Pods, 1))
+	pod := summary.Pods[0]
+	assert.Check(t, is.Equal(pod.PodRef.Name, "pod"))
+	assert.Check(t, is.Equal(*pod.CPU.UsageNanoCores, uint64(5e8)))
+	assert.Check(t, is.Equal(*pod.Memory.UsageBytes, uint64(2*1024*1024)))
+	assert.Check(t, is.Equal(*pod.Network.RxBytes, uint64(100)))
+	assert.Check(t, is.Equal(*pod.Network.TxBytes, uint64(200)))
+	assert.Check(t, is.Equal(statsRequests, 1))
+
+	// The summary is served from the cache.
+	cached, err := p.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, cached == summary)
+	assert.Check(t, is.Equal(statsRequests, 1))
+
+	// The stats are requested again once the cache expired.
+	p.metrics.MaxAge = time.Nanosecond
+	_, err = p.GetStatsSummary(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(statsRequests, 2))
+}
@@ -8,9 +8,9 @@ type CapsuleSpec struct {
 }
 
 type CapsuleTemplate struct {
-	Spec       CapsuleSpec `json:"spec,omitempty"`
-	Kind       string      `json:"kind,omitempty"`
-	Metadata   Metadata    `json:"metadata,omitempty"`
+	Spec     CapsuleSpec `json:"spec,omitempty"`
+	Kind     string      `json:"kind,omitempty"`
+	Metadata Metadata    `json:"metadata,omitempty"`
 }
 
 type Metadata struct {
@@ -19,22 +19,41 @@ type Metadata struct {
 }
 
 type Volume struct {
-	Name string `json:"name,omitempty"`
+	Name   string        `json:"name,omitempty"`
+	Cinder *CinderVolume `json:"cinder,omitempty"`
+	// Files is the content of an inline volume, keyed by the path of the
+	// files relative to the mount path. It holds the data of secrets and
+	// config maps.
+	Files map[string][]byte `json:"files,omitempty"`
+}
+
+// CinderVolume is a Cinder volume attached to a capsule, either an existing
+// volume or a new one of the given size in GiB.
+type CinderVolume struct {
+	VolumeID   string `json:"volumeID,omitempty"`
+	Size       int    `json:"size,omitempty"`
+	AutoRemove bool   `json:"autoRemove,omitempty"`
+}
+
+// VolumeMount mounts a capsule volume in a container.
+type VolumeMount struct {
+	Name      string `json:"name,omitempty"`
+	MountPath string `json:"mountPath,omitempty"`
 }
 
 type Container struct {
 	//	Name    string `json:"name" protobuf:"bytes,1,opt,name=name"`
-	Image      string   `json:"image,omitempty" protobuf:"bytes,2,opt,name=image"`
-	Command    []string `json:"command,omitempty" protobuf:"bytes,3,rep,name=command"`
-	Args       []string `json:"args,omitempty" protobuf:"bytes,4,rep,name=args"`
-	WorkingDir string   `json:"workDir,omitempty" protobuf:"bytes,5,opt,name=workingDir"`
-	//	Ports   []ContainerPort `json:"ports,omitempty" patchStrategy:"merge" patchMergeKey:"containerPort" protobuf:"bytes,6,rep,name=ports"`
-	Env map[string]string `json:"env,omitempty"`
+	Image      string            `json:"image,omitempty" protobuf:"bytes,2,opt,name=image"`
+	Command    []string          `json:"command,omitempty" protobuf:"bytes,3,rep,name=command"`
+	Args       []string          `json:"args,omitempty" protobuf:"bytes,4,rep,name=args"`
+	WorkingDir string            `json:"workDir,omitempty" protobuf:"bytes,5,opt,name=workingDir"`
+	Ports      []ContainerPort   `json:"ports,omitempty" patchStrategy:"merge" patchMergeKey:"containerPort" protobuf:"bytes,6,rep,name=ports"`
+	Env        map[string]string `json:"env,omitempty"`
 	//ENV is different with Kubernetes
 	//	Env     []EnvVar `json:"env,omitempty" patchStrategy:"merge" patchMergeKey:"name" protobuf:"bytes,6,rep,name=env"`
-	Resources ResourceRequirements `json:"resources,omitempty" protobuf:"bytes,7,opt,name=resources"`
-	//	VolumeMounts []VolumeMount `json:"volumeMounts,omitempty" patchStrategy:"merge" patchMergeKey:"mountPath" protobuf:"bytes,8,rep,name=volumeMounts"`
-	ImagePullPolicy string `json:"imagePullPolicy,omitempty" protobuf:"bytes,8,opt,name=imagePullPolicy"`
+	Resources       ResourceRequirements `json:"resources,omitempty" protobuf:"bytes,7,opt,name=resources"`
+	VolumeMounts    []VolumeMount        `json:"volumeMounts,omitempty" patchStrategy:"merge" patchMergeKey:"mountPath" protobuf:"bytes,8,rep,name=volumeMounts"`
+	ImagePullPolicy string               `json:"imagePullPolicy,omitempty" protobuf:"bytes,8,opt,name=imagePullPolicy"`
 
 	//	Stdin bool `json:"stdin,omitempty" protobuf:"varint,16,opt,name=stdin"`
 
@@ -49,13 +68,12 @@ type Container struct {
 //}
 
 // ContainerPort represents a network port in a single container.
-//type ContainerPort struct {
-//	Name string `json:"name,omitempty" protobuf:"bytes,1,opt,name=name"`
-//	HostPort int32 `json:"hostPort,omitempty" protobuf:"varint,2,opt,name=hostPort"`
-//	ContainerPort int32 `json:"containerPort" protobuf:"varint,3,opt,name=containerPort"`
-//	Protocol Protocol `json:"protocol,omitempty" protobuf:"bytes,4,opt,name=protocol,casttype=Protocol"`
-//	HostIP string `json:"hostIP,omitempty" protobuf:"bytes,5,opt,name=hostIP"`
-//}
+type ContainerPort struct {
+	Name          string `json:"name,omitempty" protobuf:"bytes,1,opt,name=name"`
+	HostPort      int32  `json:"hostPort,omitempty" protobuf:"varint,2,opt,name=hostPort"`
+	ContainerPort int32  `json:"containerPort" protobuf:"varint,3,opt,name=containerPort"`
+	Protocol      string `json:"protocol,omitempty" protobuf:"bytes,4,opt,name=protocol,casttype=Protocol"`
+}
 
 type ResourceName string
 type ResourceList map[ResourceName]float64
@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"time"
 
@@ -16,11 +16,12 @@ import (
 	"github.com/gophercloud/gophercloud/pagination"
 	"github.com/virtual-kubelet/virtual-kubelet/manager"
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/internal/statscache"
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/remotecommand"
 )
 
 // ZunProvider implements the virtual-kubelet provider interface and communicates with OpenStack's Zun APIs.
@@ -34,6 +35,8 @@ type ZunProvider struct {
 	memory             string
 	pods               string
 	daemonEndpointPort int32
+
+	metrics statscache.Cache
 }
 
 // NewZunProvider creates a new ZunProvider.
@@ -146,6 +149,9 @@ func (p *ZunProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 		"CreationTimestamp": podCreationTimestamp,
 	}
 	metadata.Name = pod.Namespace + "-" + pod.Name
+	if err := setSpecLabels(metadata.Labels, pod); err != nil {
+		return err
+	}
 	capsuleTemplate.Metadata = metadata
 	// get containers
 	containers, err := p.getContainers(ctx, pod)
@@ -153,6 +159,13 @@ func (p *ZunProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 		return err
 	}
 	capsuleTemplate.Spec.Containers = containers
+	// get volumes
+	volumes, err := p.getVolumes(ctx, pod)
+	if err != nil {
+		return err
+	}
+	capsuleTemplate.Spec.Volumes = volumes
+	capsuleTemplate.Spec.RestartPolicy = string(pod.Spec.RestartPolicy)
 	data, err := json.MarshalIndent(capsuleTemplate, "", "  ")
 	if err != nil {
 		return err
@@ -182,43 +195,310 @@ func (p *ZunProvider) getContainers(ctx context.Context, pod *v1.Pod) ([]Contain
 
 		// Container ENV need to sync with K8s in Zun and gophercloud. Will change them.
 		// From map[string]string to []map[string]string
-		c.Env = map[string]string{}
-		for _, e := range container.Env {
-			c.Env[e.Name] = e.Value
+		env, err := p.getEnv(pod, &container)
+		if err != nil {
+			return nil, err
 		}
+		c.Env = env
 
-		if container.Resources.Limits != nil {
+		// Zun takes the limits as the requests of the container, the
+		// requests are used when no limits are set.
+		resources := container.Resources.Limits
+		if resources == nil {
+			resources = container.Resources.Requests
+		}
+		if resources != nil {
 			cpuLimit := float64(1)
-			if _, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
-				cpuLimit = float64(container.Resources.Limits.Cpu().MilliValue()) / 1000.00
+			if _, ok := resources[v1.ResourceCPU]; ok {
+				cpuLimit = float64(resources.Cpu().MilliValue()) / 1000.00
 			}
 
-			memoryLimit := 0.5
-			if _, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
-				memoryLimit = float64(container.Resources.Limits.Memory().Value()) / 1000000000.00
+			// Zun takes the memory in MiB.
+			memoryLimit := float64(512)
+			if _, ok := resources[v1.ResourceMemory]; ok {
+				memoryLimit = float64(resources.Memory().Value()) / (1024 * 1024)
 			}
 
-			c.Resources.Limits["cpu"] = cpuLimit
-			c.Resources.Limits["memory"] = memoryLimit * 1024
+			c.Resources.Limits = ResourceList{
+				"cpu":    cpuLimit,
+				"memory": memoryLimit,
+			}
 		}
 
-		//TODO: Add Sync with Resource requirement
+		for _, port := range container.Ports {
+			c.Ports = append(c.Ports, ContainerPort{
+				Name:          port.Name,
+				HostPort:      port.HostPort,
+				ContainerPort: port.ContainerPort,
+				Protocol:      string(port.Protocol),
+			})
+		}
 
-		//TODO: Add port Sync
+		for _, mount := range container.VolumeMounts {
+			c.VolumeMounts = append(c.VolumeMounts, VolumeMount{
+				Name:      mount.Name,
+				MountPath: mount.MountPath,
+			})
+		}
 
-		//TODO: Add volume support
 		containers = append(containers, c)
 	}
 	return containers, nil
 }
 
-// ExecInContainer executes a command in a container in the pod, copying data
-// between in/out/err and the container's stdin/stdout/stderr.
-func (p *ZunProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
-	log.Printf("receive ExecInContainer %q\n", container)
+// Labels of the capsules recording the parts of the pod spec which Zun does
+// not return: the volumes of the pod and, for each container, the environment
+// as declared in the pod and the volume mounts.
+const (
+	volumesLabel    = "Volumes"
+	containersLabel = "Containers"
+)
+
+// containerSpec is the part of the spec of a container recorded in the
+// containers label of a capsule. The provider resolves the references to
+// secrets and config maps of the environment itself, so the label records the
+// references and not the values of secrets.
+type containerSpec struct {
+	Env          []v1.EnvVar        `json:"env,omitempty"`
+	EnvFrom      []v1.EnvFromSource `json:"envFrom,omitempty"`
+	VolumeMounts []v1.VolumeMount   `json:"volumeMounts,omitempty"`
+}
+
+// setSpecLabels records the volumes of a pod and the environment and volume
+// mounts of its containers in the labels of its capsule.
+func setSpecLabels(labels map[string]string, pod *v1.Pod) error {
+	volumes, err := json.Marshal(pod.Spec.Volumes)
+	if err != nil {
+		return err
+	}
+	labels[volumesLabel] = string(volumes)
+
+	specs := make([]containerSpec, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		specs = append(specs, containerSpec{
+			Env:          c.Env,
+			EnvFrom:      c.EnvFrom,
+			VolumeMounts: c.VolumeMounts,
+		})
+	}
+	containers, err := json.Marshal(specs)
+	if err != nil {
+		return err
+	}
+	labels[containersLabel] = string(containers)
 	return nil
 }
 
+// specLabels returns the volumes and the container specs recorded in the
+// labels of a capsule, or nil if the capsule has no such labels.
+func specLabels(capsule *capsules.CapsuleV132) ([]v1.Volume, []containerSpec, error) {
+	var volumes []v1.Volume
+	if l, ok := capsule.MetaLabels[volumesLabel]; ok {
+		if err := json.Unmarshal([]byte(l), &volumes); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s label of capsule %s: %v", volumesLabel, capsule.MetaName, err)
+		}
+	}
+
+	var specs []containerSpec
+	if l, ok := capsule.MetaLabels[containersLabel]; ok {
+		if err := json.Unmarshal([]byte(l), &specs); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s label of capsule %s: %v", containersLabel, capsule.MetaName, err)
+		}
+	}
+	return volumes, specs, nil
+}
+
+// ResolvesEnvironmentReferences implements providers.PodEnvironmentResolver.
+// The environment variables set from secrets and config maps are passed to
+// CreatePod as references and only resolved by getEnv for the capsule
+// template.
+func (p *ZunProvider) ResolvesEnvironmentReferences() bool {
+	return true
+}
+
+// getEnv returns the environment of a container, with the values of the
+// variables referencing secrets and config maps.
+func (p *ZunProvider) getEnv(pod *v1.Pod, container *v1.Container) (map[string]string, error) {
+	env := map[string]string{}
+
+	for _, envFrom := range container.EnvFrom {
+		switch {
+		case envFrom.ConfigMapRef != nil:
+			configMap, err := p.getConfigMap(envFrom.ConfigMapRef.Name, pod.Namespace, envFrom.ConfigMapRef.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if configMap == nil {
+				continue
+			}
+			for k, v := range configMap.Data {
+				env[envFrom.Prefix+k] = v
+			}
+		case envFrom.SecretRef != nil:
+			secret, err := p.getSecret(envFrom.SecretRef.Name, pod.Namespace, envFrom.SecretRef.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if secret == nil {
+				continue
+			}
+			for k, v := range secret.Data {
+				env[envFrom.Prefix+k] = string(v)
+			}
+		}
+	}
+
+	for _, e := range container.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+			continue
+		}
+
+		switch {
+		case e.ValueFrom.ConfigMapKeyRef != nil:
+			ref := e.ValueFrom.ConfigMapKeyRef
+			configMap, err := p.getConfigMap(ref.Name, pod.Namespace, ref.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if configMap == nil {
+				continue
+			}
+			v, ok := configMap.Data[ref.Key]
+			if !ok {
+				if ref.Optional != nil && *ref.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("key %s not found in config map %s/%s", ref.Key, pod.Namespace, ref.Name)
+			}
+			env[e.Name] = v
+		case e.ValueFrom.SecretKeyRef != nil:
+			ref := e.ValueFrom.SecretKeyRef
+			secret, err := p.getSecret(ref.Name, pod.Namespace, ref.Optional)
+			if err != nil {
+				return nil, err
+			}
+			if secret == nil {
+				continue
+			}
+			v, ok := secret.Data[ref.Key]
+			if !ok {
+				if ref.Optional != nil && *ref.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("key %s not found in secret %s/%s", ref.Key, pod.Namespace, ref.Name)
+			}
+			env[e.Name] = string(v)
+		default:
+			log.Printf("skipping environment variable %s of container %s: unsupported value source", e.Name, container.Name)
+		}
+	}
+
+	return env, nil
+}
+
+// getVolumes returns the capsule volumes of a pod. Cinder volumes are
+// attached to the capsule, and the content of secrets and config maps is
+// inlined in the template.
+func (p *ZunProvider) getVolumes(ctx context.Context, pod *v1.Pod) ([]Volume, error) {
+	volumes := make([]Volume, 0, len(pod.Spec.Volumes))
+	for _, v := range pod.Spec.Volumes {
+		volume := Volume{Name: v.Name}
+
+		switch {
+		case v.Cinder != nil:
+			volume.Cinder = &CinderVolume{VolumeID: v.Cinder.VolumeID}
+		case v.Secret != nil:
+			secret, err := p.getSecret(v.Secret.SecretName, pod.Namespace, v.Secret.Optional)
+			if err != nil {
+				return nil, err
+			}
+			var data map[string][]byte
+			if secret != nil {
+				data = secret.Data
+			}
+			volume.Files, err = volumeFiles(data, v.Secret.Items, v.Secret.Optional)
+			if err != nil {
+				return nil, fmt.Errorf("volume %s: %v", v.Name, err)
+			}
+		case v.ConfigMap != nil:
+			configMap, err := p.getConfigMap(v.ConfigMap.Name, pod.Namespace, v.ConfigMap.Optional)
+			if err != nil {
+				return nil, err
+			}
+			var data map[string][]byte
+			if configMap != nil {
+				data = make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+				for k, v := range configMap.Data {
+					data[k] = []byte(v)
+				}
+				for k, v := range configMap.BinaryData {
+					data[k] = v
+				}
+			}
+			volume.Files, err = volumeFiles(data, v.ConfigMap.Items, v.ConfigMap.Optional)
+			if err != nil {
+				return nil, fmt.Errorf("volume %s: %v", v.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("volume %s: only cinder, secret and config map volumes are supported", v.Name)
+		}
+
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+// volumeFiles returns the files of a secret or config map volume, all keys of
+// data or only the given items.
+func volumeFiles(data map[string][]byte, items []v1.KeyToPath, optional *bool) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(data))
+	if len(items) == 0 {
+		for k, v := range data {
+			files[k] = v
+		}
+		return files, nil
+	}
+
+	for _, item := range items {
+		v, ok := data[item.Key]
+		if !ok {
+			if optional != nil && *optional {
+				continue
+			}
+			return nil, fmt.Errorf("key %s not found", item.Key)
+		}
+		files[item.Path] = v
+	}
+	return files, nil
+}
+
+// getSecret returns a secret of the namespace of a pod, or nil if it is
+// optional and does not exist.
+func (p *ZunProvider) getSecret(name, namespace string, optional *bool) (*v1.Secret, error) {
+	secret, err := p.resourceManager.GetSecret(name, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) && optional != nil && *optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting secret %s/%s: %v", namespace, name, err)
+	}
+	return secret, nil
+}
+
+// getConfigMap returns a config map of the namespace of a pod, or nil if it
+// is optional and does not exist.
+func (p *ZunProvider) getConfigMap(name, namespace string, optional *bool) (*v1.ConfigMap, error) {
+	configMap, err := p.resourceManager.GetConfigMap(name, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) && optional != nil && *optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting config map %s/%s: %v", namespace, name, err)
+	}
+	return configMap, nil
+}
+
 // GetPodStatus returns the status of a pod by name that is running inside Zun
 // returns nil if a pod by that name is not found.
 func (p *ZunProvider) GetPodStatus(ctx context.Context, namespace, name string) (*v1.PodStatus, error) {
@@ -234,8 +514,18 @@ func (p *ZunProvider) GetPodStatus(ctx context.Context, namespace, name string)
 	return &pod.Status, nil
 }
 
+// GetContainerLogs returns the last tail lines of the logs of a container
+// running inside Zun, or all of them if tail is not positive.
 func (p *ZunProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
-	return "not support in Zun Provider", nil
+	capsule, err := capsules.Get(p.ZunClient, fmt.Sprintf("%s-%s", namespace, podName)).ExtractV132()
+	if err != nil {
+		return "", err
+	}
+	c, err := capsuleContainer(capsule, containerName)
+	if err != nil {
+		return "", err
+	}
+	return containerLogs(p.ZunClient, c.UUID, containerLogsOpts{Tail: tail})
 }
 
 // NodeConditions returns a list of conditions (Ready, OutOfDisk, etc), for updates to the node status
@@ -317,10 +607,23 @@ func capsuleToPod(capsule *capsules.CapsuleV132) (*v1.Pod, error) {
 		containerStartTime = metav1.NewTime(capsule.Containers[0].StartedAt)
 	}
 	containerStartTime = metav1.NewTime(time.Time{})
+
+	volumes, specs, err := specLabels(capsule)
+	if err != nil {
+		return nil, err
+	}
+	if volumes == nil {
+		volumes = []v1.Volume{}
+	}
+	// Zun keeps the containers of a capsule in the order of its template.
+	if len(specs) != len(capsule.Containers) {
+		specs = nil
+	}
+
 	// Deal with container inside capsule
 	containers := make([]v1.Container, 0, len(capsule.Containers))
 	containerStatuses := make([]v1.ContainerStatus, 0, len(capsule.Containers))
-	for _, c := range capsule.Containers {
+	for i, c := range capsule.Containers {
 		containerMemoryMB := 0
 		if c.Memory != "" {
 			containerMemory, err := strconv.Atoi(c.Memory)
@@ -329,22 +632,47 @@ func capsuleToPod(capsule *capsules.CapsuleV132) (*v1.Pod, error) {
 			}
 			containerMemoryMB = containerMemory
 		}
+		limits := v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%g", float64(c.CPU))),
+			v1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dMi", containerMemoryMB)),
+		}
 		container := v1.Container{
-			Name:    c.Name,
-			Image:   c.Image,
-			Command: c.Command,
+			Name:            c.Name,
+			Image:           c.Image,
+			Command:         c.Command,
+			WorkingDir:      c.WorkDir,
+			ImagePullPolicy: v1.PullPolicy(c.ImagePullPolicy),
 			Resources: v1.ResourceRequirements{
-				Limits: v1.ResourceList{
-					v1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%g", float64(c.CPU))),
-					v1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dM", containerMemoryMB)),
-				},
-
-				Requests: v1.ResourceList{
-					v1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%g", float64(c.CPU*1024/100))),
-					v1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dM", containerMemoryMB)),
-				},
+				Limits: limits,
+				// Zun sets the limits as the requests of the container.
+				Requests: limits.DeepCopy(),
 			},
 		}
+
+		if specs != nil {
+			container.Env = specs[i].Env
+			container.EnvFrom = specs[i].EnvFrom
+			container.VolumeMounts = specs[i].VolumeMounts
+		} else {
+			// The capsule was created without the containers label, its
+			// environment only holds the values of the pod.
+			envNames := make([]string, 0, len(c.Environment))
+			for name := range c.Environment {
+				envNames = append(envNames, name)
+			}
+			sort.Strings(envNames)
+			for _, name := range envNames {
+				container.Env = append(container.Env, v1.EnvVar{Name: name, Value: c.Environment[name]})
+			}
+		}
+
+		for _, port := range c.Ports {
+			container.Ports = append(container.Ports, v1.ContainerPort{
+				ContainerPort: int32(port),
+				Protocol:      v1.ProtocolTCP,
+			})
+		}
+
 		containers = append(containers, container)
 		containerStatus := v1.ContainerStatus{
 			Name:                 c.Name,
@@ -385,7 +713,7 @@ func capsuleToPod(capsule *capsules.CapsuleV132) (*v1.Pod, error) {
 		},
 		Spec: v1.PodSpec{
 			NodeName:   capsule.MetaLabels["NodeName"],
-			Volumes:    []v1.Volume{},
+			Volumes:    volumes,
 			Containers: containers,
 		},
 
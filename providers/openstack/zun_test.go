@@ -0,0 +1,223 @@
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/container/v1/capsules"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	testutil "github.com/virtual-kubelet/virtual-kubelet/test/util"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"k8s.io/api/core/v1"
+)
+
+func newTestProvider() *ZunProvider {
+	return &ZunProvider{
+		resourceManager: testutil.FakeResourceManager(
+			testutil.FakeSecret("ns", "secret", map[string]string{"password": "s3cr3t"}),
+			testutil.FakeConfigMap("ns", "config", map[string]string{"level": "debug", "mode": "fast"}),
+		),
+		nodeName: "vk",
+	}
+}
+
+func TestGetEnv(t *testing.T) {
+	p := newTestProvider()
+	optional := true
+	container := &v1.Container{
+		Name: "app",
+		EnvFrom: []v1.EnvFromSource{
+			{Prefix: "CONFIG_", ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}},
+			{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "missing"}, Optional: &optional}},
+		},
+		Env: []v1.EnvVar{
+			{Name: "PLAIN", Value: "value"},
+			{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "secret"}, Key: "password"}}},
+			{Name: "LEVEL", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "config"}, Key: "level"}}},
+			{Name: "OPTIONAL", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "config"}, Key: "missing", Optional: &optional}}},
+		},
+	}
+
+	env, err := p.getEnv(testutil.FakePodWithSingleContainer("ns", "pod", "image"), container)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(env, map[string]string{
+		"CONFIG_level": "debug",
+		"CONFIG_mode":  "fast",
+		"PLAIN":        "value",
+		"PASSWORD":     "s3cr3t",
+		"LEVEL":        "debug",
+	}))
+
+	// Missing keys which are not optional are errors.
+	container.Env = []v1.EnvVar{
+		{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "secret"}, Key: "missing"}}},
+	}
+	_, err = p.getEnv(testutil.FakePodWithSingleContainer("ns", "pod", "image"), container)
+	assert.Check(t, is.ErrorContains(err, "key missing not found in secret ns/secret"))
+}
+
+// TestCreatePodEnvironmentReferences creates a pod whose environment
+// references secrets, as passed to CreatePod by virtual-kubelet when the
+// provider resolves them: the capsule gets the values and its labels the
+// references.
+func TestCreatePodEnvironmentReferences(t *testing.T) {
+	p := newTestProvider()
+	var resolver providers.PodEnvironmentResolver = p
+	assert.Check(t, resolver.ResolvesEnvironmentReferences())
+
+	var template CapsuleTemplate
+	var cleanup func()
+	p.ZunClient, cleanup = newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, is.Equal(r.Method, http.MethodPost))
+		assert.Check(t, is.Equal(r.URL.Path, "/capsules"))
+		var body struct {
+			Template string `json:"template"`
+		}
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Check(t, json.Unmarshal([]byte(body.Template), &template))
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"uuid": "capsule-1", "name": "ns-pod"}`)
+	}))
+	defer cleanup()
+
+	pod := testutil.FakePodWithSingleContainer("ns", "pod", "image")
+	container := &pod.Spec.Containers[0]
+	container.EnvFrom = []v1.EnvFromSource{
+		{Prefix: "CONFIG_", ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}},
+	}
+	container.Env = []v1.EnvVar{
+		{Name: "NODE", Value: "vk"},
+		{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "secret"}, Key: "password"}}},
+	}
+	assert.NilError(t, p.CreatePod(context.Background(), pod))
+
+	assert.Assert(t, is.Len(template.Spec.Containers, 1))
+	assert.Check(t, is.DeepEqual(template.Spec.Containers[0].Env, map[string]string{
+		"CONFIG_level": "debug",
+		"CONFIG_mode":  "fast",
+		"NODE":         "vk",
+		"PASSWORD":     "s3cr3t",
+	}))
+
+	label := template.Metadata.Labels[containersLabel]
+	assert.Check(t, !strings.Contains(label, "s3cr3t"), label)
+	var specs []containerSpec
+	assert.NilError(t, json.Unmarshal([]byte(label), &specs))
+	assert.Assert(t, is.Len(specs, 1))
+	assert.Check(t, is.DeepEqual(specs[0].Env, container.Env))
+	assert.Check(t, is.DeepEqual(specs[0].EnvFrom, container.EnvFrom))
+}
+
+func TestGetVolumes(t *testing.T) {
+	p := newTestProvider()
+	pod := testutil.FakePodWithSingleContainer("ns", "pod", "image")
+	pod.Spec.Volumes = []v1.Volume{
+		{Name: "data", VolumeSource: v1.VolumeSource{Cinder: &v1.CinderVolumeSource{VolumeID: "cinder-1"}}},
+		{Name: "secret", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "secret"}}},
+		{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+			LocalObjectReference: v1.LocalObjectReference{Name: "config"},
+			Items:                []v1.KeyToPath{{Key: "level", Path: "conf/level"}},
+		}}},
+	}
+
+	volumes, err := p.getVolumes(context.Background(), pod)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(volumes, []Volume{
+		{Name: "data", Cinder: &CinderVolume{VolumeID: "cinder-1"}},
+		{Name: "secret", Files: map[string][]byte{"password": []byte("s3cr3t")}},
+		{Name: "config", Files: map[string][]byte{"conf/level": []byte("debug")}},
+	}))
+
+	// Other types of volumes are rejected.
+	pod.Spec.Volumes = []v1.Volume{
+		{Name: "tmp", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+	}
+	_, err = p.getVolumes(context.Background(), pod)
+	assert.Check(t, is.ErrorContains(err, "volume tmp: only cinder, secret and config map volumes are supported"))
+}
+
+func TestVolumeFiles(t *testing.T) {
+	data := map[string][]byte{"a": []byte("1"), "b": []byte("2")}
+
+	files, err := volumeFiles(data, nil, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(files, data))
+
+	files, err = volumeFiles(data, []v1.KeyToPath{{Key: "b", Path: "dir/b"}}, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(files, map[string][]byte{"dir/b": []byte("2")}))
+
+	items := []v1.KeyToPath{{Key: "a", Path: "a"}, {Key: "c", Path: "c"}}
+	_, err = volumeFiles(data, items, nil)
+	assert.Check(t, is.ErrorContains(err, "key c not found"))
+
+	optional := true
+	files, err = volumeFiles(data, items, &optional)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(files, map[string][]byte{"a": []byte("1")}))
+}
+
+func TestCapsuleToPod(t *testing.T) {
+	pod := testutil.FakePodWithSingleContainer("ns", "pod", "image")
+	pod.Spec.NodeName = "vk"
+	pod.Spec.Volumes = []v1.Volume{
+		{Name: "secret", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "secret"}}},
+	}
+	container := &pod.Spec.Containers[0]
+	container.Env = []v1.EnvVar{
+		{Name: "PLAIN", Value: "value"},
+		{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "secret"}, Key: "password"}}},
+	}
+	container.VolumeMounts = []v1.VolumeMount{{Name: "secret", MountPath: "/etc/secret", ReadOnly: true}}
+
+	labels := map[string]string{
+		"PodName":   pod.Name,
+		"Namespace": pod.Namespace,
+		"NodeName":  pod.Spec.NodeName,
+	}
+	assert.NilError(t, setSpecLabels(labels, pod))
+	capsule := &capsules.CapsuleV132{
+		UUID:       "capsule-1",
+		MetaName:   "ns-pod",
+		MetaLabels: labels,
+		Status:     "Running",
+		Containers: []capsules.Container{{
+			Name:        "capsule-ns-pod-0",
+			UUID:        "container-1",
+			Image:       "image",
+			Status:      "Running",
+			Environment: map[string]string{"PLAIN": "value", "PASSWORD": "s3cr3t"},
+		}},
+	}
+
+	p, err := capsuleToPod(capsule)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(p.Name, "pod"))
+	assert.Check(t, is.Equal(p.Namespace, "ns"))
+	assert.Check(t, is.Equal(p.Status.Phase, v1.PodRunning))
+	assert.Check(t, is.DeepEqual(p.Spec.Volumes, pod.Spec.Volumes))
+	assert.Assert(t, is.Len(p.Spec.Containers, 1))
+	// The environment is returned as declared, without the value of the secret.
+	assert.Check(t, is.DeepEqual(p.Spec.Containers[0].Env, container.Env))
+	assert.Check(t, is.DeepEqual(p.Spec.Containers[0].VolumeMounts, container.VolumeMounts))
+
+	// Capsules without the spec labels return the environment of the capsule.
+	delete(capsule.MetaLabels, volumesLabel)
+	delete(capsule.MetaLabels, containersLabel)
+	capsule.Containers[0].Environment = map[string]string{"PLAIN": "value"}
+	p, err = capsuleToPod(capsule)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(p.Spec.Volumes, 0))
+	assert.Check(t, is.DeepEqual(p.Spec.Containers[0].Env, []v1.EnvVar{{Name: "PLAIN", Value: "value"}}))
+	assert.Check(t, is.Len(p.Spec.Containers[0].VolumeMounts, 0))
+
+	// Invalid labels are errors.
+	capsule.MetaLabels[containersLabel] = "{"
+	_, err = capsuleToPod(capsule)
+	assert.Check(t, is.ErrorContains(err, "invalid Containers label of capsule ns-pod"))
+}
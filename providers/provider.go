@@ -57,11 +57,43 @@ type Provider interface {
 	OperatingSystem() string
 }
 
+// ContainerLogOpts are the options of a container logs request.
+type ContainerLogOpts struct {
+	// Tail is the number of lines to return from the end of the logs,
+	// all lines are returned if it is not positive.
+	Tail int
+	// Follow streams the logs until the container stops or the request is
+	// cancelled.
+	Follow bool
+	// Timestamps prefixes each line with its RFC3339 timestamp.
+	Timestamps bool
+	// SinceTime only returns the lines logged at or after it, if set.
+	SinceTime time.Time
+}
+
+// ContainerLogsStreamer is an optional interface container logs backends can
+// implement to stream logs and support the follow, timestamps and since
+// options of log requests.
+type ContainerLogsStreamer interface {
+	// StreamContainerLogs writes the logs of a container to w as they are
+	// read, until there are no more or, when following, ctx is done.
+	StreamContainerLogs(ctx context.Context, namespace, podName, containerName string, opts ContainerLogOpts, w io.Writer) error
+}
+
 // PodMetricsProvider is an optional interface that providers can implement to expose pod stats
 type PodMetricsProvider interface {
 	GetStatsSummary(context.Context) (*stats.Summary, error)
 }
 
+// PodStatsProvider is an optional, lighter alternative to PodMetricsProvider.
+// Providers only need to report stats for a single pod, the stats summary
+// including the node level rollup is then built by the caller.
+type PodStatsProvider interface {
+	// GetPodStats retrieves the CPU, memory and network stats of a pod and its
+	// containers by name from the provider.
+	GetPodStats(ctx context.Context, namespace, name string) (*stats.PodStats, error)
+}
+
 // PodNotifier notifies callers of pod changes.
 // Providers should implement this interface to enable callers to be notified
 // of pod status updates asyncronously.
@@ -72,3 +104,39 @@ type PodNotifier interface {
 	// NotifyPods should not block callers.
 	NotifyPods(context.Context, func(*v1.Pod))
 }
+
+// PodReconciler is an optional interface providers can implement to reconcile
+// the state they keep outside of Kubernetes with the pods of the node when the
+// virtual kubelet starts, e.g. to resume work which only lived in the memory
+// of a previous run.
+type PodReconciler interface {
+	// ReconcilePods is called once at startup, after the pods of the node
+	// are known to the resource manager and before the pods which Kubernetes
+	// doesn't know about are deleted from the provider.
+	// Errors are logged, startup goes on.
+	ReconcilePods(context.Context) error
+}
+
+// PodEnvironmentResolver is an optional interface providers can implement to
+// resolve the environment variables set from secrets and config maps
+// themselves, e.g. to keep the values of secrets out of the definitions they
+// create.
+type PodEnvironmentResolver interface {
+	// ResolvesEnvironmentReferences returns whether the envFrom sources and the
+	// env variables set from secret or config map keys of the containers are
+	// passed to CreatePod as is. The other variables, such as field references
+	// and service links, are still resolved by the caller and set as values.
+	ResolvesEnvironmentReferences() bool
+}
+
+// PodEventNotifier is an optional interface providers can implement to record
+// Kubernetes events for pods, such as image pulls or provider specific failures.
+type PodEventNotifier interface {
+	// NotifyPodEvents instructs the notifier to call the passed in function to
+	// record an event for a pod.
+	// The event type must be either v1.EventTypeNormal or v1.EventTypeWarning.
+	// Events are rate limited by the caller, excess events are dropped.
+	//
+	// NotifyPodEvents should not block callers.
+	NotifyPodEvents(ctx context.Context, record func(pod *v1.Pod, eventType, reason, message string))
+}
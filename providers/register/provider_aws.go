@@ -3,8 +3,11 @@
 package register
 
 import (
+	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"github.com/virtual-kubelet/virtual-kubelet/providers/aws"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/aws/fargate"
+	"go.opencensus.io/stats/view"
 )
 
 func init() {
@@ -12,5 +15,8 @@ func init() {
 }
 
 func initAWS(cfg InitConfig) (providers.Provider, error) {
+	if err := view.Register(fargate.TaskDefinitionsView); err != nil {
+		return nil, errors.Wrap(err, "error registering Fargate task definition metrics")
+	}
 	return aws.NewFargateProvider(cfg.ConfigPath, cfg.ResourceManager, cfg.NodeName, cfg.OperatingSystem, cfg.InternalIP, cfg.DaemonPort)
 }
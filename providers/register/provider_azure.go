@@ -9,6 +9,7 @@ import (
 
 func init() {
 	register("azure", initAzure)
+	registerConfigValidator("azure", azure.ValidateConfig)
 }
 
 func initAzure(cfg InitConfig) (providers.Provider, error) {
@@ -9,7 +9,10 @@ import (
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
 )
 
-var providerInits = make(map[string]initFunc)
+var (
+	providerInits            = make(map[string]initFunc)
+	providerConfigValidators = make(map[string]configValidatorFunc)
+)
 
 // InitConfig is the config passed to initialize a registered provider.
 type InitConfig struct {
@@ -23,6 +26,10 @@ type InitConfig struct {
 
 type initFunc func(InitConfig) (providers.Provider, error)
 
+// configValidatorFunc checks the provider config at the given path without
+// initializing the provider.
+type configValidatorFunc func(configPath string) error
+
 // GetProvider gets the provider specified by the given name
 func GetProvider(name string, cfg InitConfig) (providers.Provider, error) {
 	f, ok := providerInits[name]
@@ -32,6 +39,19 @@ func GetProvider(name string, cfg InitConfig) (providers.Provider, error) {
 	return f(cfg)
 }
 
+// ValidateConfig validates the config of the provider specified by the given
+// name, without initializing the provider.
+func ValidateConfig(name, configPath string) error {
+	if !Exists(name) {
+		return strongerrors.NotFound(errors.Errorf("provider not found: %s", name))
+	}
+	f, ok := providerConfigValidators[name]
+	if !ok {
+		return strongerrors.NotImplemented(errors.Errorf("provider %s does not support validating its config", name))
+	}
+	return f(configPath)
+}
+
 // Exists checks if a provider is regstered
 func Exists(name string) bool {
 	_, ok := providerInits[name]
@@ -51,3 +71,7 @@ func List() []string {
 func register(name string, f initFunc) {
 	providerInits[name] = f
 }
+
+func registerConfigValidator(name string, f configValidatorFunc) {
+	providerConfigValidators[name] = f
+}
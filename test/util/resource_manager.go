@@ -34,7 +34,7 @@ func FakeResourceManager(objects ...runtime.Object) *manager.ResourceManager {
 		panic("failed to wait for caches to be synced")
 	}
 	// Create a new instance of the resource manager using the listers for pods, configmaps and secrets.
-	r, err := manager.NewResourceManager(pInformer.Lister(), sInformer.Lister(), mInformer.Lister(), svcInformer.Lister())
+	r, err := manager.NewResourceManager(pInformer.Lister(), sInformer.Lister(), mInformer.Lister(), svcInformer.Lister(), manager.WithServiceAccountTokens(kubeClient.CoreV1()))
 	if err != nil {
 		panic(err)
 	}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/cpuguy83/strongerrors"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 )
 
 // ContainerLogsBackend is used in place of backend implementations for getting container logs
@@ -17,6 +21,10 @@ type ContainerLogsBackend interface {
 }
 
 // PodLogsHandlerFunc creates an http handler function from a provider to serve logs from a pod
+//
+// If the provider implements providers.ContainerLogsStreamer, the logs are streamed to
+// the client, and the follow, timestamps, sinceSeconds and sinceTime query
+// parameters are supported.
 func PodLogsHandlerFunc(p ContainerLogsBackend) http.HandlerFunc {
 	return handleError(func(w http.ResponseWriter, req *http.Request) error {
 		vars := mux.Vars(req)
@@ -40,14 +48,102 @@ func PodLogsHandlerFunc(p ContainerLogsBackend) http.HandlerFunc {
 			tail = t
 		}
 
+		if s, ok := p.(providers.ContainerLogsStreamer); ok {
+			opts, err := parseLogOpts(q, tail)
+			if err != nil {
+				return err
+			}
+
+			fw := newFlushWriter(w)
+			if err := s.StreamContainerLogs(ctx, namespace, pod, container, opts, fw); err != nil {
+				if errors.Cause(err) == context.Canceled {
+					return nil
+				}
+				err = errors.Wrap(err, "error streaming container logs")
+				if fw.written > 0 {
+					// The response is already under way, the error can't be
+					// reported to the client.
+					log.G(ctx).WithError(err).Error("Error streaming container logs")
+					return nil
+				}
+				return err
+			}
+			return nil
+		}
+
 		podsLogs, err := p.GetContainerLogs(ctx, namespace, pod, container, tail)
 		if err != nil {
 			return errors.Wrap(err, "error getting container logs?)")
 		}
 
-		if _, err := io.WriteString(w, podsLogs); err != nil {
-			return strongerrors.Unknown(errors.Wrap(err, "error writing response to client"))
+		if n, err := io.WriteString(w, podsLogs); err != nil {
+			err = strongerrors.Unknown(errors.Wrap(err, "error writing response to client"))
+			if n > 0 {
+				log.G(ctx).WithError(err).Error("Error writing container logs")
+				return nil
+			}
+			return err
 		}
 		return nil
 	})
 }
+
+// parseLogOpts parses the options of a logs request from its query.
+func parseLogOpts(q url.Values, tail int) (providers.ContainerLogOpts, error) {
+	opts := providers.ContainerLogOpts{Tail: tail}
+
+	var err error
+	if v := q.Get("follow"); v != "" {
+		if opts.Follow, err = strconv.ParseBool(v); err != nil {
+			return opts, strongerrors.InvalidArgument(errors.Wrap(err, "could not parse \"follow\""))
+		}
+	}
+	if v := q.Get("timestamps"); v != "" {
+		if opts.Timestamps, err = strconv.ParseBool(v); err != nil {
+			return opts, strongerrors.InvalidArgument(errors.Wrap(err, "could not parse \"timestamps\""))
+		}
+	}
+	if v := q.Get("sinceSeconds"); v != "" {
+		s, err := strconv.Atoi(v)
+		if err != nil || s < 0 {
+			return opts, strongerrors.InvalidArgument(errors.Errorf("could not parse \"sinceSeconds\": %q", v))
+		}
+		opts.SinceTime = time.Now().Add(-time.Duration(s) * time.Second)
+	}
+	if v := q.Get("sinceTime"); v != "" {
+		if !opts.SinceTime.IsZero() {
+			return opts, strongerrors.InvalidArgument(errors.New("only one of \"sinceSeconds\" and \"sinceTime\" may be set"))
+		}
+		if opts.SinceTime, err = time.Parse(time.RFC3339, v); err != nil {
+			return opts, strongerrors.InvalidArgument(errors.Wrap(err, "could not parse \"sinceTime\""))
+		}
+	}
+
+	return opts, nil
+}
+
+// flushWriter flushes the response after each write, so that streamed logs
+// reach the client as they are read. It counts the bytes written, so that
+// errors can only be reported to the client as long as none were.
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+	written int64
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	fw := &flushWriter{w: w}
+	if f, ok := w.(http.Flusher); ok {
+		fw.flusher = f
+	}
+	return fw
+}
+
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	n, err := fw.w.Write(b)
+	fw.written += int64(n)
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	return n, err
+}
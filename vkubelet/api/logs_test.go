@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type fakeLogsBackend struct {
+	opts providers.ContainerLogOpts
+	// err is returned by StreamContainerLogs after writing the logs.
+	err error
+}
+
+func (b *fakeLogsBackend) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
+	return "all at once\n", nil
+}
+
+func (b *fakeLogsBackend) StreamContainerLogs(ctx context.Context, namespace, podName, containerName string, opts providers.ContainerLogOpts, w io.Writer) error {
+	b.opts = opts
+	if _, err := io.WriteString(w, namespace+"/"+podName+"/"+containerName+"\n"); err != nil {
+		return err
+	}
+	return b.err
+}
+
+func serveLogs(t *testing.T, b ContainerLogsBackend, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/containerLogs/default/web/nginx?"+query, nil)
+	req = mux.SetURLVars(req, map[string]string{"namespace": "default", "pod": "web", "container": "nginx"})
+	w := httptest.NewRecorder()
+	PodLogsHandlerFunc(b).ServeHTTP(w, req)
+	return w
+}
+
+func TestPodLogsHandlerStreams(t *testing.T) {
+	b := &fakeLogsBackend{}
+	w := serveLogs(t, b, "follow=true&timestamps=1&tailLines=5&sinceTime=2019-01-01T00:00:00Z")
+
+	assert.Check(t, is.Equal(w.Code, http.StatusOK))
+	assert.Check(t, is.Equal(w.Body.String(), "default/web/nginx\n"))
+	assert.Check(t, w.Flushed, "streamed logs should be flushed")
+	assert.Check(t, is.DeepEqual(b.opts, providers.ContainerLogOpts{
+		Tail:       5,
+		Follow:     true,
+		Timestamps: true,
+		SinceTime:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+	}))
+
+	serveLogs(t, b, "sinceSeconds=60")
+	assert.Check(t, is.Equal(b.opts.Tail, 10))
+	assert.Check(t, time.Since(b.opts.SinceTime) >= time.Minute)
+	assert.Check(t, time.Since(b.opts.SinceTime) < 2*time.Minute)
+}
+
+func TestPodLogsHandlerStreamError(t *testing.T) {
+	// Errors after the logs started to be sent don't corrupt the response.
+	w := serveLogs(t, &fakeLogsBackend{err: errors.New("connection reset")}, "follow=true")
+	assert.Check(t, is.Equal(w.Code, http.StatusOK))
+	assert.Check(t, is.Equal(w.Body.String(), "default/web/nginx\n"))
+}
+
+func TestPodLogsHandlerInvalidOptions(t *testing.T) {
+	for _, query := range []string{
+		"follow=maybe",
+		"sinceSeconds=-1",
+		"sinceTime=yesterday",
+		"sinceSeconds=1&sinceTime=2019-01-01T00:00:00Z",
+	} {
+		w := serveLogs(t, &fakeLogsBackend{}, query)
+		assert.Check(t, is.Equal(w.Code, http.StatusBadRequest), query)
+	}
+}
+
+func TestPodLogsHandlerWithoutStreaming(t *testing.T) {
+	b := struct{ ContainerLogsBackend }{&fakeLogsBackend{}}
+	w := serveLogs(t, b, "follow=true")
+
+	assert.Check(t, is.Equal(w.Code, http.StatusOK))
+	assert.Check(t, is.Equal(w.Body.String(), "all at once\n"))
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+// prometheusContentType is the content type of the prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ResourceMetricsHandlerFunc makes an HTTP handler for implementing the kubelet
+// `/metrics/resource` endpoint.
+// The metrics are derived from the stats summary and served in the prometheus
+// text exposition format.
+func ResourceMetricsHandlerFunc(b PodMetricsBackend) http.HandlerFunc {
+	return metricsHandlerFunc(b, resourceMetrics)
+}
+
+// CadvisorMetricsHandlerFunc makes an HTTP handler for implementing the
+// container metrics of the kubelet `/metrics/cadvisor` endpoint.
+// The metrics are derived from the stats summary and served in the prometheus
+// text exposition format.
+func CadvisorMetricsHandlerFunc(b PodMetricsBackend) http.HandlerFunc {
+	return metricsHandlerFunc(b, cadvisorMetrics)
+}
+
+func metricsHandlerFunc(b PodMetricsBackend, collect func(*stats.Summary) []*metricFamily) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, req *http.Request) error {
+		summary, err := b.GetStatsSummary(req.Context())
+		if err != nil {
+			if errors.Cause(err) == context.Canceled {
+				return strongerrors.Cancelled(err)
+			}
+			return errors.Wrap(err, "error getting status from provider")
+		}
+
+		w.Header().Set("Content-Type", prometheusContentType)
+		bw := bufio.NewWriter(w)
+		for _, mf := range collect(summary) {
+			mf.writeTo(bw)
+		}
+		if err := bw.Flush(); err != nil {
+			return strongerrors.Unknown(errors.Wrap(err, "could not write to client"))
+		}
+		return nil
+	})
+}
+
+// resourceMetrics builds the metrics served by the kubelet resource metrics endpoint.
+func resourceMetrics(s *stats.Summary) []*metricFamily {
+	var (
+		nodeCPU = newMetricFamily("node_cpu_usage_seconds_total", "counter",
+			"Cumulative cpu time consumed by the node in core-seconds")
+		nodeMemory = newMetricFamily("node_memory_working_set_bytes", "gauge",
+			"Current working set of the node in bytes")
+		podCPU = newMetricFamily("pod_cpu_usage_seconds_total", "counter",
+			"Cumulative cpu time consumed by the pod in core-seconds")
+		podMemory = newMetricFamily("pod_memory_working_set_bytes", "gauge",
+			"Current working set of the pod in bytes")
+		containerCPU = newMetricFamily("container_cpu_usage_seconds_total", "counter",
+			"Cumulative cpu time consumed by the container in core-seconds")
+		containerMemory = newMetricFamily("container_memory_working_set_bytes", "gauge",
+			"Current working set of the container in bytes")
+	)
+
+	nodeCPU.addCPUSeconds(nil, s.Node.CPU)
+	nodeMemory.addWorkingSet(nil, s.Node.Memory)
+
+	for _, ps := range s.Pods {
+		podLabels := []label{{"namespace", ps.PodRef.Namespace}, {"pod", ps.PodRef.Name}}
+		podCPU.addCPUSeconds(podLabels, ps.CPU)
+		podMemory.addWorkingSet(podLabels, ps.Memory)
+
+		for _, cs := range ps.Containers {
+			containerLabels := []label{{"container", cs.Name}, {"namespace", ps.PodRef.Namespace}, {"pod", ps.PodRef.Name}}
+			containerCPU.addCPUSeconds(containerLabels, cs.CPU)
+			containerMemory.addWorkingSet(containerLabels, cs.Memory)
+		}
+	}
+
+	return []*metricFamily{nodeCPU, nodeMemory, podCPU, podMemory, containerCPU, containerMemory}
+}
+
+// cadvisorMetrics builds the container metrics served by the kubelet cadvisor
+// metrics endpoint.
+// Like cadvisor, network metrics are reported for the pod sandbox, which uses
+// the "POD" container name.
+func cadvisorMetrics(s *stats.Summary) []*metricFamily {
+	var (
+		cpu = newMetricFamily("container_cpu_usage_seconds_total", "counter",
+			"Cumulative cpu time consumed in seconds.")
+		memory = newMetricFamily("container_memory_working_set_bytes", "gauge",
+			"Current working set in bytes.")
+		rx = newMetricFamily("container_network_receive_bytes_total", "counter",
+			"Cumulative count of bytes received")
+		tx = newMetricFamily("container_network_transmit_bytes_total", "counter",
+			"Cumulative count of bytes transmitted")
+	)
+
+	for _, ps := range s.Pods {
+		for _, cs := range ps.Containers {
+			containerLabels := []label{{"container", cs.Name}, {"namespace", ps.PodRef.Namespace}, {"pod", ps.PodRef.Name}}
+			cpu.addCPUSeconds(containerLabels, cs.CPU)
+			memory.addWorkingSet(containerLabels, cs.Memory)
+		}
+
+		if ps.Network == nil {
+			continue
+		}
+		interfaces := ps.Network.Interfaces
+		if len(interfaces) == 0 {
+			interfaces = []stats.InterfaceStats{ps.Network.InterfaceStats}
+		}
+		for _, i := range interfaces {
+			netLabels := []label{{"container", "POD"}, {"interface", i.Name}, {"namespace", ps.PodRef.Namespace}, {"pod", ps.PodRef.Name}}
+			rx.addUint64(netLabels, i.RxBytes, ps.Network.Time)
+			tx.addUint64(netLabels, i.TxBytes, ps.Network.Time)
+		}
+	}
+
+	return []*metricFamily{cpu, memory, rx, tx}
+}
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	labels    []label
+	value     float64
+	timestamp int64
+}
+
+// metricFamily is a set of samples for a single metric in the prometheus text
+// exposition format.
+type metricFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples []sample
+}
+
+func newMetricFamily(name, typ, help string) *metricFamily {
+	return &metricFamily{name: name, typ: typ, help: help}
+}
+
+func (mf *metricFamily) add(labels []label, value float64, t metav1.Time) {
+	var ts int64
+	if !t.IsZero() {
+		ts = t.UnixNano() / 1e6
+	}
+	mf.samples = append(mf.samples, sample{labels: labels, value: value, timestamp: ts})
+}
+
+func (mf *metricFamily) addUint64(labels []label, v *uint64, t metav1.Time) {
+	if v == nil {
+		return
+	}
+	mf.add(labels, float64(*v), t)
+}
+
+func (mf *metricFamily) addCPUSeconds(labels []label, cpu *stats.CPUStats) {
+	if cpu == nil || cpu.UsageCoreNanoSeconds == nil {
+		return
+	}
+	mf.add(labels, float64(*cpu.UsageCoreNanoSeconds)/1e9, cpu.Time)
+}
+
+func (mf *metricFamily) addWorkingSet(labels []label, mem *stats.MemoryStats) {
+	if mem == nil {
+		return
+	}
+	mf.addUint64(labels, mem.WorkingSetBytes, mem.Time)
+}
+
+// writeTo writes the metric family in the prometheus text exposition format.
+// Families without samples are skipped.
+func (mf *metricFamily) writeTo(w *bufio.Writer) {
+	if len(mf.samples) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", mf.name, mf.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", mf.name, mf.typ)
+	for _, s := range mf.samples {
+		w.WriteString(mf.name)
+		if len(s.labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range s.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l.name, escapeLabelValue(l.value))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+		if s.timestamp != 0 {
+			w.WriteByte(' ')
+			w.WriteString(strconv.FormatInt(s.timestamp, 10))
+		}
+		w.WriteByte('\n')
+	}
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
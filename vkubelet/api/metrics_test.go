@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+type fakeMetricsBackend struct {
+	summary *stats.Summary
+}
+
+func (b fakeMetricsBackend) GetStatsSummary(context.Context) (*stats.Summary, error) {
+	return b.summary, nil
+}
+
+func TestResourceMetricsHandler(t *testing.T) {
+	body := serveMetrics(t, ResourceMetricsHandlerFunc(fakeMetricsBackend{testSummary()}))
+
+	assert.Assert(t, strings.Contains(body, "# TYPE node_cpu_usage_seconds_total counter\n"))
+	assert.Assert(t, strings.Contains(body, "node_cpu_usage_seconds_total 3 1546300800000\n"), body)
+	assert.Assert(t, strings.Contains(body, "node_memory_working_set_bytes 4096 1546300800000\n"), body)
+	assert.Assert(t, strings.Contains(body, `pod_cpu_usage_seconds_total{namespace="default",pod="web"} 3 1546300800000`), body)
+	assert.Assert(t, strings.Contains(body, `container_cpu_usage_seconds_total{container="nginx",namespace="default",pod="web"} 1.5 1546300800000`), body)
+	assert.Assert(t, strings.Contains(body, `container_memory_working_set_bytes{container="quote\"d",namespace="default",pod="web"} 2048 1546300800000`), body)
+}
+
+func TestCadvisorMetricsHandler(t *testing.T) {
+	body := serveMetrics(t, CadvisorMetricsHandlerFunc(fakeMetricsBackend{testSummary()}))
+
+	assert.Assert(t, !strings.Contains(body, "node_cpu_usage_seconds_total"), body)
+	assert.Assert(t, strings.Contains(body, `container_cpu_usage_seconds_total{container="nginx",namespace="default",pod="web"} 1.5 1546300800000`), body)
+	assert.Assert(t, strings.Contains(body, `container_network_receive_bytes_total{container="POD",interface="eth0",namespace="default",pod="web"} 100 1546300800000`), body)
+	assert.Assert(t, strings.Contains(body, `container_network_transmit_bytes_total{container="POD",interface="eth0",namespace="default",pod="web"} 200 1546300800000`), body)
+}
+
+func serveMetrics(t *testing.T, h http.HandlerFunc) string {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/metrics/resource", nil)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, rec.Header().Get("Content-Type"), prometheusContentType)
+	return rec.Body.String()
+}
+
+func testSummary() *stats.Summary {
+	ts := metav1.NewTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	cpu := func(ns uint64) *stats.CPUStats {
+		return &stats.CPUStats{Time: ts, UsageCoreNanoSeconds: &ns}
+	}
+	mem := func(b uint64) *stats.MemoryStats {
+		return &stats.MemoryStats{Time: ts, WorkingSetBytes: &b}
+	}
+	rx, tx := uint64(100), uint64(200)
+
+	return &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName: "vk",
+			CPU:      cpu(3e9),
+			Memory:   mem(4096),
+		},
+		Pods: []stats.PodStats{{
+			PodRef: stats.PodReference{Name: "web", Namespace: "default"},
+			CPU:    cpu(3e9),
+			Memory: mem(4096),
+			Containers: []stats.ContainerStats{
+				{Name: "nginx", CPU: cpu(1.5e9), Memory: mem(2048)},
+				{Name: `quote"d`, CPU: cpu(1.5e9), Memory: mem(2048)},
+			},
+			Network: &stats.NetworkStats{
+				Time:           ts,
+				InterfaceStats: stats.InterfaceStats{Name: "eth0", RxBytes: &rx, TxBytes: &tx},
+			},
+		}},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/cpuguy83/strongerrors"
 	"github.com/pkg/errors"
@@ -16,8 +17,21 @@ type PodMetricsBackend interface {
 }
 
 // PodMetricsHandlerFunc makes an HTTP handler for implementing the kubelet summary stats endpoint
+//
+// Like the kubelet, the `only_cpu_and_memory` query parameter can be used to
+// strip everything but the CPU and memory stats from the summary, which is what
+// metrics-server requests.
 func PodMetricsHandlerFunc(b PodMetricsBackend) http.HandlerFunc {
 	return handleError(func(w http.ResponseWriter, req *http.Request) error {
+		var onlyCPUAndMemory bool
+		if v := req.URL.Query().Get("only_cpu_and_memory"); v != "" {
+			var err error
+			onlyCPUAndMemory, err = strconv.ParseBool(v)
+			if err != nil {
+				return strongerrors.InvalidArgument(errors.Wrap(err, "invalid value for only_cpu_and_memory"))
+			}
+		}
+
 		stats, err := b.GetStatsSummary(req.Context())
 		if err != nil {
 			if errors.Cause(err) == context.Canceled {
@@ -26,6 +40,10 @@ func PodMetricsHandlerFunc(b PodMetricsBackend) http.HandlerFunc {
 			return errors.Wrap(err, "error getting status from provider")
 		}
 
+		if onlyCPUAndMemory {
+			stats = filterCPUAndMemoryStats(stats)
+		}
+
 		b, err := json.Marshal(stats)
 		if err != nil {
 			return strongerrors.Unknown(errors.Wrap(err, "error marshalling stats"))
@@ -37,3 +55,37 @@ func PodMetricsHandlerFunc(b PodMetricsBackend) http.HandlerFunc {
 		return nil
 	})
 }
+
+// filterCPUAndMemoryStats returns a copy of the summary with only the CPU and
+// memory stats of the node, pods and containers.
+func filterCPUAndMemoryStats(s *stats.Summary) *stats.Summary {
+	filtered := &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName:  s.Node.NodeName,
+			StartTime: s.Node.StartTime,
+			CPU:       s.Node.CPU,
+			Memory:    s.Node.Memory,
+		},
+		Pods: make([]stats.PodStats, 0, len(s.Pods)),
+	}
+
+	for _, ps := range s.Pods {
+		fps := stats.PodStats{
+			PodRef:     ps.PodRef,
+			StartTime:  ps.StartTime,
+			CPU:        ps.CPU,
+			Memory:     ps.Memory,
+			Containers: make([]stats.ContainerStats, 0, len(ps.Containers)),
+		}
+		for _, cs := range ps.Containers {
+			fps.Containers = append(fps.Containers, stats.ContainerStats{
+				Name:      cs.Name,
+				StartTime: cs.StartTime,
+				CPU:       cs.CPU,
+				Memory:    cs.Memory,
+			})
+		}
+		filtered.Pods = append(filtered.Pods, fps)
+	}
+	return filtered
+}
@@ -31,25 +31,41 @@ func PodHandler(p providers.Provider) http.Handler {
 }
 
 // MetricsSummaryHandler creates an http handler for serving pod metrics.
+// Besides the stats summary, the metrics are also served in the prometheus
+// format on the kubelet resource and cadvisor metrics endpoints.
 //
 // If the passed in provider does not implement providers.PodMetricsProvider,
 // it will create handlers that just serves http.StatusNotImplemented
+// Providers implementing the lighter providers.PodStatsProvider interface can
+// be wrapped with `NewStatsAggregator` to serve a full stats summary.
 func MetricsSummaryHandler(p providers.Provider) http.Handler {
 	r := mux.NewRouter()
 
-	const summaryRoute = "/stats/summary"
-	var h http.HandlerFunc
+	const (
+		summaryRoute         = "/stats/summary"
+		resourceMetricsRoute = "/metrics/resource"
+		cadvisorMetricsRoute = "/metrics/cadvisor"
+	)
+	var h, rh, ch http.HandlerFunc
 
 	mp, ok := p.(providers.PodMetricsProvider)
 	if !ok {
 		h = NotImplemented
+		rh = NotImplemented
+		ch = NotImplemented
 	} else {
 		h = api.PodMetricsHandlerFunc(mp)
+		rh = api.ResourceMetricsHandlerFunc(mp)
+		ch = api.CadvisorMetricsHandlerFunc(mp)
 	}
 
 	r.Handle(summaryRoute, ochttp.WithRouteTag(h, "PodStatsSummaryHandler")).Methods("GET")
 	r.Handle(summaryRoute+"/", ochttp.WithRouteTag(h, "PodStatsSummaryHandler")).Methods("GET")
 
+	r.Handle(resourceMetricsRoute, ochttp.WithRouteTag(rh, "ResourceMetricsHandler")).Methods("GET")
+	r.Handle(resourceMetricsRoute+"/v1alpha1", ochttp.WithRouteTag(rh, "ResourceMetricsHandler")).Methods("GET")
+	r.Handle(cadvisorMetricsRoute, ochttp.WithRouteTag(ch, "CadvisorMetricsHandler")).Methods("GET")
+
 	r.NotFoundHandler = http.HandlerFunc(NotFound)
 	return r
 }
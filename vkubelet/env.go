@@ -96,6 +96,65 @@ func populateContainerEnvironment(ctx context.Context, pod *corev1.Pod, containe
 	return nil
 }
 
+// populateEnvironmentValues populates the environment of each container (and init container) in the specified pod like
+// populateEnvironmentVariables, except that the references to secrets and config maps are kept, for the providers
+// implementing providers.PodEnvironmentResolver.
+func populateEnvironmentValues(ctx context.Context, pod *corev1.Pod, rm *manager.ResourceManager, recorder record.EventRecorder) error {
+	for idx := range pod.Spec.InitContainers {
+		if err := populateContainerEnvironmentValues(ctx, pod, &pod.Spec.InitContainers[idx], rm, recorder); err != nil {
+			return err
+		}
+	}
+	for idx := range pod.Spec.Containers {
+		if err := populateContainerEnvironmentValues(ctx, pod, &pod.Spec.Containers[idx], rm, recorder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// populateContainerEnvironmentValues populates the environment of a single container in the specified pod, keeping its
+// ".envFrom" field and the entries of its ".env" field set from secret or config map keys as is.
+// The other entries of ".env" and the service env vars are resolved and set as values. As the keys of the ".envFrom"
+// sources aren't known, the service env vars take precedence over them.
+func populateContainerEnvironmentValues(ctx context.Context, pod *corev1.Pod, container *corev1.Container, rm *manager.ResourceManager, recorder record.EventRecorder) error {
+	// Split the references from the other variables. The last entry of a name wins, as in makeEnvironmentMapBasedOnEnv.
+	refs := make(map[string]int)
+	others := make([]corev1.EnvVar, 0, len(container.Env))
+	for idx, env := range container.Env {
+		if isEnvReference(env) {
+			refs[env.Name] = idx
+			continue
+		}
+		delete(refs, env.Name)
+		others = append(others, env)
+	}
+
+	c := container.DeepCopy()
+	c.Env = others
+	env, err := makeEnvironmentMapBasedOnEnv(ctx, pod, c, rm, recorder)
+	if err != nil {
+		return err
+	}
+	for name := range refs {
+		delete(env, name)
+	}
+
+	res := mergeEnvironments(nil, env)
+	for idx, e := range container.Env {
+		if i, ok := refs[e.Name]; ok && i == idx {
+			res = append(res, e)
+		}
+	}
+	container.Env = res
+	return nil
+}
+
+// isEnvReference returns whether the environment variable is set from a secret or config map key.
+func isEnvReference(env corev1.EnvVar) bool {
+	return env.Value == "" && env.ValueFrom != nil && (env.ValueFrom.SecretKeyRef != nil || env.ValueFrom.ConfigMapKeyRef != nil)
+}
+
 // getServiceEnvVarMap makes a map[string]string of env vars for services a
 // pod in namespace ns should see.
 // Based on getServiceEnvVarMap in kubelet_pods.go.
@@ -0,0 +1,145 @@
+package vkubelet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// ReasonPulling is the reason used in events emitted when a pod is handed to the provider, which pulls the container images.
+	ReasonPulling = "Pulling"
+	// ReasonCreated is the reason used in events emitted when a container was created in the provider.
+	ReasonCreated = "Created"
+	// ReasonStarted is the reason used in events emitted when the provider reports a container as running.
+	ReasonStarted = "Started"
+	// ReasonKilling is the reason used in events emitted when a container is being deleted from the provider.
+	ReasonKilling = "Killing"
+	// ReasonBackOff is the reason used in events emitted when the provider reports a container restarting or waiting after a failure.
+	ReasonBackOff = "BackOff"
+	// ReasonProviderFailed is the reason used in events emitted when the provider fails to create a pod.
+	ReasonProviderFailed = podStatusReasonProviderFailed
+	// ReasonFailedKillPod is the reason used in events emitted when the provider fails to delete a pod.
+	ReasonFailedKillPod = "FailedKillPod"
+	// ReasonNotFound is the reason used in events emitted when the provider no longer knows about a running pod.
+	ReasonNotFound = "NotFound"
+)
+
+// The default rate limits for events recorded by providers.
+const (
+	DefaultProviderEventQPS   = 5
+	DefaultProviderEventBurst = 10
+)
+
+// newEventRecorder creates an event recorder which records events to the Kubernetes API.
+// Events are aggregated and spam filtered by the broadcaster.
+func newEventRecorder(s *Server) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(log.L.Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: s.k8sClient.CoreV1().Events("")})
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: fmt.Sprintf("%s/pod-controller", s.nodeName), Host: s.nodeName})
+}
+
+// recordCreatingPodEvents records the events for a pod which is about to be created in the provider.
+func recordCreatingPodEvents(recorder record.EventRecorder, pod *corev1.Pod) {
+	for _, c := range allContainers(pod) {
+		recorder.Eventf(pod, corev1.EventTypeNormal, ReasonPulling, "Pulling image %q", c.Image)
+	}
+}
+
+// recordCreatedPodEvents records the events for a pod which was created in the provider.
+func recordCreatedPodEvents(recorder record.EventRecorder, pod *corev1.Pod) {
+	for _, c := range allContainers(pod) {
+		recorder.Eventf(pod, corev1.EventTypeNormal, ReasonCreated, "Created container %s", c.Name)
+	}
+}
+
+// recordDeletingPodEvents records the events for a pod which is about to be deleted from the provider.
+func recordDeletingPodEvents(recorder record.EventRecorder, pod *corev1.Pod) {
+	for _, c := range pod.Spec.Containers {
+		recorder.Eventf(pod, corev1.EventTypeNormal, ReasonKilling, "Stopping container %s", c.Name)
+	}
+}
+
+// recordPodStatusEvents records the events for the container state transitions
+// between the old and new status reported by the provider.
+func recordPodStatusEvents(recorder record.EventRecorder, pod *corev1.Pod, oldStatus, newStatus *corev1.PodStatus) {
+	old := make(map[string]corev1.ContainerStatus, len(oldStatus.ContainerStatuses)+len(oldStatus.InitContainerStatuses))
+	for _, cs := range oldStatus.InitContainerStatuses {
+		old[cs.Name] = cs
+	}
+	for _, cs := range oldStatus.ContainerStatuses {
+		old[cs.Name] = cs
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, newStatus.InitContainerStatuses...), newStatus.ContainerStatuses...)
+	for _, cs := range statuses {
+		prev, known := old[cs.Name]
+
+		if cs.State.Running != nil && (!known || prev.State.Running == nil || !prev.State.Running.StartedAt.Equal(&cs.State.Running.StartedAt)) {
+			recorder.Eventf(pod, corev1.EventTypeNormal, ReasonStarted, "Started container %s", cs.Name)
+		}
+
+		if known && cs.RestartCount > prev.RestartCount {
+			recorder.Eventf(pod, corev1.EventTypeWarning, ReasonBackOff, "Back-off restarting failed container %s", cs.Name)
+		} else if w := cs.State.Waiting; w != nil && w.Reason == "CrashLoopBackOff" && (!known || prev.State.Waiting == nil || prev.State.Waiting.Reason != w.Reason) {
+			recorder.Eventf(pod, corev1.EventTypeWarning, ReasonBackOff, "Back-off restarting failed container %s", cs.Name)
+		}
+	}
+}
+
+func allContainers(pod *corev1.Pod) []corev1.Container {
+	return append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+}
+
+// providerEventRecorder records events on behalf of a provider implementing
+// providers.PodEventNotifier.
+// Events are rate limited and only recorded for pods known to Kubernetes.
+type providerEventRecorder struct {
+	s       *Server
+	limiter flowcontrol.RateLimiter
+}
+
+func newProviderEventRecorder(s *Server) *providerEventRecorder {
+	return &providerEventRecorder{
+		s:       s,
+		limiter: flowcontrol.NewTokenBucketRateLimiter(DefaultProviderEventQPS, DefaultProviderEventBurst),
+	}
+}
+
+func (r *providerEventRecorder) record(ctx context.Context, p *corev1.Pod, eventType, reason, message string) {
+	logger := log.G(ctx).WithFields(log.Fields{
+		"pod":       loggablePodName(p),
+		"eventType": eventType,
+		"reason":    reason,
+	})
+
+	if eventType != corev1.EventTypeNormal && eventType != corev1.EventTypeWarning {
+		logger.Warn("Dropping provider event with invalid event type")
+		return
+	}
+
+	if !r.limiter.TryAccept() {
+		logger.Debug("Dropping provider event due to rate limiting")
+		return
+	}
+
+	// Use the pod known to Kubernetes, as the copy held by the provider may
+	// not have all the fields required to reference it in the event.
+	pod, err := r.s.podInformer.Lister().Pods(p.Namespace).Get(p.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.WithError(err).Warn("Error looking up pod for provider event")
+		}
+		return
+	}
+
+	r.s.recorder.Event(pod, eventType, reason, message)
+}
@@ -0,0 +1,80 @@
+package vkubelet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+func TestRecordPodStatusEvents(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	started := metav1.NewTime(time.Now())
+
+	oldStatus := &corev1.PodStatus{
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "waiting", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+			{Name: "running", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: started}}},
+			{Name: "restarting", RestartCount: 1},
+		},
+	}
+	newStatus := &corev1.PodStatus{
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "waiting", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: started}}},
+			{Name: "running", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: started}}},
+			{Name: "restarting", RestartCount: 2},
+			{Name: "crashing", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(defaultEventRecorderBufferSize)
+	recordPodStatusEvents(recorder, pod, oldStatus, newStatus)
+	close(recorder.Events)
+
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	assert.DeepEqual(t, events, []string{
+		"Normal Started Started container waiting",
+		"Warning BackOff Back-off restarting failed container restarting",
+		"Warning BackOff Back-off restarting failed container crashing",
+	})
+
+	// Nothing changed, so no events should be recorded.
+	recorder = record.NewFakeRecorder(defaultEventRecorderBufferSize)
+	recordPodStatusEvents(recorder, pod, newStatus, newStatus)
+	assert.Equal(t, len(recorder.Events), 0)
+}
+
+func TestProviderEventRecorder(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", UID: "1234"}}
+
+	informer := kubeinformers.NewSharedInformerFactory(testclient.NewSimpleClientset(), 0).Core().V1().Pods()
+	assert.NilError(t, informer.Informer().GetIndexer().Add(pod))
+
+	recorder := record.NewFakeRecorder(defaultEventRecorderBufferSize)
+	r := &providerEventRecorder{
+		s:       &Server{podInformer: informer, recorder: recorder},
+		limiter: flowcontrol.NewTokenBucketRateLimiter(1, 2),
+	}
+
+	ctx := context.Background()
+	providerPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	unknownPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unknown", Namespace: "default"}}
+
+	r.record(ctx, providerPod, "Bogus", "Reason", "invalid event types are dropped")
+	r.record(ctx, unknownPod, corev1.EventTypeNormal, "Reason", "unknown pods are dropped")
+	r.record(ctx, providerPod, corev1.EventTypeNormal, "Pulled", "first")
+	r.record(ctx, providerPod, corev1.EventTypeNormal, "Pulled", "rate limited")
+
+	assert.Equal(t, len(recorder.Events), 1)
+	assert.Equal(t, <-recorder.Events, "Normal Pulled first")
+}
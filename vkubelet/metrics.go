@@ -0,0 +1,44 @@
+package vkubelet
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// The results recorded for pod status writes.
+const (
+	podStatusUpdateResultPatched  = "patched"
+	podStatusUpdateResultSkipped  = "skipped"
+	podStatusUpdateResultConflict = "conflict"
+	podStatusUpdateResultError    = "error"
+)
+
+var (
+	podStatusUpdates = stats.Int64("virtual-kubelet/pod_status_updates", "Number of pod status writes to the Kubernetes API server", stats.UnitDimensionless)
+
+	keyPodStatusUpdateResult, _ = tag.NewKey("result")
+
+	// PodStatusUpdatesView counts the pod status writes, tagged by the result
+	// of the write: patched, skipped (the status did not change), conflict or
+	// error.
+	//
+	// Register it with `view.Register` to collect the metric.
+	PodStatusUpdatesView = &view.View{
+		Name:        "virtual-kubelet/pod_status_updates",
+		Description: "Number of pod status writes to the Kubernetes API server, by result",
+		Measure:     podStatusUpdates,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{keyPodStatusUpdateResult},
+	}
+)
+
+func recordPodStatusUpdate(ctx context.Context, result string) {
+	ctx, err := tag.New(ctx, tag.Upsert(keyPodStatusUpdateResult, result))
+	if err != nil {
+		return
+	}
+	stats.Record(ctx, podStatusUpdates.M(1))
+}
@@ -2,16 +2,20 @@ package vkubelet
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/cpuguy83/strongerrors/status/ocstatus"
 	pkgerrors "github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"github.com/virtual-kubelet/virtual-kubelet/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -42,7 +46,11 @@ func (s *Server) createOrUpdatePod(ctx context.Context, pod *corev1.Pod, recorde
 	defer span.End()
 	addPodAttributes(ctx, span, pod)
 
-	if err := populateEnvironmentVariables(ctx, pod, s.resourceManager, recorder); err != nil {
+	populate := populateEnvironmentVariables
+	if r, ok := s.provider.(providers.PodEnvironmentResolver); ok && r.ResolvesEnvironmentReferences() {
+		populate = populateEnvironmentValues
+	}
+	if err := populate(ctx, pod, s.resourceManager, recorder); err != nil {
 		span.SetStatus(ocstatus.FromError(err))
 		return err
 	}
@@ -52,24 +60,27 @@ func (s *Server) createOrUpdatePod(ctx context.Context, pod *corev1.Pod, recorde
 		"namespace": pod.GetNamespace(),
 	})
 
+	recordCreatingPodEvents(recorder, pod)
+
 	if origErr := s.provider.CreatePod(ctx, pod); origErr != nil {
+		recorder.Event(pod, corev1.EventTypeWarning, ReasonProviderFailed, origErr.Error())
+
 		podPhase := corev1.PodPending
 		if pod.Spec.RestartPolicy == corev1.RestartPolicyNever {
 			podPhase = corev1.PodFailed
 		}
 
-		pod.ResourceVersion = "" // Blank out resource version to prevent object has been modified error
-		pod.Status.Phase = podPhase
-		pod.Status.Reason = podStatusReasonProviderFailed
-		pod.Status.Message = origErr.Error()
+		status := pod.Status.DeepCopy()
+		status.Phase = podPhase
+		status.Reason = podStatusReasonProviderFailed
+		status.Message = origErr.Error()
 
 		logger := log.G(ctx).WithFields(log.Fields{
 			"podPhase": podPhase,
-			"reason":   pod.Status.Reason,
+			"reason":   status.Reason,
 		})
 
-		_, err := s.k8sClient.CoreV1().Pods(pod.Namespace).UpdateStatus(pod)
-		if err != nil {
+		if err := s.patchPodStatus(ctx, pod, *status); err != nil {
 			logger.WithError(err).Warn("Failed to update pod status")
 		} else {
 			logger.Info("Updated k8s pod status")
@@ -80,6 +91,7 @@ func (s *Server) createOrUpdatePod(ctx context.Context, pod *corev1.Pod, recorde
 	}
 
 	log.G(ctx).Info("Created pod in provider")
+	recordCreatedPodEvents(recorder, pod)
 
 	return nil
 }
@@ -98,11 +110,22 @@ func (s *Server) deletePod(ctx context.Context, namespace, name string) error {
 	defer span.End()
 	ctx = addPodAttributes(ctx, span, pod)
 
+	// Prefer the pod known to Kubernetes for recording events, the provider's
+	// copy may not carry all the fields needed to reference it.
+	eventPod := pod
+	if p, err := s.podInformer.Lister().Pods(namespace).Get(name); err == nil {
+		eventPod = p
+	}
+	recordDeletingPodEvents(s.recorder, eventPod)
+
 	var delErr error
 	if delErr = s.provider.DeletePod(ctx, pod); delErr != nil && errors.IsNotFound(delErr) {
 		span.SetStatus(ocstatus.FromError(delErr))
 		return delErr
 	}
+	if delErr != nil {
+		s.recorder.Event(eventPod, corev1.EventTypeWarning, ReasonFailedKillPod, delErr.Error())
+	}
 
 	log.G(ctx).Debug("Deleted pod from provider")
 
@@ -180,39 +203,52 @@ func (s *Server) updatePodStatus(ctx context.Context, pod *corev1.Pod) error {
 		return pkgerrors.Wrap(err, "error retreiving pod status")
 	}
 
+	// Work on a copy of the status, the pod comes from the informer cache and
+	// must not be modified.
+	newStatus := pod.Status.DeepCopy()
+
 	// Update the pod's status
 	if status != nil {
-		pod.Status = *status
+		newStatus = status
 	} else {
 		// Only change the status when the pod was already up
 		// Only doing so when the pod was successfully running makes sure we don't run into race conditions during pod creation.
 		if pod.Status.Phase == corev1.PodRunning || pod.ObjectMeta.CreationTimestamp.Add(time.Minute).Before(time.Now()) {
 			// Set the pod to failed, this makes sure if the underlying container implementation is gone that a new pod will be created.
-			pod.Status.Phase = corev1.PodFailed
-			pod.Status.Reason = "NotFound"
-			pod.Status.Message = "The pod status was not found and may have been deleted from the provider"
-			for i, c := range pod.Status.ContainerStatuses {
-				pod.Status.ContainerStatuses[i].State.Terminated = &corev1.ContainerStateTerminated{
+			newStatus.Phase = corev1.PodFailed
+			newStatus.Reason = "NotFound"
+			newStatus.Message = "The pod status was not found and may have been deleted from the provider"
+			for i, c := range newStatus.ContainerStatuses {
+				terminated := &corev1.ContainerStateTerminated{
 					ExitCode:    -137,
 					Reason:      "NotFound",
 					Message:     "Container was not found and was likely deleted",
 					FinishedAt:  metav1.NewTime(time.Now()),
-					StartedAt:   c.State.Running.StartedAt,
 					ContainerID: c.ContainerID,
 				}
-				pod.Status.ContainerStatuses[i].State.Running = nil
+				if c.State.Running != nil {
+					terminated.StartedAt = c.State.Running.StartedAt
+				}
+				newStatus.ContainerStatuses[i].State.Terminated = terminated
+				newStatus.ContainerStatuses[i].State.Running = nil
 			}
 		}
 	}
 
-	if _, err := s.k8sClient.CoreV1().Pods(pod.Namespace).UpdateStatus(pod); err != nil {
+	if err := s.patchPodStatus(ctx, pod, *newStatus); err != nil {
 		span.SetStatus(ocstatus.FromError(err))
 		return pkgerrors.Wrap(err, "error while updating pod status in kubernetes")
 	}
 
+	if status != nil {
+		recordPodStatusEvents(s.recorder, pod, &pod.Status, status)
+	} else if newStatus.Reason == ReasonNotFound && pod.Status.Reason != ReasonNotFound {
+		s.recorder.Event(pod, corev1.EventTypeWarning, ReasonNotFound, newStatus.Message)
+	}
+
 	log.G(ctx).WithFields(log.Fields{
-		"new phase":  string(pod.Status.Phase),
-		"new reason": pod.Status.Reason,
+		"new phase":  string(newStatus.Phase),
+		"new reason": newStatus.Reason,
 	}).Debug("Updated pod status in kubernetes")
 
 	return nil
@@ -247,3 +283,64 @@ func (s *Server) podStatusHandler(ctx context.Context, key string) (retErr error
 
 	return s.updatePodStatus(ctx, pod)
 }
+
+// patchPodStatus writes the passed in status to the status subresource of the
+// pod in Kubernetes.
+// Only the fields which differ from the pod's current status are sent, and the
+// write is skipped entirely when the status did not change.
+func (s *Server) patchPodStatus(ctx context.Context, pod *corev1.Pod, status corev1.PodStatus) error {
+	patchBytes, err := preparePatchBytesForPodStatus(pod, status)
+	if err != nil {
+		recordPodStatusUpdate(ctx, podStatusUpdateResultError)
+		return err
+	}
+	if patchBytes == nil {
+		recordPodStatusUpdate(ctx, podStatusUpdateResultSkipped)
+		log.G(ctx).Debug("Pod status unchanged, skipping update")
+		return nil
+	}
+
+	if _, err := s.k8sClient.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.StrategicMergePatchType, patchBytes, "status"); err != nil {
+		if errors.IsConflict(err) {
+			recordPodStatusUpdate(ctx, podStatusUpdateResultConflict)
+		} else {
+			recordPodStatusUpdate(ctx, podStatusUpdateResultError)
+		}
+		return pkgerrors.Wrapf(err, "failed to patch status %q for pod %q", patchBytes, loggablePodName(pod))
+	}
+
+	recordPodStatusUpdate(ctx, podStatusUpdateResultPatched)
+	return nil
+}
+
+// preparePatchBytesForPodStatus creates a strategic merge patch from the pod's
+// current status to the passed in status.
+// If the status did not change, nil is returned.
+//
+// The pod UID is added to the patch as a precondition, so that the patch fails
+// with a conflict instead of being applied to a re-created pod of the same name.
+func preparePatchBytesForPodStatus(pod *corev1.Pod, status corev1.PodStatus) ([]byte, error) {
+	oldData, err := json.Marshal(corev1.Pod{Status: pod.Status})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to marshal old status for pod %q", loggablePodName(pod))
+	}
+	newData, err := json.Marshal(corev1.Pod{Status: status})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to marshal new status for pod %q", loggablePodName(pod))
+	}
+
+	patchBytes, err := strategicpatch.CreateTwoWayMergePatch(oldData, newData, corev1.Pod{})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to create status patch for pod %q", loggablePodName(pod))
+	}
+	if string(patchBytes) == "{}" {
+		return nil, nil
+	}
+
+	var patch map[string]interface{}
+	if err := json.Unmarshal(patchBytes, &patch); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal status patch for pod %q", loggablePodName(pod))
+	}
+	patch["metadata"] = map[string]interface{}{"uid": pod.UID}
+	return json.Marshal(patch)
+}
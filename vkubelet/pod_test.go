@@ -0,0 +1,163 @@
+package vkubelet
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	testutil "github.com/virtual-kubelet/virtual-kubelet/test/util"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPreparePatchBytesForPodStatus(t *testing.T) {
+	started := metav1.NewTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", UID: "1234"},
+		Status: corev1.PodStatus{
+			Phase:     corev1.PodPending,
+			StartTime: &started,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		// Times are only compared at the precision they are stored with.
+		status := pod.Status.DeepCopy()
+		status.StartTime = &metav1.Time{Time: started.Add(time.Millisecond)}
+
+		patch, err := preparePatchBytesForPodStatus(pod, *status)
+		assert.NilError(t, err)
+		assert.Assert(t, patch == nil, string(patch))
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		status := pod.Status.DeepCopy()
+		status.Phase = corev1.PodRunning
+		status.PodIP = "10.0.0.1"
+
+		patch, err := preparePatchBytesForPodStatus(pod, *status)
+		assert.NilError(t, err)
+
+		var decoded map[string]map[string]interface{}
+		assert.NilError(t, json.Unmarshal(patch, &decoded))
+		assert.DeepEqual(t, decoded["metadata"], map[string]interface{}{"uid": "1234"})
+		assert.DeepEqual(t, decoded["status"], map[string]interface{}{"phase": "Running", "podIP": "10.0.0.1"})
+	})
+}
+
+// creatingProvider records the pods passed to CreatePod. Only GetPod and
+// CreatePod are implemented.
+type creatingProvider struct {
+	providers.Provider
+	created []*corev1.Pod
+}
+
+func (p *creatingProvider) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	return nil, nil
+}
+
+func (p *creatingProvider) CreatePod(ctx context.Context, pod *corev1.Pod) error {
+	p.created = append(p.created, pod.DeepCopy())
+	return nil
+}
+
+// envResolvingProvider resolves the references to secrets and config maps of
+// the environment of pods itself.
+type envResolvingProvider struct {
+	creatingProvider
+}
+
+func (p *envResolvingProvider) ResolvesEnvironmentReferences() bool {
+	return true
+}
+
+func newEnvPod() *corev1.Pod {
+	pod := testutil.FakePodWithSingleContainer(namespace, "pod", "image")
+	pod.Spec.Containers[0].EnvFrom = []corev1.EnvFromSource{
+		{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret1.Name}}},
+	}
+	pod.Spec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "PLAIN", Value: "value"},
+		{Name: "NODE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "spec.nodeName"}}},
+		{Name: envVarName1, ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: configMap1.Name}, Key: keyFoo}}},
+		{Name: envVarName2, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret2.Name}, Key: keyFoo}}},
+		// The last entry of a name wins.
+		{Name: envVarName3, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret2.Name}, Key: keyFoo}}},
+		{Name: envVarName3, Value: "override"},
+	}
+	pod.Spec.NodeName = "vk"
+	return pod
+}
+
+func sortedEnv(env []corev1.EnvVar) []corev1.EnvVar {
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	return env
+}
+
+func TestCreateOrUpdatePodResolvesEnvironment(t *testing.T) {
+	p := &creatingProvider{}
+	s := &Server{
+		provider:        p,
+		resourceManager: testutil.FakeResourceManager(configMap1, secret1, secret2, testutil.FakeService(namespace, "web", "10.0.0.2", "TCP", 80)),
+	}
+
+	pod := newEnvPod()
+	assert.NilError(t, s.createOrUpdatePod(context.Background(), pod, testutil.FakeEventRecorder(defaultEventRecorderBufferSize)))
+	assert.Assert(t, is.Len(p.created, 1))
+
+	container := p.created[0].Spec.Containers[0]
+	assert.Check(t, is.Len(container.EnvFrom, 0))
+	env := make(map[string]string)
+	for _, e := range container.Env {
+		assert.Check(t, e.ValueFrom == nil, e.Name)
+		env[e.Name] = e.Value
+	}
+	assert.Check(t, is.Equal(env["PLAIN"], "value"))
+	assert.Check(t, is.Equal(env["NODE"], "vk"))
+	assert.Check(t, is.Equal(env[keyBaz], "__baz__"))
+	assert.Check(t, is.Equal(env[envVarName1], "__foo__"))
+	assert.Check(t, is.Equal(env[envVarName2], "__foo__"))
+	assert.Check(t, is.Equal(env[envVarName3], "override"))
+	assert.Check(t, is.Equal(env["WEB_SERVICE_HOST"], "10.0.0.2"))
+}
+
+func TestCreateOrUpdatePodKeepsEnvironmentReferences(t *testing.T) {
+	p := &envResolvingProvider{}
+	s := &Server{
+		provider:        p,
+		resourceManager: testutil.FakeResourceManager(configMap1, secret1, secret2, testutil.FakeService(namespace, "web", "10.0.0.2", "TCP", 80)),
+	}
+
+	pod := newEnvPod()
+	declared := pod.Spec.Containers[0].DeepCopy()
+	assert.NilError(t, s.createOrUpdatePod(context.Background(), pod, testutil.FakeEventRecorder(defaultEventRecorderBufferSize)))
+	assert.Assert(t, is.Len(p.created, 1))
+
+	// The references are passed as is, the other variables are resolved.
+	container := p.created[0].Spec.Containers[0]
+	assert.Check(t, is.DeepEqual(container.EnvFrom, declared.EnvFrom))
+	var values []corev1.EnvVar
+	for _, e := range container.Env {
+		if e.Name != "WEB_SERVICE_HOST" && strings.HasPrefix(e.Name, "WEB_") {
+			continue
+		}
+		values = append(values, e)
+	}
+	assert.Check(t, is.DeepEqual(sortedEnv(values), sortedEnv([]corev1.EnvVar{
+		{Name: "PLAIN", Value: "value"},
+		{Name: "NODE", Value: "vk"},
+		{Name: "WEB_SERVICE_HOST", Value: "10.0.0.2"},
+		declared.Env[2],
+		declared.Env[3],
+		{Name: envVarName3, Value: "override"},
+	})))
+}
@@ -24,13 +24,12 @@ import (
 
 	"github.com/cpuguy83/strongerrors/status/ocstatus"
 	pkgerrors "github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"github.com/virtual-kubelet/virtual-kubelet/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 	v1 "k8s.io/client-go/informers/core/v1"
-	"k8s.io/client-go/kubernetes/scheme"
-	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -58,11 +57,11 @@ type PodController struct {
 
 // NewPodController returns a new instance of PodController.
 func NewPodController(server *Server) *PodController {
-	// Create an event broadcaster.
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(log.L.Infof)
-	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: server.k8sClient.CoreV1().Events("")})
-	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: fmt.Sprintf("%s/pod-controller", server.nodeName)})
+	// Create an event recorder, unless the server already has one.
+	if server.recorder == nil {
+		server.recorder = newEventRecorder(server)
+	}
+	recorder := server.recorder
 
 	// Create an instance of PodController having a work queue that uses the rate limiter created above.
 	pc := &PodController{
@@ -127,6 +126,9 @@ func (pc *PodController) Run(ctx context.Context, threadiness int) error {
 		return pkgerrors.New("failed to wait for caches to sync")
 	}
 
+	// Let the provider reconcile its state with the pods of the node before looking for dangling pods.
+	pc.reconcilePods(ctx)
+
 	// Perform a reconciliation step that deletes any dangling pods from the provider.
 	// This happens only when the virtual-kubelet is starting, and operates on a "best-effort" basis.
 	// If by any reason the provider fails to delete a dangling pod, it will stay in the provider and deletion won't be retried.
@@ -239,6 +241,23 @@ func (pc *PodController) syncPodInProvider(ctx context.Context, pod *corev1.Pod)
 	return nil
 }
 
+// reconcilePods lets the provider reconcile its state with the pods of the node, if it implements providers.PodReconciler.
+func (pc *PodController) reconcilePods(ctx context.Context) {
+	r, ok := pc.server.provider.(providers.PodReconciler)
+	if !ok {
+		return
+	}
+
+	ctx, span := trace.StartSpan(ctx, "reconcilePods")
+	defer span.End()
+
+	if err := r.ReconcilePods(ctx); err != nil {
+		err := pkgerrors.Wrap(err, "failed to reconcile the pods of the provider")
+		span.SetStatus(ocstatus.FromError(err))
+		log.G(ctx).Error(err)
+	}
+}
+
 // deleteDanglingPods checks whether the provider knows about any pods which Kubernetes doesn't know about, and deletes them.
 func (pc *PodController) deleteDanglingPods(ctx context.Context, threadiness int) {
 	ctx, span := trace.StartSpan(ctx, "deleteDanglingPods")
@@ -0,0 +1,74 @@
+package vkubelet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// reconcilingProvider records the calls made at startup by the pod
+// controller. Only ReconcilePods and GetPods are implemented.
+type reconcilingProvider struct {
+	providers.Provider
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (p *reconcilingProvider) ReconcilePods(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, "ReconcilePods")
+	return nil
+}
+
+func (p *reconcilingProvider) GetPods(ctx context.Context) ([]*corev1.Pod, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, "GetPods")
+	return nil, nil
+}
+
+func (p *reconcilingProvider) getCalls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+func TestPodControllerReconcilesPodsAtStartup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	factory := kubeinformers.NewSharedInformerFactory(testclient.NewSimpleClientset(), 0)
+	p := &reconcilingProvider{}
+	s := &Server{
+		provider:    p,
+		podInformer: factory.Core().V1().Pods(),
+		recorder:    record.NewFakeRecorder(defaultEventRecorderBufferSize),
+	}
+	pc := NewPodController(s)
+	factory.Start(ctx.Done())
+
+	done := make(chan error)
+	go func() {
+		done <- pc.Run(ctx, 1)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(p.getCalls()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	assert.NilError(t, <-done)
+
+	// Pods are reconciled before the dangling pods are looked for.
+	assert.DeepEqual(t, p.getCalls(), []string{"ReconcilePods", "GetPods"})
+}
@@ -0,0 +1,313 @@
+package vkubelet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodeConditionResourcePressure is the node condition set by the
+// PodResourceTracker when the requests of the pods running on the node cross
+// the configured pressure threshold of the allocatable amount of any resource.
+const NodeConditionResourcePressure corev1.NodeConditionType = "ResourcePressure"
+
+// PodResourceTrackerOpt are the functional options used for configuring a
+// PodResourceTracker.
+type PodResourceTrackerOpt func(*PodResourceTracker) error
+
+// WithReservedResources sets the resources which are held back from the node's
+// capacity and never made allocatable to pods.
+func WithReservedResources(rl corev1.ResourceList) PodResourceTrackerOpt {
+	return func(t *PodResourceTracker) error {
+		for name, q := range rl {
+			if q.Sign() < 0 {
+				return pkgerrors.Errorf("reserved resource %q must not be negative", name)
+			}
+		}
+		t.reserved = rl.DeepCopy()
+		return nil
+	}
+}
+
+// WithResourcePressureThreshold sets the percentage of the allocatable
+// resources which, once requested by running pods, causes the
+// ResourcePressure condition to be set on the node.
+// A threshold of 0 disables the condition.
+func WithResourcePressureThreshold(percent int) PodResourceTrackerOpt {
+	return func(t *PodResourceTracker) error {
+		if percent < 0 || percent > 100 {
+			return pkgerrors.Errorf("resource pressure threshold must be between 0 and 100: %d", percent)
+		}
+		t.pressureThreshold = percent
+		return nil
+	}
+}
+
+// PodResourceTracker is a NodeProvider which reports the node capacity minus
+// the reserved resources as allocatable, and keeps the resource pressure
+// condition of the node in sync with the requests of the non-terminal pods
+// scheduled to it.
+//
+// The requests of the pods are not subtracted from the allocatable resources,
+// the scheduler already does so.
+//
+// It wraps another NodeProvider, which is still used for pings and whose node
+// status updates are passed through after the allocatable resources and the
+// resource pressure condition have been applied to them.
+type PodResourceTracker struct {
+	providers.NodeProvider
+
+	pods              corev1informers.PodInformer
+	reserved          corev1.ResourceList
+	pressureThreshold int
+
+	mu        sync.Mutex
+	node      *corev1.Node
+	lastState *corev1.NodeStatus
+	chChanged chan struct{}
+}
+
+// NewPodResourceTracker creates a new PodResourceTracker for the passed in node.
+// The pod informer is expected to only hold pods scheduled to the node.
+//
+// This does not have any side-effects on the system or kubernetes, tracking
+// only starts once `NotifyNodeStatus` is called.
+func NewPodResourceTracker(p providers.NodeProvider, node *corev1.Node, pods corev1informers.PodInformer, opts ...PodResourceTrackerOpt) (*PodResourceTracker, error) {
+	t := &PodResourceTracker{
+		NodeProvider: p,
+		pods:         pods,
+		node:         node.DeepCopy(),
+		chChanged:    make(chan struct{}, 1),
+	}
+	for _, o := range opts {
+		if err := o(t); err != nil {
+			return nil, pkgerrors.Wrap(err, "error applying pod resource tracker option")
+		}
+	}
+	return t, nil
+}
+
+// NotifyNodeStatus implements the NodeProvider interface.
+//
+// The passed in callback is called whenever the resource pressure condition of
+// the node changes, as well as whenever the
+// wrapped NodeProvider reports a node status change.
+func (t *PodResourceTracker) NotifyNodeStatus(ctx context.Context, cb func(*corev1.Node)) {
+	t.pods.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { t.notifyChanged() },
+		UpdateFunc: func(interface{}, interface{}) { t.notifyChanged() },
+		DeleteFunc: func(interface{}) { t.notifyChanged() },
+	})
+
+	t.NodeProvider.NotifyNodeStatus(ctx, func(n *corev1.Node) {
+		t.mu.Lock()
+		t.node = n.DeepCopy()
+		t.lastState = nil
+		t.mu.Unlock()
+		t.notifyChanged()
+	})
+
+	go t.run(ctx, cb)
+}
+
+func (t *PodResourceTracker) notifyChanged() {
+	select {
+	case t.chChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (t *PodResourceTracker) run(ctx context.Context, cb func(*corev1.Node)) {
+	if ok := cache.WaitForCacheSync(ctx.Done(), t.pods.Informer().HasSynced); !ok {
+		return
+	}
+	t.notifyChanged()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.chChanged:
+			node, err := t.nodeWithAllocatable()
+			if err != nil {
+				log.G(ctx).WithError(err).Error("Error computing node allocatable resources")
+				continue
+			}
+			if node != nil {
+				cb(node)
+			}
+		}
+	}
+}
+
+// nodeWithAllocatable returns a copy of the node with the allocatable
+// resources computed from its capacity, and the resource pressure condition
+// computed from the pods currently scheduled to the node.
+// If nothing changed since the last call, nil is returned.
+func (t *PodResourceTracker) nodeWithAllocatable() (*corev1.Node, error) {
+	pods, err := t.pods.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "error listing pods")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node := t.node.DeepCopy()
+	node.Status.Allocatable = allocatableResources(node.Status.Capacity, t.reserved)
+
+	if t.pressureThreshold > 0 {
+		c := resourcePressureCondition(node.Status.Allocatable, sumPodRequests(pods), t.pressureThreshold)
+		node.Status.Conditions = setNodeCondition(node.Status.Conditions, c)
+	}
+
+	if t.lastState != nil &&
+		reflect.DeepEqual(t.lastState.Allocatable, node.Status.Allocatable) &&
+		conditionStatusesEqual(t.lastState.Conditions, node.Status.Conditions) {
+		return nil, nil
+	}
+
+	t.node.Status.Conditions = node.Status.Conditions
+	t.lastState = node.Status.DeepCopy()
+	return node, nil
+}
+
+// allocatableResources computes the allocatable resources from the capacity
+// minus the reserved resources.
+// Resources never go below zero.
+func allocatableResources(capacity, reserved corev1.ResourceList) corev1.ResourceList {
+	allocatable := make(corev1.ResourceList, len(capacity))
+	for name, c := range capacity {
+		q := c.DeepCopy()
+		if r, ok := reserved[name]; ok {
+			q.Sub(r)
+		}
+		if q.Sign() < 0 {
+			q = *resource.NewQuantity(0, c.Format)
+		}
+		allocatable[name] = q
+	}
+	return allocatable
+}
+
+// sumPodRequests sums up the resource requests of all non-terminal pods.
+// Each pod also counts towards the "pods" resource.
+func sumPodRequests(pods []*corev1.Pod) corev1.ResourceList {
+	total := corev1.ResourceList{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		addResourceList(total, podRequests(pod))
+		addResourceList(total, corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)})
+	}
+	return total
+}
+
+// podRequests computes the effective requests of a pod the same way the
+// scheduler does: the sum of the requests of all containers, or the largest
+// request of any init container if that is bigger.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	reqs := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addResourceList(reqs, c.Resources.Requests)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if cur, ok := reqs[name]; !ok || q.Cmp(cur) > 0 {
+				reqs[name] = q.DeepCopy()
+			}
+		}
+	}
+	return reqs
+}
+
+func addResourceList(list, add corev1.ResourceList) {
+	for name, q := range add {
+		if cur, ok := list[name]; ok {
+			cur.Add(q)
+			list[name] = cur
+		} else {
+			list[name] = q.DeepCopy()
+		}
+	}
+}
+
+// resourcePressureCondition builds the ResourcePressure condition, which is
+// true when the requests for any resource reach the threshold percentage of
+// the allocatable amount of that resource.
+func resourcePressureCondition(allocatable, requested corev1.ResourceList, threshold int) corev1.NodeCondition {
+	var pressured []string
+	for name, a := range allocatable {
+		r, ok := requested[name]
+		if !ok || a.IsZero() {
+			continue
+		}
+		percent := r.MilliValue() * 100 / a.MilliValue()
+		if percent >= int64(threshold) {
+			pressured = append(pressured, fmt.Sprintf("%s at %d%%", name, percent))
+		}
+	}
+	sort.Strings(pressured)
+
+	c := corev1.NodeCondition{
+		Type:    NodeConditionResourcePressure,
+		Status:  corev1.ConditionFalse,
+		Reason:  "SufficientResources",
+		Message: fmt.Sprintf("pod requests are below %d%% of allocatable resources", threshold),
+	}
+	if len(pressured) > 0 {
+		c.Status = corev1.ConditionTrue
+		c.Reason = "InsufficientResources"
+		c.Message = "pod requests exceed threshold: " + strings.Join(pressured, ", ")
+	}
+	return c
+}
+
+// setNodeCondition replaces the condition of the same type in the list, or
+// appends it if there is none.
+// The transition time is only updated when the condition status changes.
+func setNodeCondition(conditions []corev1.NodeCondition, c corev1.NodeCondition) []corev1.NodeCondition {
+	now := metav1.NewTime(time.Now())
+	c.LastHeartbeatTime = now
+	c.LastTransitionTime = now
+
+	for i, existing := range conditions {
+		if existing.Type != c.Type {
+			continue
+		}
+		if existing.Status == c.Status {
+			c.LastTransitionTime = existing.LastTransitionTime
+		}
+		conditions[i] = c
+		return conditions
+	}
+	return append(conditions, c)
+}
+
+// conditionStatusesEqual compares conditions while ignoring their timestamps.
+func conditionStatusesEqual(a, b []corev1.NodeCondition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Status != b[i].Status || a[i].Reason != b[i].Reason || a[i].Message != b[i].Message {
+			return false
+		}
+	}
+	return true
+}
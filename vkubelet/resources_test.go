@@ -0,0 +1,177 @@
+package vkubelet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSumPodRequests(t *testing.T) {
+	pods := []*corev1.Pod{
+		testResourcePod("running", corev1.PodRunning, "500m", "1Gi"),
+		testResourcePod("pending", corev1.PodPending, "250m", "512Mi"),
+		testResourcePod("succeeded", corev1.PodSucceeded, "4", "8Gi"),
+		testResourcePod("failed", corev1.PodFailed, "4", "8Gi"),
+	}
+
+	// The init container request is bigger than the sum of the regular containers.
+	withInit := testResourcePod("init", corev1.PodRunning, "100m", "128Mi")
+	withInit.Spec.InitContainers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("2"),
+		}},
+	}}
+	pods = append(pods, withInit)
+
+	total := sumPodRequests(pods)
+	assertQuantity(t, total, corev1.ResourceCPU, "2750m")
+	assertQuantity(t, total, corev1.ResourceMemory, "1664Mi")
+	assertQuantity(t, total, corev1.ResourcePods, "3")
+}
+
+func TestAllocatableResources(t *testing.T) {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("8Gi"),
+		corev1.ResourcePods:   resource.MustParse("10"),
+	}
+	reserved := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("10Gi"),
+	}
+
+	allocatable := allocatableResources(capacity, reserved)
+	assertQuantity(t, allocatable, corev1.ResourceCPU, "3")
+	assertQuantity(t, allocatable, corev1.ResourceMemory, "0")
+	assertQuantity(t, allocatable, corev1.ResourcePods, "10")
+}
+
+func TestResourcePressureCondition(t *testing.T) {
+	allocatable := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("8Gi"),
+	}
+
+	c := resourcePressureCondition(allocatable, corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("2"),
+	}, 80)
+	assert.Equal(t, c.Status, corev1.ConditionFalse)
+
+	c = resourcePressureCondition(allocatable, corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("7Gi"),
+	}, 80)
+	assert.Equal(t, c.Status, corev1.ConditionTrue)
+	assert.Equal(t, c.Message, "pod requests exceed threshold: memory at 87%")
+}
+
+func TestPodResourceTracker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := testclient.NewSimpleClientset(testResourcePod("running", corev1.PodRunning, "1", "1Gi"))
+	factory := kubeinformers.NewSharedInformerFactory(c, 0)
+	pods := factory.Core().V1().Pods()
+
+	node := testNode(t)
+	node.Status.Capacity = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("4Gi"),
+		corev1.ResourcePods:   resource.MustParse("10"),
+	}
+
+	testP := &testNodeProvider{NodeProvider: &NaiveNodeProvider{}}
+	tracker, err := NewPodResourceTracker(testP, node, pods,
+		WithReservedResources(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}),
+		WithResourcePressureThreshold(50),
+	)
+	assert.NilError(t, err)
+
+	chNode := make(chan *corev1.Node, 10)
+	tracker.NotifyNodeStatus(ctx, func(n *corev1.Node) {
+		chNode <- n
+	})
+	factory.Start(ctx.Done())
+
+	n := waitForNode(t, chNode)
+	assertQuantity(t, n.Status.Allocatable, corev1.ResourceCPU, "3")
+	assertQuantity(t, n.Status.Allocatable, corev1.ResourceMemory, "4Gi")
+	assertQuantity(t, n.Status.Allocatable, corev1.ResourcePods, "10")
+	assert.Equal(t, n.Status.Conditions[0].Status, corev1.ConditionFalse)
+
+	_, err = c.CoreV1().Pods("default").Create(testResourcePod("big", corev1.PodPending, "1", "2Gi"))
+	assert.NilError(t, err)
+
+	// The requests of the pods are reported by the pressure condition only,
+	// allocatable resources are left to the scheduler.
+	n = waitForNode(t, chNode)
+	assertQuantity(t, n.Status.Allocatable, corev1.ResourceCPU, "3")
+	assertQuantity(t, n.Status.Allocatable, corev1.ResourceMemory, "4Gi")
+	assert.Equal(t, n.Status.Conditions[0].Message, "pod requests exceed threshold: cpu at 66%, memory at 75%")
+	assert.Equal(t, n.Status.Conditions[0].Type, NodeConditionResourcePressure)
+	assert.Equal(t, n.Status.Conditions[0].Status, corev1.ConditionTrue)
+
+	// Updates from the wrapped provider keep the computed allocatable resources.
+	update := node.DeepCopy()
+	update.Status.Capacity[corev1.ResourceCPU] = resource.MustParse("8")
+	testP.triggerStatusUpdate(update)
+
+	n = waitForNode(t, chNode)
+	assertQuantity(t, n.Status.Allocatable, corev1.ResourceCPU, "7")
+}
+
+func TestPodResourceTrackerOptions(t *testing.T) {
+	pods := kubeinformers.NewSharedInformerFactory(testclient.NewSimpleClientset(), 0).Core().V1().Pods()
+
+	_, err := NewPodResourceTracker(&NaiveNodeProvider{}, testNode(t), pods, WithResourcePressureThreshold(101))
+	assert.ErrorContains(t, err, "between 0 and 100")
+
+	_, err = NewPodResourceTracker(&NaiveNodeProvider{}, testNode(t), pods, WithReservedResources(corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("-1"),
+	}))
+	assert.ErrorContains(t, err, "must not be negative")
+}
+
+func testResourcePod(name string, phase corev1.PodPhase, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: name,
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func assertQuantity(t *testing.T, rl corev1.ResourceList, name corev1.ResourceName, expected string) {
+	t.Helper()
+	q, ok := rl[name]
+	assert.Assert(t, ok, "missing resource %s", name)
+	assert.Assert(t, q.Cmp(resource.MustParse(expected)) == 0, "expected %s to be %s, got %s", name, expected, q.String())
+}
+
+func waitForNode(t *testing.T, ch <-chan *corev1.Node) *corev1.Node {
+	t.Helper()
+	select {
+	case n := <-ch:
+		return n
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for node status update")
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package vkubelet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/cpuguy83/strongerrors/status/ocstatus"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"github.com/virtual-kubelet/virtual-kubelet/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+// DefaultStatsCacheTTL is the default amount of time a stats summary built by
+// the StatsAggregator is served from cache.
+const DefaultStatsCacheTTL = 10 * time.Second
+
+// maxConcurrentPodStats is the maximum number of concurrent calls to the
+// provider when collecting pod stats.
+const maxConcurrentPodStats = 10
+
+// StatsAggregator builds the kubelet stats summary, including the node level
+// rollup, for providers which implement providers.PodStatsProvider.
+//
+// It wraps the provider and implements providers.PodMetricsProvider, so it can
+// be passed to `AttachMetricsRoutes` in place of the provider.
+type StatsAggregator struct {
+	providers.Provider
+
+	sp        providers.PodStatsProvider
+	nodeName  string
+	ttl       time.Duration
+	startTime metav1.Time
+
+	mu       sync.Mutex
+	summary  *stats.Summary
+	syncTime time.Time
+}
+
+// NewStatsAggregator creates a new StatsAggregator for the passed in provider,
+// which must implement providers.PodStatsProvider.
+// Summaries are cached for the passed in ttl, if it is 0 DefaultStatsCacheTTL
+// is used.
+func NewStatsAggregator(p providers.Provider, nodeName string, ttl time.Duration) (*StatsAggregator, error) {
+	sp, ok := p.(providers.PodStatsProvider)
+	if !ok {
+		return nil, strongerrors.InvalidArgument(pkgerrors.New("provider does not implement PodStatsProvider"))
+	}
+	if ttl == 0 {
+		ttl = DefaultStatsCacheTTL
+	}
+	return &StatsAggregator{
+		Provider:  p,
+		sp:        sp,
+		nodeName:  nodeName,
+		ttl:       ttl,
+		startTime: metav1.Now(),
+	}, nil
+}
+
+// GetStatsSummary implements the providers.PodMetricsProvider interface.
+// It collects the stats of all running pods from the provider and rolls them
+// up into the node stats.
+func (a *StatsAggregator) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
+	ctx, span := trace.StartSpan(ctx, "StatsAggregator.GetStatsSummary")
+	defer span.End()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.summary != nil && time.Since(a.syncTime) < a.ttl {
+		span.WithField(ctx, "cachedResultSampleTime", a.syncTime.String())
+		return a.summary, nil
+	}
+
+	pods, err := a.GetPods(ctx)
+	if err != nil {
+		span.SetStatus(ocstatus.FromError(err))
+		return nil, pkgerrors.Wrap(err, "error getting pods from provider")
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sema     = make(chan struct{}, maxConcurrentPodStats)
+		chResult = make(chan stats.PodStats, len(pods))
+	)
+
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pod *corev1.Pod) {
+			defer wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case sema <- struct{}{}:
+			}
+			defer func() {
+				<-sema
+			}()
+
+			ps, err := a.sp.GetPodStats(ctx, pod.Namespace, pod.Name)
+			if err != nil {
+				logger := log.G(ctx).WithError(err).WithField("pod", loggablePodName(pod))
+				if strongerrors.IsNotFound(err) {
+					logger.Debug("Pod stats not found in provider")
+				} else {
+					logger.Warn("Error getting pod stats from provider")
+				}
+				return
+			}
+			if ps != nil {
+				chResult <- completePodStats(pod, *ps)
+			}
+		}(pod)
+	}
+
+	wg.Wait()
+	close(chResult)
+
+	if err := ctx.Err(); err != nil {
+		span.SetStatus(ocstatus.FromError(err))
+		return nil, err
+	}
+
+	summary := &stats.Summary{
+		Pods: make([]stats.PodStats, 0, len(chResult)),
+	}
+	for ps := range chResult {
+		summary.Pods = append(summary.Pods, ps)
+	}
+	summary.Node = rollupNodeStats(a.nodeName, a.startTime, summary.Pods)
+
+	a.summary = summary
+	a.syncTime = time.Now()
+
+	return summary, nil
+}
+
+// completePodStats fills in the pod reference and start time if the provider
+// did not set them, and computes the pod level CPU and memory stats from the
+// container stats if they are missing.
+func completePodStats(pod *corev1.Pod, ps stats.PodStats) stats.PodStats {
+	if ps.PodRef.Name == "" {
+		ps.PodRef = stats.PodReference{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			UID:       string(pod.UID),
+		}
+	}
+	if ps.StartTime.IsZero() {
+		if pod.Status.StartTime != nil {
+			ps.StartTime = *pod.Status.StartTime
+		} else {
+			ps.StartTime = pod.CreationTimestamp
+		}
+	}
+
+	if ps.CPU == nil {
+		for _, c := range ps.Containers {
+			ps.CPU = addCPUStats(ps.CPU, c.CPU)
+		}
+	}
+	if ps.Memory == nil {
+		for _, c := range ps.Containers {
+			ps.Memory = addMemoryStats(ps.Memory, c.Memory)
+		}
+	}
+	return ps
+}
+
+// rollupNodeStats sums up the CPU, memory and network stats of all pods into
+// the node stats.
+func rollupNodeStats(nodeName string, startTime metav1.Time, pods []stats.PodStats) stats.NodeStats {
+	ns := stats.NodeStats{
+		NodeName:  nodeName,
+		StartTime: startTime,
+	}
+
+	for _, ps := range pods {
+		ns.CPU = addCPUStats(ns.CPU, ps.CPU)
+		ns.Memory = addMemoryStats(ns.Memory, ps.Memory)
+		ns.Network = addNetworkStats(ns.Network, ps.Network)
+	}
+	return ns
+}
+
+func addCPUStats(dst, src *stats.CPUStats) *stats.CPUStats {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &stats.CPUStats{}
+	}
+	dst.Time = latestTime(dst.Time, src.Time)
+	dst.UsageNanoCores = addUint64(dst.UsageNanoCores, src.UsageNanoCores)
+	dst.UsageCoreNanoSeconds = addUint64(dst.UsageCoreNanoSeconds, src.UsageCoreNanoSeconds)
+	return dst
+}
+
+func addMemoryStats(dst, src *stats.MemoryStats) *stats.MemoryStats {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &stats.MemoryStats{}
+	}
+	dst.Time = latestTime(dst.Time, src.Time)
+	dst.UsageBytes = addUint64(dst.UsageBytes, src.UsageBytes)
+	dst.WorkingSetBytes = addUint64(dst.WorkingSetBytes, src.WorkingSetBytes)
+	dst.RSSBytes = addUint64(dst.RSSBytes, src.RSSBytes)
+	dst.PageFaults = addUint64(dst.PageFaults, src.PageFaults)
+	dst.MajorPageFaults = addUint64(dst.MajorPageFaults, src.MajorPageFaults)
+	return dst
+}
+
+func addNetworkStats(dst, src *stats.NetworkStats) *stats.NetworkStats {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &stats.NetworkStats{}
+	}
+	dst.Time = latestTime(dst.Time, src.Time)
+	dst.RxBytes = addUint64(dst.RxBytes, src.RxBytes)
+	dst.RxErrors = addUint64(dst.RxErrors, src.RxErrors)
+	dst.TxBytes = addUint64(dst.TxBytes, src.TxBytes)
+	dst.TxErrors = addUint64(dst.TxErrors, src.TxErrors)
+	return dst
+}
+
+// addUint64 adds two optional values, the result is only nil if both are nil.
+func addUint64(a, b *uint64) *uint64 {
+	if b == nil {
+		return a
+	}
+	var sum uint64
+	if a != nil {
+		sum = *a
+	}
+	sum += *b
+	return &sum
+}
+
+func latestTime(a, b metav1.Time) metav1.Time {
+	if a.Before(&b) {
+		return b
+	}
+	return a
+}
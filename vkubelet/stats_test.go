@@ -0,0 +1,125 @@
+package vkubelet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+type testStatsProvider struct {
+	providers.Provider
+	pods  []*corev1.Pod
+	stats map[string]*stats.PodStats
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *testStatsProvider) GetPods(context.Context) ([]*corev1.Pod, error) {
+	return p.pods, nil
+}
+
+func (p *testStatsProvider) GetPodStats(ctx context.Context, namespace, name string) (*stats.PodStats, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+
+	ps, ok := p.stats[name]
+	if !ok {
+		return nil, strongerrors.NotFound(errors.New("not found"))
+	}
+	return ps, nil
+}
+
+func TestStatsAggregator(t *testing.T) {
+	ctx := context.Background()
+
+	p := &testStatsProvider{
+		pods: []*corev1.Pod{
+			testStatsPod("a", corev1.PodRunning),
+			testStatsPod("b", corev1.PodRunning),
+			testStatsPod("missing", corev1.PodRunning),
+			testStatsPod("pending", corev1.PodPending),
+		},
+		stats: map[string]*stats.PodStats{
+			"a": {
+				Containers: []stats.ContainerStats{
+					{Name: "c1", CPU: testCPUStats(100), Memory: testMemoryStats(1000)},
+					{Name: "c2", CPU: testCPUStats(200), Memory: testMemoryStats(2000)},
+				},
+				Network: &stats.NetworkStats{InterfaceStats: stats.InterfaceStats{RxBytes: uint64Ptr(10), TxBytes: uint64Ptr(20)}},
+			},
+			"b": {
+				PodRef: stats.PodReference{Name: "b", Namespace: "default", UID: "custom"},
+				CPU:    testCPUStats(50),
+				Memory: testMemoryStats(500),
+			},
+			"pending": {CPU: testCPUStats(1000)},
+		},
+	}
+
+	_, err := NewStatsAggregator(struct{ providers.Provider }{}, "node", 0)
+	assert.Assert(t, strongerrors.IsInvalidArgument(err))
+
+	a, err := NewStatsAggregator(p, "node", time.Hour)
+	assert.NilError(t, err)
+
+	summary, err := a.GetStatsSummary(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(summary.Pods), 2)
+	assert.Equal(t, p.calls, 3)
+
+	byName := make(map[string]stats.PodStats)
+	for _, ps := range summary.Pods {
+		byName[ps.PodRef.Name] = ps
+	}
+	assert.Equal(t, byName["a"].PodRef.Namespace, "default")
+	assert.Equal(t, *byName["a"].CPU.UsageNanoCores, uint64(300))
+	assert.Equal(t, *byName["a"].Memory.WorkingSetBytes, uint64(3000))
+	assert.Equal(t, byName["b"].PodRef.UID, "custom")
+
+	assert.Equal(t, summary.Node.NodeName, "node")
+	assert.Equal(t, *summary.Node.CPU.UsageNanoCores, uint64(350))
+	assert.Equal(t, *summary.Node.Memory.WorkingSetBytes, uint64(3500))
+	assert.Equal(t, *summary.Node.Network.RxBytes, uint64(10))
+	assert.Equal(t, *summary.Node.Network.TxBytes, uint64(20))
+
+	// The second call is served from the cache.
+	cached, err := a.GetStatsSummary(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, p.calls, 3)
+	assert.Equal(t, cached, summary)
+}
+
+func testStatsPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID("uid-" + name),
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func testCPUStats(nanoCores uint64) *stats.CPUStats {
+	return &stats.CPUStats{Time: metav1.Now(), UsageNanoCores: &nanoCores}
+}
+
+func testMemoryStats(bytes uint64) *stats.MemoryStats {
+	return &stats.MemoryStats{Time: metav1.Now(), WorkingSetBytes: &bytes, UsageBytes: &bytes}
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
@@ -11,6 +11,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -27,6 +28,7 @@ type Server struct {
 	resourceManager *manager.ResourceManager
 	podSyncWorkers  int
 	podInformer     corev1informers.PodInformer
+	recorder        record.EventRecorder
 }
 
 // Config is used to configure a new server.
@@ -63,6 +65,17 @@ func New(cfg Config) *Server {
 // info to the Kubernetes API Server, such as logs, metrics, exec, etc.
 // See `AttachPodRoutes` and `AttachMetricsRoutes` to set these up.
 func (s *Server) Run(ctx context.Context) error {
+	if s.recorder == nil {
+		s.recorder = newEventRecorder(s)
+	}
+
+	if en, ok := s.provider.(providers.PodEventNotifier); ok {
+		r := newProviderEventRecorder(s)
+		en.NotifyPodEvents(ctx, func(pod *corev1.Pod, eventType, reason, message string) {
+			r.record(ctx, pod, eventType, reason, message)
+		})
+	}
+
 	q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "podStatusUpdate")
 	go s.runProviderSyncWorkers(ctx, q)
 